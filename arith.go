@@ -0,0 +1,291 @@
+package pongo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// An arithNode is a node of a (simple) arithmetic expression like 'i + 1'
+// as used in subscripts (items[i + 1]). Operands are regular expressions
+// (identifiers, literals, filters).
+type arithNode interface {
+	eval(*Context) (interface{}, error)
+}
+
+type arithOperand struct {
+	e *expr
+}
+
+type arithNegation struct {
+	operand arithNode
+}
+
+type arithOperation struct {
+	op          byte // one of + - * / %
+	left, right arithNode
+}
+
+// arithParser is a recursive descent parser with the following grammar:
+//     sum     := product (('+' | '-') product)*
+//     product := unary (('*' | '/' | '%') unary)*
+//     unary   := '-' unary | primary
+//     primary := '(' sum ')' | <expr>
+type arithParser struct {
+	raw string
+	pos int
+}
+
+func parseArith(raw string) (arithNode, error) {
+	p := &arithParser{raw: raw}
+
+	node, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.raw) {
+		return nil, errors.New(fmt.Sprintf("Unexpected '%s' in expression '%s'", p.raw[p.pos:], raw))
+	}
+
+	return node, nil
+}
+
+func (p *arithParser) skipSpaces() {
+	for p.pos < len(p.raw) && (p.raw[p.pos] == ' ' || p.raw[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peekOperator returns the next char if it's one of ops.
+func (p *arithParser) peekOperator(ops string) (byte, bool) {
+	p.skipSpaces()
+	if p.pos < len(p.raw) && strings.IndexByte(ops, p.raw[p.pos]) >= 0 {
+		return p.raw[p.pos], true
+	}
+	return 0, false
+}
+
+func (p *arithParser) parseSum() (arithNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, is_op := p.peekOperator("+-")
+		if !is_op {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithOperation{op: op, left: left, right: right}
+	}
+}
+
+func (p *arithParser) parseProduct() (arithNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, is_op := p.peekOperator("*/%")
+		if !is_op {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithOperation{op: op, left: left, right: right}
+	}
+}
+
+func (p *arithParser) parseUnary() (arithNode, error) {
+	if _, is_neg := p.peekOperator("-"); is_neg {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithNegation{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *arithParser) parsePrimary() (arithNode, error) {
+	if _, is_paren := p.peekOperator("("); is_paren {
+		p.pos++
+		node, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if _, is_paren := p.peekOperator(")"); !is_paren {
+			return nil, errors.New(fmt.Sprintf("Missing ')' in expression '%s'", p.raw))
+		}
+		p.pos++
+		return node, nil
+	}
+
+	// Read the operand until the next operator (outside of strings,
+	// parentheses and brackets)
+	start := p.pos
+	in_string := false
+	depth := 0
+	for ; p.pos < len(p.raw); p.pos++ {
+		c := p.raw[p.pos]
+		if in_string {
+			if c == '\\' {
+				p.pos++
+			} else if c == '"' {
+				in_string = false
+			}
+			continue
+		}
+		if c == '"' {
+			in_string = true
+			continue
+		}
+		if c == '(' || c == '[' {
+			depth++
+			continue
+		}
+		if depth > 0 {
+			if c == ')' || c == ']' {
+				depth--
+			}
+			continue
+		}
+		if strings.IndexByte("+-*/%() \t", c) >= 0 {
+			break
+		}
+	}
+
+	operand := p.raw[start:p.pos]
+	if operand == "" {
+		return nil, errors.New(fmt.Sprintf("Missing operand in expression '%s'", p.raw))
+	}
+	e, err := newExpr(&operand)
+	if err != nil {
+		return nil, err
+	}
+	return &arithOperand{e: e}, nil
+}
+
+// arithUsesVar returns whether one of the operands looks up the variable name.
+func arithUsesVar(n arithNode, name string) bool {
+	switch n := n.(type) {
+	case *arithOperand:
+		return n.e.usesVar(name)
+	case *arithNegation:
+		return arithUsesVar(n.operand, name)
+	case *arithOperation:
+		return arithUsesVar(n.left, name) || arithUsesVar(n.right, name)
+	}
+	return false
+}
+
+func (n *arithOperand) eval(ctx *Context) (interface{}, error) {
+	return n.e.evalValue(ctx)
+}
+
+func (n *arithNegation) eval(ctx *Context) (interface{}, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (&arithOperation{op: '-'}).apply(0, value)
+}
+
+func (n *arithOperation) eval(ctx *Context) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return n.apply(left, right)
+}
+
+func (n *arithOperation) apply(left, right interface{}) (interface{}, error) {
+	// String concatenation
+	if ls, is_str := left.(string); is_str && n.op == '+' {
+		if rs, is_str := right.(string); is_str {
+			return ls + rs, nil
+		}
+	}
+
+	li, lf, l_is_int, l_ok := toNumber(left)
+	ri, rf, r_is_int, r_ok := toNumber(right)
+	if !l_ok || !r_ok {
+		return nil, errors.New(fmt.Sprintf("Operator '%c' can't be applied to %v (%T) and %v (%T)", n.op, left, left, right, right))
+	}
+
+	if l_is_int && r_is_int {
+		switch n.op {
+		case '+':
+			return int(li + ri), nil
+		case '-':
+			return int(li - ri), nil
+		case '*':
+			return int(li * ri), nil
+		case '/', '%':
+			if ri == 0 {
+				return nil, errors.New("Division by zero")
+			}
+			if n.op == '/' {
+				return int(li / ri), nil
+			}
+			return int(li % ri), nil
+		}
+	}
+
+	switch n.op {
+	case '+':
+		return lf + rf, nil
+	case '-':
+		return lf - rf, nil
+	case '*':
+		return lf * rf, nil
+	case '/':
+		if rf == 0 {
+			return nil, errors.New("Division by zero")
+		}
+		return lf / rf, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Operator '%c' requires integers", n.op))
+}
+
+// arithExprs returns all operands of the arithmetic expression n.
+func arithExprs(n arithNode) []*expr {
+	switch n := n.(type) {
+	case *arithOperand:
+		return []*expr{n.e}
+	case *arithNegation:
+		return arithExprs(n.operand)
+	case *arithOperation:
+		return append(arithExprs(n.left), arithExprs(n.right)...)
+	}
+	return nil
+}
+
+// toNumber converts ints and floats (of any size) to int64 and float64.
+func toNumber(value interface{}) (int64, float64, bool, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), float64(rv.Int()), true, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), float64(rv.Uint()), true, true
+	case reflect.Float32, reflect.Float64:
+		return int64(rv.Float()), rv.Float(), false, true
+	}
+	return 0, 0, false, false
+}
@@ -0,0 +1,754 @@
+package pongo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// contextShape describes the variables of the Context a template expects
+// (see Template.Bind).
+type contextShape struct {
+	root   reflect.Type            // type of the root value (see ExecuteValue) whose methods are variables as well
+	fields reflect.Type            // struct type whose fields are the variables (if not nil)
+	vars   map[string]reflect.Type // types of the variables; nil if only known at runtime
+}
+
+func (s *contextShape) lookup(name string) (reflect.Type, bool) {
+	if s.fields != nil {
+		return lookupFieldType(s.fields, name)
+	}
+	t, has_var := s.vars[name]
+	return t, has_var
+}
+
+// method returns the type of the root value's method name (if any).
+func (s *contextShape) method(name string) (reflect.Type, bool) {
+	if s.root == nil {
+		return nil, false
+	}
+	return methodType(s.root, name)
+}
+
+// Bind declares the shape of the Context the template expects, so it can be
+// verified by Check. shape might be
+//
+//     - a struct (or a pointer to a struct or its reflect.Type); its methods
+//       and fields are the variables (like ExecuteValue resolves them)
+//     - a Context (or map[string]interface{}) with sample values
+//     - a map[string]reflect.Type
+//
+// Variables with a nil value (or of type interface{}) are only checked for
+// existence.
+func (tpl *Template) Bind(shape interface{}) error {
+	s := &contextShape{vars: make(map[string]reflect.Type)}
+
+	var values map[string]interface{}
+	switch v := shape.(type) {
+	case map[string]reflect.Type:
+		for name, t := range v {
+			s.vars[name] = t
+		}
+	case Context:
+		values = v
+	case *Context:
+		values = *v
+	case map[string]interface{}:
+		values = v
+	default:
+		t, is_type := shape.(reflect.Type)
+		if !is_type {
+			t = reflect.TypeOf(shape)
+		}
+		s.root = t
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return errors.New(fmt.Sprintf("Can't bind a Context of type %v (a struct or a map is required).", t))
+		}
+		s.fields = t
+	}
+	for name, value := range values {
+		s.vars[name] = reflect.TypeOf(value)
+	}
+
+	tpl.shape = s
+	return nil
+}
+
+// A CheckError is an error found by Template.Check.
+type CheckError struct {
+	Template string // name of the template containing the error
+	Line     int
+	Col      int
+	Msg      string
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("[Check error: %s] [Line %d, Column %d] %s", e.Template, e.Line, e.Col, e.Msg)
+}
+
+// CheckErrors are all errors found by Template.Check.
+type CheckErrors []*CheckError
+
+func (errs CheckErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Check verifies all expressions of the template (including the templates it
+// extends or includes) against the Context declared by Bind: variables,
+// fields and methods must exist and methods must be called with the right
+// number of arguments (filters are checked by the parser, see FilterSpec).
+// Returns nil or CheckErrors.
+//
+// Values whose type is only known at runtime (like interface{} or the results
+// of filters) are not checked any further. Templates referenced by a name
+// which is only known at runtime and custom tags are not checked.
+func (tpl *Template) Check() error {
+	if tpl.shape == nil {
+		return errors.New("Check requires the shape of the Context (see Bind).")
+	}
+
+	c := &checker{
+		shape:     tpl.shape,
+		blocks:    make(blockOverrides),
+		reported:  make(map[string]bool),
+		including: make(map[*Template]bool),
+	}
+	c.checkTemplate(tpl)
+
+	if len(c.errors) == 0 {
+		return nil
+	}
+	return c.errors
+}
+
+var (
+	stringType      = reflect.TypeOf("")
+	intType         = reflect.TypeOf(0)
+	boolType        = reflect.TypeOf(true)
+	forContextType  = reflect.TypeOf(&forContext{})
+	forContextsType = reflect.TypeOf([]*forContext{})
+	blockType       = reflect.TypeOf(Context{})
+)
+
+// checker walks a template like the interpreter, but only determines the
+// types of its expressions.
+type checker struct {
+	shape  *contextShape
+	scopes []map[string]reflect.Type // variables defined by tags (like for)
+
+	errors    CheckErrors
+	reported  map[string]bool // a node might be checked more than once (e. g. in blocks)
+	including map[*Template]bool
+
+	tpl    *Template // template whose nodes are checked
+	pos    int
+	blocks blockOverrides
+}
+
+func (c *checker) report(n node, err error) {
+	check_err := &CheckError{
+		Template: c.tpl.name,
+		Line:     n.getLine(),
+		Col:      n.getCol(),
+		Msg:      err.Error(),
+	}
+	if key := check_err.Error(); !c.reported[key] {
+		c.reported[key] = true
+		c.errors = append(c.errors, check_err)
+	}
+}
+
+func (c *checker) checkTemplate(tpl *Template) {
+	outer_tpl, outer_pos := c.tpl, c.pos
+	c.tpl, c.pos = tpl, 0
+	c.including[tpl] = true
+	defer func() {
+		delete(c.including, tpl)
+		c.tpl, c.pos = outer_tpl, outer_pos
+	}()
+
+	for ; c.pos < len(tpl.nodes); c.pos++ {
+		c.checkNode()
+	}
+}
+
+// checkUntilAnyTagNode checks all nodes following the current one until one of
+// the given tags is reached (like executeUntilAnyTagNode).
+func (c *checker) checkUntilAnyTagNode(nodenames ...string) *tagNode {
+	start := c.tpl.nodes[c.pos]
+
+	for c.pos++; c.pos < len(c.tpl.nodes); c.pos++ {
+		if tn, is_tag := c.tpl.nodes[c.pos].(*tagNode); is_tag {
+			for _, name := range nodenames {
+				if tn.tagname == name {
+					return tn
+				}
+			}
+		}
+		c.checkNode()
+	}
+
+	c.report(start, errors.New(fmt.Sprintf("No end-node (possible nodes: %v) found.", nodenames)))
+	return nil
+}
+
+func (c *checker) checkNode() {
+	switch n := c.tpl.nodes[c.pos].(type) {
+	case *filterNode:
+		if _, err := c.exprType(n.e); err != nil {
+			c.report(n, err)
+		}
+	case *tagNode:
+		if n.taghandler != nil && n.taghandler.Check != nil {
+			n.taghandler.Check(c, n)
+		}
+	}
+}
+
+func (c *checker) pushScope(scope map[string]reflect.Type) {
+	c.scopes = append(c.scopes, scope)
+}
+
+func (c *checker) popScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *checker) lookupVar(name string) (reflect.Type, bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if t, has_var := c.scopes[i][name]; has_var {
+			return dynamicType(t), true
+		}
+	}
+	t, has_var := c.shape.lookup(name)
+	if !has_var && name == "_" {
+		return translateFuncType, true
+	}
+	return dynamicType(t), has_var
+}
+
+// rootMethod returns the type of the root value's method name unless a
+// variable of the Context shadows it.
+func (c *checker) rootMethod(name string) (reflect.Type, bool) {
+	for _, scope := range c.scopes {
+		if _, has_var := scope[name]; has_var {
+			return nil, false
+		}
+	}
+	return c.shape.method(name)
+}
+
+// dynamicType returns nil if the type of a value of type t is only known at
+// runtime (t is interface{}).
+func dynamicType(t reflect.Type) reflect.Type {
+	if t != nil && t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return nil
+	}
+	return t
+}
+
+// exprType determines the type of the value the expression evaluates to (nil
+// if it's only known at runtime).
+func (c *checker) exprType(e *expr) (reflect.Type, error) {
+	var t reflect.Type
+	if _, is_ident := e.root.(exprIdent); is_ident {
+		var err error
+		t, err = c.identType(e.ident, len(e.root_args))
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range e.root_args {
+			if err := c.checkArg(arg.Interface()); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		t = reflect.TypeOf(e.root)
+	}
+
+	for _, filter := range e.filters {
+		for _, arg := range filter.args {
+			if err := c.checkArg(arg); err != nil {
+				return nil, err
+			}
+		}
+		if filter.fn != nil {
+			t = nil
+		}
+	}
+
+	if e.negate {
+		t = boolType
+	}
+	return t, nil
+}
+
+// checkArg checks an argument of a filter (or of a method call like {{ MsgTo:User }}).
+func (c *checker) checkArg(arg interface{}) error {
+	ident, is_ident := arg.(exprIdent)
+	if !is_ident {
+		return nil
+	}
+	parts, err := splitIdent(ident)
+	if err != nil {
+		return err
+	}
+	_, err = c.identType(parts, 0)
+	return err
+}
+
+func (c *checker) checkExprs(exprs []*expr) error {
+	for _, e := range exprs {
+		if _, err := c.exprType(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// identType determines the type of an identifier like resolveIdentParts
+// resolves its value. call_args is the number of arguments a method at the
+// end of the chain is called with.
+func (c *checker) identType(parts []identPart, call_args int) (reflect.Type, error) {
+	root := parts[0]
+	t, has_var := c.lookupVar(root.name)
+	if ft, is_method := c.rootMethod(root.name); is_method {
+		// Method of the root value (see lookupRoot)
+		if err := c.checkExprs(root.args); err != nil {
+			return nil, err
+		}
+		n := len(root.args)
+		if !root.is_call {
+			n = 0
+			if len(parts) == 1 {
+				n = call_args
+			}
+		}
+		var err error
+		t, err = callType(root.name, ft, n)
+		if err != nil {
+			return nil, err
+		}
+	} else if !has_var {
+		return nil, errors.New(fmt.Sprintf("Variable '%s' is not part of the Context.", root.name))
+	} else if root.is_call {
+		if err := c.checkExprs(root.args); err != nil {
+			return nil, err
+		}
+		if t != nil {
+			if t.Kind() != reflect.Func {
+				return nil, errors.New(fmt.Sprintf("'%s' (%s) is not a function and can't be called", root.name, t))
+			}
+			var err error
+			t, err = callType(root.name, t, len(root.args))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	parts = parts[1:]
+	for idx, part := range parts {
+		if part.is_subscript {
+			if err := c.checkExprs(arithExprs(part.subscript)); err != nil {
+				return nil, err
+			}
+			if t == nil {
+				continue
+			}
+			var err error
+			t, err = subscriptType(t)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if part.is_call {
+			if err := c.checkExprs(part.args); err != nil {
+				return nil, err
+			}
+			if t == nil {
+				continue
+			}
+			ft, found := callableType(t, part.name)
+			if !found {
+				return nil, errors.New(fmt.Sprintf("Method '%s' not found in type %s.", part.name, t))
+			}
+			if ft == nil {
+				t = nil
+				continue
+			}
+			var err error
+			t, err = callType(part.name, ft, len(part.args))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if t == nil {
+			continue
+		}
+		if part.specifier == nil {
+			return nil, errors.New(fmt.Sprintf("Specifier '%s' is not valid.", part.name))
+		}
+
+		attr, is_ident := part.specifier.(exprIdent)
+		if is_ident {
+			if ft, has_method := methodType(t, string(attr)); has_method {
+				// Methods are called without arguments unless they are at the
+				// end of the chain (see resolveIdentParts)
+				n := 0
+				if idx == len(parts)-1 {
+					n = call_args
+				}
+				var err error
+				t, err = callType(string(attr), ft, n)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Array, reflect.Slice, reflect.String:
+			if is_ident {
+				// The index might be taken from the Context
+				if _, has_var := c.lookupVar(string(attr)); !has_var {
+					return nil, errors.New(fmt.Sprintf("Index '%s' of %s is neither an integer nor part of the Context.", part.name, t))
+				}
+			}
+			if t.Kind() == reflect.String {
+				t = stringType
+			} else {
+				t = t.Elem()
+			}
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			if !is_ident {
+				return nil, errors.New(fmt.Sprintf("Struct %s can't be accessed by index %s.", t, part.name))
+			}
+			field_t, has_field := lookupFieldType(t, string(attr))
+			if !has_field {
+				// The field name might be taken from the Context
+				if _, has_var := c.lookupVar(string(attr)); !has_var {
+					return nil, errors.New(fmt.Sprintf("Field or method '%s' not found in type %s.", attr, t))
+				}
+			}
+			t = field_t
+		case reflect.Interface:
+			t = nil
+		default:
+			return nil, errors.New(fmt.Sprintf("Specifier '%s' can't be applied to type %s.", part.name, t))
+		}
+		t = dynamicType(t)
+	}
+
+	return t, nil
+}
+
+// methodType returns the type of the method name of t (without the receiver).
+func methodType(t reflect.Type, name string) (reflect.Type, bool) {
+	m, has_method := t.MethodByName(name)
+	if !has_method {
+		return nil, false
+	}
+	if t.Kind() == reflect.Interface {
+		return m.Type, true
+	}
+
+	in := make([]reflect.Type, 0, m.Type.NumIn()-1)
+	for i := 1; i < m.Type.NumIn(); i++ {
+		in = append(in, m.Type.In(i))
+	}
+	out := make([]reflect.Type, 0, m.Type.NumOut())
+	for i := 0; i < m.Type.NumOut(); i++ {
+		out = append(out, m.Type.Out(i))
+	}
+	return reflect.FuncOf(in, out, m.Type.IsVariadic()), true
+}
+
+// callableType returns the type of the method (or the function stored in a
+// field or map) name of t like lookupCallable. The type is nil if it's only
+// known at runtime.
+func callableType(t reflect.Type, name string) (reflect.Type, bool) {
+	if ft, has_method := methodType(t, name); has_method {
+		return ft, true
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var ft reflect.Type
+	switch t.Kind() {
+	case reflect.Struct:
+		field_t, has_field := lookupFieldType(t, name)
+		if !has_field {
+			return nil, false
+		}
+		ft = field_t
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, false
+		}
+		ft = t.Elem()
+	case reflect.Interface:
+		return nil, true
+	default:
+		return nil, false
+	}
+
+	switch ft.Kind() {
+	case reflect.Func:
+		return ft, true
+	case reflect.Interface:
+		return nil, true
+	}
+	return nil, false
+}
+
+// callType checks the number of arguments of a call like callMethod does and
+// returns the type of its result.
+func callType(name string, ft reflect.Type, args int) (reflect.Type, error) {
+	in := ft.NumIn()
+	if in > 0 && ft.In(0) == goContextType {
+		// The context.Context is passed implicitly
+		in--
+	}
+
+	if ft.IsVariadic() {
+		if args < in-1 {
+			return nil, errors.New(fmt.Sprintf("Method '%s' requires at least %d argument(s), %d given.", name, in-1, args))
+		}
+	} else if args != in {
+		return nil, errors.New(fmt.Sprintf("Method '%s' requires %d argument(s), %d given.", name, in, args))
+	}
+
+	switch {
+	case ft.NumOut() == 0:
+		return stringType, nil
+	case ft.NumOut() == 1, ft.NumOut() == 2 && ft.Out(1) == errorType:
+		return dynamicType(ft.Out(0)), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Method '%s' returns more than one value (only a single value or (value, error) are supported).", name))
+}
+
+// subscriptType returns the type of t's elements accessed by a subscript.
+func subscriptType(t reflect.Type) (reflect.Type, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		return dynamicType(t.Elem()), nil
+	case reflect.String:
+		return stringType, nil
+	case reflect.Struct, reflect.Interface:
+		return nil, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Type %s can't be subscripted.", t))
+}
+
+// dependency returns the template referenced by a constant name (or nil).
+func (c *checker) dependency(e *expr) *Template {
+	if !e.isConstant() {
+		return nil
+	}
+	name, err := e.evalString(&Context{})
+	if err != nil {
+		return nil
+	}
+	return c.tpl.dep_templates[*name]
+}
+
+func (c *checker) checkCondition(tn *tagNode, cond *condition) {
+	if cond.op != nil {
+		c.checkCondition(tn, cond.left)
+		c.checkCondition(tn, cond.right)
+		return
+	}
+	if _, err := c.exprType(cond.e); err != nil {
+		c.report(tn, err)
+	}
+}
+
+func checkIf(c *checker, tn *tagNode) {
+	args := strings.TrimSpace(tn.tagargs)
+	if len(args) == 0 {
+		c.report(tn, errors.New("If-argument is empty."))
+		return
+	}
+	cond, err := parseCondition(args)
+	if err != nil {
+		c.report(tn, err)
+		return
+	}
+	c.checkCondition(tn, cond)
+}
+
+func checkFor(c *checker, tn *tagNode) {
+	scope := map[string]reflect.Type{
+		"forloop":     forContextType,
+		"forloops":    forContextsType,
+		"forcounter":  intType,
+		"forcounter1": intType,
+	}
+
+	fa, err := parseForArgs(tn.tagargs)
+	if err != nil {
+		c.report(tn, err)
+	} else {
+		t, err := c.exprType(fa.e)
+		if err != nil {
+			c.report(tn, err)
+			t = nil
+		}
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		var item_t reflect.Type
+		switch {
+		case t == nil:
+		case !fa.has_in:
+			if t != intType {
+				c.report(tn, errors.New(fmt.Sprintf("For-loop error: Cannot iterate over '%v' (%s).", fa.raw, t)))
+			}
+		case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+			item_t = t.Elem()
+		case t.Kind() == reflect.String:
+			item_t = stringType
+		case t.Kind() == reflect.Map:
+			// See forArgs.loop
+			item_t = reflect.StructOf([]reflect.StructField{
+				{Name: "Key", Type: t.Key()},
+				{Name: "Value", Type: t.Elem()},
+			})
+		default:
+			c.report(tn, errors.New(fmt.Sprintf("For-loop 'in'-operator can't be used for %s (only slices/arrays/strings/maps).", t)))
+		}
+		if fa.has_in {
+			scope[fa.varname] = item_t
+		}
+	}
+
+	c.pushScope(scope)
+	end := c.checkUntilAnyTagNode("else", "endfor")
+	c.popScope()
+	if end != nil && end.tagname == "else" {
+		c.checkUntilAnyTagNode("endfor")
+	}
+}
+
+func checkBlock(c *checker, tn *tagNode) {
+	bi, has_block := c.tpl.blocks[tn.tagargs]
+	if !has_block {
+		c.report(tn, errors.New(fmt.Sprintf("Block '%s' is not indexed. Please report this issue.", tn.tagargs)))
+		return
+	}
+
+	// Check the most derived definition and all it renders via block.super
+	chain := c.blocks.chain(c.tpl, bi.name)
+	for _, def := range chain {
+		outer_tpl, outer_pos := c.tpl, c.pos
+		c.tpl, c.pos = def.template, def.info.start
+		c.pushScope(map[string]reflect.Type{"block": blockType})
+		c.checkUntilAnyTagNode("endblock")
+		c.popScope()
+		c.tpl, c.pos = outer_tpl, outer_pos
+
+		if !def.info.uses_super {
+			break
+		}
+	}
+
+	// Skip the default content of this block
+	c.pos = bi.end
+}
+
+func checkRemove(c *checker, tn *tagNode) {
+	patterns, err := parseRemovePatterns(tn.tagargs)
+	if err != nil {
+		c.report(tn, err)
+		return
+	}
+	if err := c.checkExprs(patterns); err != nil {
+		c.report(tn, err)
+	}
+}
+
+func checkExtends(c *checker, tn *tagNode) {
+	e, _, err := parseExtendsArgs(tn.tagargs)
+	if err != nil {
+		c.report(tn, err)
+		return
+	}
+	if _, err := c.exprType(e); err != nil {
+		c.report(tn, err)
+	}
+
+	base_tpl := c.dependency(e)
+	if base_tpl == nil || c.including[base_tpl] {
+		// Unknown until execution; check the blocks in place
+		return
+	}
+
+	c.blocks.register(c.tpl)
+	c.checkTemplate(base_tpl)
+
+	// Everything after the extends-tag is only relevant through its blocks
+	c.pos = len(c.tpl.nodes)
+}
+
+func checkInclude(c *checker, tn *tagNode) {
+	ia, err := parseIncludeArgs(tn.tagargs)
+	if err != nil {
+		c.report(tn, err)
+		return
+	}
+	if _, err := c.exprType(ia.name); err != nil {
+		c.report(tn, err)
+	}
+
+	// See includeArgs.includeContext
+	scope := make(map[string]reflect.Type, len(ia.with_keys))
+	for idx, key := range ia.with_keys {
+		t, err := c.exprType(ia.with_exprs[idx])
+		if err != nil {
+			c.report(tn, err)
+		}
+		scope[key] = t
+	}
+
+	dep_tpl := c.dependency(ia.name)
+	if dep_tpl == nil || c.including[dep_tpl] {
+		return
+	}
+
+	outer_shape, outer_scopes, outer_blocks := c.shape, c.scopes, c.blocks
+	if ia.only {
+		c.shape = &contextShape{}
+		c.scopes = []map[string]reflect.Type{scope}
+	} else {
+		c.scopes = append(c.scopes[:len(c.scopes):len(c.scopes)], scope)
+	}
+	// An included template has its own blocks
+	c.blocks = make(blockOverrides)
+
+	c.checkTemplate(dep_tpl)
+
+	c.shape, c.scopes, c.blocks = outer_shape, outer_scopes, outer_blocks
+}
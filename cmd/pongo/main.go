@@ -0,0 +1,322 @@
+// Command pongo generates Go code from pongo templates, extracts their
+// translatable messages and documents the filters.
+//
+// Usage:
+//
+//     pongo gen -type importpath.Type [-pkg importpath] [-o file] template.html[=Type]...
+//     pongo extract [-o file.pot] template.html...
+//     pongo filters [-o file.md]
+//
+// gen generates a render function for every template which is named after the
+// template's file name (user_profile.html becomes RenderUserProfile), see
+// pongo.GenerateGo. Templates referenced by extends/include tags are looked up
+// relative to the directory of the referencing template. The templates are
+// bound to the struct type given by -type (like example.com/app/views.Page)
+// or to another type of its package given after the template's file name
+// (like user_profile.html=Profile). The code is generated for the package
+// given by -pkg (defaults to the type's package) by a temporary program in
+// this package's directory which is run with the build tag pongo_gen (so a
+// previously generated file doesn't need to compile), see "go run".
+//
+// extract writes the messages of the templates' trans- and blocktrans-tags and
+// _("...") calls as gettext template (POT), see pongo.Template.Messages.
+//
+// filters writes a reference of the built-in filters (as Markdown) generated
+// from their specs, see pongo.FilterSpec.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/flosch/pongo"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: pongo gen -type importpath.Type [-pkg importpath] [-o file] template.html[=Type]...\n")
+	fmt.Fprintf(os.Stderr, "       pongo extract [-o file.pot] template.html...\n")
+	fmt.Fprintf(os.Stderr, "       pongo filters [-o file.md]\n")
+	os.Exit(2)
+}
+
+// funcName returns the name of the render function of the given template file.
+func funcName(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	name := "Render"
+	upper := true
+	for _, r := range base {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name += string(r)
+	}
+	return name
+}
+
+// genMain is the program generating the code (run by gen); it's formatted
+// with the import path of the data types' package, the templates, the
+// import path and the name of the generated package.
+const genMain = `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/flosch/pongo"
+	data %s
+)
+
+func main() {
+	funcs := make(map[string]*pongo.Template)
+	for _, t := range []struct {
+		name, path string
+		data       interface{}
+	}{
+%s	} {
+		tpl, err := pongo.FromFile(t.path, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tpl.Bind(t.data); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		funcs[t.name] = tpl
+	}
+
+	src, err := pongo.GenerateGo(%s, %s, funcs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(src)
+}
+`
+
+// goList returns the name and the directory of the package pkg_path.
+func goList(pkg_path string) (name string, dir string, err error) {
+	out, err := exec.Command("go", "list", "-tags", "pongo_gen", "-f", "{{.Name}}\n{{.Dir}}", pkg_path).Output()
+	if err != nil {
+		if exit_err, is_exit_err := err.(*exec.ExitError); is_exit_err {
+			return "", "", fmt.Errorf("Package '%s' not found: %s", pkg_path, strings.TrimSpace(string(exit_err.Stderr)))
+		}
+		return "", "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		return "", "", fmt.Errorf("Package '%s' not found", pkg_path)
+	}
+	return lines[0], lines[1], nil
+}
+
+func gen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	data_type := flags.String("type", "", "struct type of the templates' data (like example.com/app/views.Page)")
+	pkg := flags.String("pkg", "", "import path of the generated package (default: the type's package)")
+	output := flags.String("o", "", "output file (default: stdout)")
+	flags.Usage = usage
+	flags.Parse(args)
+
+	dot := strings.LastIndex(*data_type, ".")
+	if flags.NArg() == 0 || dot <= strings.LastIndex(*data_type, "/") {
+		usage()
+	}
+	data_pkg, type_name := (*data_type)[:dot], (*data_type)[dot+1:]
+	if *pkg == "" {
+		*pkg = data_pkg
+	}
+
+	var templates bytes.Buffer
+	names := make(map[string]bool)
+	for _, arg := range flags.Args() {
+		path, name := arg, type_name
+		if idx := strings.LastIndex(arg, "="); idx >= 0 {
+			path, name = arg[:idx], arg[idx+1:]
+		}
+		fn := funcName(path)
+		if names[fn] {
+			return fmt.Errorf("Templates '%s' and another one would both be rendered by %s", path, fn)
+		}
+		names[fn] = true
+		abs_path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&templates, "\t\t{%s, %s, (*data.%s)(nil)},\n", strconv.Quote(fn), strconv.Quote(abs_path), name)
+	}
+
+	pkg_name, pkg_dir, err := goList(*pkg)
+	if err != nil {
+		return err
+	}
+
+	// The program is run in the generated package's directory, so it's
+	// built within the same module (or GOPATH)
+	tmp_dir, err := ioutil.TempDir(pkg_dir, "pongo_gen")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp_dir)
+	main_src := fmt.Sprintf(genMain, strconv.Quote(data_pkg), templates.String(), strconv.Quote(*pkg), strconv.Quote(pkg_name))
+	if err := ioutil.WriteFile(filepath.Join(tmp_dir, "main.go"), []byte(main_src), 0644); err != nil {
+		return err
+	}
+
+	var src bytes.Buffer
+	cmd := exec.Command("go", "run", "-tags", "pongo_gen", "./"+filepath.Base(tmp_dir))
+	cmd.Dir = pkg_dir
+	cmd.Stdout = &src
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Generating the code failed: %s", err)
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(src.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*output, src.Bytes(), 0644)
+}
+
+// poQuote quotes str like a string of a PO file.
+func poQuote(str string) string {
+	str = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(str)
+	return `"` + str + `"`
+}
+
+func extract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	output := flags.String("o", "", "output file (default: stdout)")
+	flags.Usage = usage
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		usage()
+	}
+
+	// Messages are merged by their ids, their references are collected
+	type entry struct {
+		msg  pongo.ExtractedMessage
+		refs []string
+	}
+	entries := make([]*entry, 0, 50)
+	index := make(map[string]*entry)
+	for _, path := range flags.Args() {
+		tpl, err := pongo.FromFile(path, nil)
+		if err != nil {
+			return err
+		}
+		messages, err := tpl.Messages()
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			key := msg.ID + "\x00" + msg.Plural
+			e, has_entry := index[key]
+			if !has_entry {
+				e = &entry{msg: msg}
+				index[key] = e
+				entries = append(entries, e)
+			}
+			e.refs = append(e.refs, fmt.Sprintf("%s:%d", filepath.ToSlash(path), msg.Line))
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	buf.WriteString(`"Content-Type: text/plain; charset=UTF-8\n"` + "\n")
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "\n#: %s\n", strings.Join(e.refs, " "))
+		if strings.Contains(e.msg.ID+e.msg.Plural, "%(") {
+			buf.WriteString("#, python-format\n")
+		}
+		fmt.Fprintf(&buf, "msgid %s\n", poQuote(e.msg.ID))
+		if e.msg.Plural != "" {
+			fmt.Fprintf(&buf, "msgid_plural %s\nmsgstr[0] \"\"\nmsgstr[1] \"\"\n", poQuote(e.msg.Plural))
+		} else {
+			buf.WriteString("msgstr \"\"\n")
+		}
+	}
+
+	if *output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*output, buf.Bytes(), 0644)
+}
+
+func filters(args []string) error {
+	flags := flag.NewFlagSet("filters", flag.ExitOnError)
+	output := flags.String("o", "", "output file (default: stdout)")
+	flags.Usage = usage
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		usage()
+	}
+
+	names := make([]string, 0, len(pongo.Filters))
+	for name := range pongo.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Filters\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\n## %s\n\n", name)
+		spec, has_spec := pongo.FilterSpecs[name]
+		if !has_spec {
+			buf.WriteString("Not documented.\n")
+			continue
+		}
+		fmt.Fprintf(&buf, "    {{ value|%s }}\n\n%s\n", spec.Usage(name), spec.Doc)
+		if spec.SafePreserving {
+			buf.WriteString("\nThe result is safe if the value is safe.\n")
+		}
+	}
+
+	if *output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*output, buf.Bytes(), 0644)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = gen(os.Args[2:])
+	case "extract":
+		err = extract(os.Args[2:])
+	case "filters":
+		err = filters(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,336 @@
+package pongo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A compiledNode renders a part of a compiled template into out.
+type compiledNode func(execCtx *executionContext, ctx *Context, out *strings.Builder) error
+
+// A program is a template compiled into a tree of closures. Unlike the
+// interpreter (see executionContext.execute) it doesn't look up end-tags by
+// name or re-parse tag arguments on every execution; all jumps (like if/else)
+// are resolved at compile time.
+//
+// Tags without a Compile function are executed by the interpreter, so custom
+// tags keep working. Their bodies are skipped by their Ignore function; a
+// template using a tag which has neither isn't compiled at all (as the end of
+// the tag's body isn't known).
+type program struct {
+	nodes  []compiledNode
+	blocks map[int][]compiledNode // bodies of all blocks, indexed by the position of their block-tag
+}
+
+// errStopExecution is returned by a compiledNode if the rest of the template
+// must not be rendered (e. g. after an extends-tag).
+var errStopExecution = errors.New("Execution stopped")
+
+type compiler struct {
+	tpl    *Template
+	pos    int // position of the node which is currently compiled
+	depth  int // nesting level of bodies (like the content of an if-tag)
+	blocks map[int][]compiledNode
+
+	unsupported bool // whether the template must be interpreted (see program)
+}
+
+// compile compiles the parsed template. Errors (like a missing end-tag) are
+// not reported at compile time but when the affected node is executed, the
+// same way the interpreter does.
+func (tpl *Template) compile() {
+	c := &compiler{
+		tpl:    tpl,
+		blocks: make(map[int][]compiledNode),
+	}
+
+	nodes := make([]compiledNode, 0, len(tpl.nodes))
+	for ; c.pos < len(tpl.nodes); c.pos++ {
+		nodes = append(nodes, c.compileNode())
+	}
+	if c.unsupported {
+		tpl.program = nil
+		return
+	}
+
+	tpl.program = &program{
+		nodes:  nodes,
+		blocks: c.blocks,
+	}
+}
+
+// compileUntilAnyTagNode compiles all nodes following the current one until
+// one of the given tags is reached (like executeUntilAnyTagNode does).
+func (c *compiler) compileUntilAnyTagNode(nodenames ...string) ([]compiledNode, *tagNode, error) {
+	var nodes []compiledNode
+
+	c.depth++
+	defer func() { c.depth-- }()
+
+	for c.pos++; c.pos < len(c.tpl.nodes); c.pos++ {
+		if tn, is_tag := c.tpl.nodes[c.pos].(*tagNode); is_tag {
+			for _, name := range nodenames {
+				if tn.tagname == name {
+					return nodes, tn, nil
+				}
+			}
+		}
+		nodes = append(nodes, c.compileNode())
+	}
+
+	// One nodename MUST be found! Otherwise error.
+	return nodes, nil, errors.New(fmt.Sprintf("No end-node (possible nodes: %v) found.", nodenames))
+}
+
+func (c *compiler) compileNode() compiledNode {
+	n := c.tpl.nodes[c.pos]
+
+	var compiled compiledNode
+	switch n := n.(type) {
+	case *contentNode:
+		content := n.content
+		compiled = func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+			out.WriteString(content)
+			return execCtx.state.write(len(content))
+		}
+	case *filterNode:
+		e := n.e
+		compiled = func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+			str, err := e.evalString(ctx)
+			if err != nil {
+				return err
+			}
+			out.WriteString(*str)
+			return execCtx.state.write(len(*str))
+		}
+	case *tagNode:
+		if n.taghandler != nil && n.taghandler.Compile != nil {
+			compiled = n.taghandler.Compile(c, n)
+		} else {
+			if n.taghandler != nil && n.taghandler.Ignore == nil {
+				// The tag might have a body which isn't known to the compiler
+				c.unsupported = true
+			}
+			compiled = c.compileInterpreted(n)
+		}
+	}
+
+	// Add position information to errors the same way the interpreter does
+	format := "[Error: %s] [Line %d Col %d (%s)] %w"
+	if c.depth > 0 {
+		format = "[Error in block-execution: %s] [Line %d Col %d (%s)] %w"
+	}
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		err := execCtx.state.check()
+		if err == nil {
+			err = compiled(execCtx, ctx, out)
+		}
+		if err != nil && err != errStopExecution {
+			return fmt.Errorf(format, execCtx.template.name, n.getLine(), n.getCol(), *n.getContent(), err)
+		}
+		return err
+	}
+}
+
+// compileInterpreted creates a node which executes the tag with the
+// interpreter. The tag spans the same nodes it skips when it's ignored.
+func (c *compiler) compileInterpreted(tn *tagNode) compiledNode {
+	pos := c.pos
+	if tn.taghandler != nil && tn.taghandler.Ignore != nil {
+		ignoreCtx := newExecutionContext(c.tpl, nil)
+		ignoreCtx.node_pos = pos
+		tn.taghandler.Ignore(&tn.tagargs, ignoreCtx)
+		c.pos = ignoreCtx.node_pos
+	}
+
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		execCtx.node_pos = pos
+		str, err := tn.execute(execCtx, ctx)
+		if err != nil {
+			return err
+		}
+		out.WriteString(*str)
+		if execCtx.node_pos >= len(execCtx.template.nodes) {
+			// The tag consumed the rest of the template (like extends)
+			return errStopExecution
+		}
+		return nil
+	}
+}
+
+// compileSingle compiles a tag without a body (like include) which is executed
+// by the interpreter.
+func compileSingle(c *compiler, tn *tagNode) compiledNode {
+	return c.compileInterpreted(tn)
+}
+
+// compileFailed creates a node which returns err when it's executed.
+func compileFailed(err error) compiledNode {
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		return err
+	}
+}
+
+func runCompiled(nodes []compiledNode, execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+	for _, n := range nodes {
+		if err := n(execCtx, ctx, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (execCtx *executionContext) executeCompiled(ctx *Context) (*string, error) {
+	var out strings.Builder
+	err := runCompiled(execCtx.template.program.nodes, execCtx, ctx, &out)
+	if err != nil && err != errStopExecution {
+		return nil, err
+	}
+	outputString := out.String()
+	return &outputString, nil
+}
+
+// compileBody compiles the body of a tag ending with one of the given
+// end-tags and optionally continuing with an else-tag, e. g.
+//     {% if ... %}body{% else %}else_body{% endif %}
+// If an end-tag is missing, the bodies contain all nodes compiled so far
+// and err is returned; it must be reported after executing the bodies (as
+// the interpreter does).
+func (c *compiler) compileBody(end_name string, with_else bool) (body []compiledNode, else_body []compiledNode, err error) {
+	nodenames := []string{end_name}
+	if with_else {
+		nodenames = []string{"else", end_name}
+	}
+
+	body, end, err := c.compileUntilAnyTagNode(nodenames...)
+	if err != nil || end.tagname != "else" {
+		return body, nil, err
+	}
+	else_body, _, err = c.compileUntilAnyTagNode(end_name)
+	return body, else_body, err
+}
+
+func compileIf(c *compiler, tn *tagNode) compiledNode {
+	then_nodes, else_nodes, end_err := c.compileBody("endif", true)
+
+	args := strings.TrimSpace(tn.tagargs)
+	if len(args) == 0 {
+		return compileFailed(errors.New("If-argument is empty."))
+	}
+	cond, err := parseCondition(args)
+	if err != nil {
+		return compileFailed(err)
+	}
+
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		evaled, err := cond.eval(ctx)
+		if err != nil {
+			return err
+		}
+		if isTrue(evaled) {
+			err = runCompiled(then_nodes, execCtx, ctx, out)
+		} else {
+			err = runCompiled(else_nodes, execCtx, ctx, out)
+		}
+		if err != nil {
+			return err
+		}
+		return end_err
+	}
+}
+
+func compileFor(c *compiler, tn *tagNode) compiledNode {
+	body, empty, end_err := c.compileBody("endfor", true)
+
+	fa, err := parseForArgs(tn.tagargs)
+	if err != nil {
+		return compileFailed(err)
+	}
+
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		return fa.loop(execCtx.state, ctx, func() error {
+			if err := runCompiled(body, execCtx, ctx, out); err != nil {
+				return err
+			}
+			return end_err
+		}, func() error {
+			if err := runCompiled(empty, execCtx, ctx, out); err != nil {
+				return err
+			}
+			return end_err
+		})
+	}
+}
+
+func compileBlock(c *compiler, tn *tagNode) compiledNode {
+	// The body is rendered by tagBlock (see renderBlock), maybe as part of
+	// a child template
+	pos := c.pos
+	body, _, err := c.compileUntilAnyTagNode("endblock")
+	if err == nil {
+		c.blocks[pos] = body
+	}
+	c.pos = pos
+	return c.compileInterpreted(tn)
+}
+
+func compileTrim(c *compiler, tn *tagNode) compiledNode {
+	body, _, end_err := c.compileBody("endtrim", false)
+
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		var body_out strings.Builder
+		if err := runCompiled(body, execCtx, ctx, &body_out); err != nil {
+			return err
+		}
+		if end_err != nil {
+			return end_err
+		}
+		out.WriteString(strings.TrimSpace(body_out.String()))
+		return nil
+	}
+}
+
+func compileRemove(c *compiler, tn *tagNode) compiledNode {
+	body, _, end_err := c.compileBody("endremove", false)
+	patterns, patterns_err := parseRemovePatterns(tn.tagargs)
+
+	return func(execCtx *executionContext, ctx *Context, out *strings.Builder) error {
+		var body_out strings.Builder
+		if err := runCompiled(body, execCtx, ctx, &body_out); err != nil {
+			return err
+		}
+		if end_err != nil {
+			return end_err
+		}
+		if patterns_err != nil {
+			return patterns_err
+		}
+		str, err := removePatterns(body_out.String(), patterns, ctx)
+		if err != nil {
+			return err
+		}
+		out.WriteString(*str)
+		return nil
+	}
+}
+
+// compiledBlock returns the compiled body of the block whose block-tag is at
+// node position pos.
+func (tpl *Template) compiledBlock(pos int) ([]compiledNode, bool) {
+	if tpl.program == nil {
+		return nil, false
+	}
+	body, has_body := tpl.program.blocks[pos]
+	return body, has_body
+}
+
+// executeInterpreted executes the template without using the compiled program
+// (used to compare both).
+func (tpl *Template) executeInterpreted(ctx *Context) (out *string, err error) {
+	defer tpl.recoverPanic(&out, &err)
+	execCtx := newExecutionContext(tpl, nil)
+	execCtx.interpret = true
+	execCtx.state = tpl.newRenderState(nil)
+	return tpl.execute(ctx, execCtx)
+}
@@ -3,3 +3,21 @@ package pongo
 // A Context is used to pass data to the template. You can pass whatever you
 // want in interface{}.
 type Context map[string]interface{}
+
+// Methods called from a template which take a context.Context as their first
+// parameter (like Comments(ctx context.Context, limit int)) receive the
+// context.Context stored under this key; context.Background() is used if
+// there is none. The key is not accessible from within templates.
+const GoContextKey = "@context"
+
+// The root value passed to Template.ExecuteValue is stored under this key;
+// identifiers which aren't part of the Context are resolved against it.
+const rootValueKey = "@root"
+
+// The Sandbox of the executed template is stored under this key (see
+// Template.execute).
+const sandboxKey = "@sandbox"
+
+// The renderState of the execution is stored under this key (so filters can
+// check the limits, see Template.execute).
+const renderStateKey = "@state"
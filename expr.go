@@ -1,8 +1,10 @@
 package pongo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -10,9 +12,419 @@ import (
 )
 
 var exprIdentChecker = regexp.MustCompile("^[A-Za-z0-9_]+[A-Za-z0-9_.]*$")
+var exprIdentPartChecker = regexp.MustCompile("^[A-Za-z0-9_]+$")
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	goContextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
 
 type exprIdent string
 
+// identPart is one part of an identifier, e. g. 'Name', 'Greeting("Hi", name)'
+// or '[i + 1]' in user.Greeting("Hi", name).Friends[i + 1].Name
+//
+// Identifiers are split once at parse time, so executing a template doesn't
+// need to split and convert them over and over again.
+type identPart struct {
+	name         string
+	specifier    interface{} // name converted by convertTypeString (int or exprIdent); nil if invalid
+	is_call      bool
+	args         []*expr // arguments of a call
+	is_subscript bool
+	subscript    arithNode
+}
+
+// matchingClose returns the index of the bracket closing the one opened at
+// in[open] (or -1 if it isn't closed). Strings and nested parentheses or
+// brackets are skipped.
+func matchingClose(in string, open int) int {
+	in_string := false
+	depth := 0
+	for pos := open; pos < len(in); pos++ {
+		c := in[pos]
+		if in_string {
+			if c == '\\' {
+				pos++ // skip escaped char
+			} else if c == '"' {
+				in_string = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			in_string = true
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth == 0 {
+				return pos
+			}
+		}
+	}
+	return -1
+}
+
+func splitIdent(name exprIdent) ([]identPart, error) {
+	raw_parts := splitOutside(string(name), '.')
+	parts := make([]identPart, 0, len(raw_parts))
+
+	for _, raw_part := range raw_parts {
+		part := identPart{name: raw_part}
+		rest := ""
+		raw_args := []string{}
+
+		if bracket := strings.IndexAny(raw_part, "(["); bracket >= 0 {
+			part.name = raw_part[:bracket]
+			rest = raw_part[bracket:]
+		}
+
+		if strings.HasPrefix(rest, "(") {
+			end := matchingClose(rest, 0)
+			if end < 0 {
+				return nil, errors.New(fmt.Sprintf("Identifier ('%s') has an unclosed parenthesis", name))
+			}
+			part.is_call = true
+
+			args := rest[1:end]
+			if strings.HasSuffix(strings.TrimSpace(args), ",") {
+				return nil, errors.New(fmt.Sprintf("Empty argument in method call '%s'", raw_part))
+			}
+			for _, arg := range *splitArgs(&args, ",") {
+				arg = strings.TrimSpace(arg)
+				if arg == "" {
+					return nil, errors.New(fmt.Sprintf("Empty argument in method call '%s'", raw_part))
+				}
+				raw_args = append(raw_args, arg)
+			}
+			rest = rest[end+1:]
+		}
+
+		if len(strings.TrimSpace(part.name)) == 0 {
+			return nil, errors.New("Specifier is empty!")
+		}
+		if !exprIdentPartChecker.MatchString(part.name) {
+			return nil, errors.New(fmt.Sprintf("Identifier ('%s') must only contain A-Za-z0-9_", name))
+		}
+		for _, arg := range raw_args {
+			e, err := newExpr(&arg)
+			if err != nil {
+				return nil, err
+			}
+			part.args = append(part.args, e)
+		}
+		if !part.is_call {
+			part.specifier = convertSpecifier(part.name)
+		}
+		parts = append(parts, part)
+
+		// Subscripts, e. g. items[i + 1] or grid[0][1]
+		for len(rest) > 0 {
+			if rest[0] != '[' {
+				return nil, errors.New(fmt.Sprintf("Identifier ('%s') must only contain A-Za-z0-9_", name))
+			}
+			end := matchingClose(rest, 0)
+			if end < 0 {
+				return nil, errors.New(fmt.Sprintf("Identifier ('%s') has an unclosed bracket", name))
+			}
+			raw_subscript := strings.TrimSpace(rest[1:end])
+			if raw_subscript == "" {
+				return nil, errors.New(fmt.Sprintf("Empty subscript in identifier '%s'", name))
+			}
+			subscript, err := parseArith(raw_subscript)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, identPart{name: raw_subscript, is_subscript: true, subscript: subscript})
+			rest = rest[end+1:]
+		}
+	}
+
+	return parts, nil
+}
+
+// convertSpecifier converts a (valid) part of an identifier like
+// convertTypeString does: 0 becomes an int, true a bool and name an exprIdent.
+// It returns nil for invalid numbers like 1a.
+func convertSpecifier(name string) interface{} {
+	switch {
+	case name == "true" || name == "false":
+		return name == "true"
+	case name[0] >= '0' && name[0] <= '9':
+		i, err := strconv.Atoi(name)
+		if err != nil {
+			return nil
+		}
+		return i
+	}
+	return exprIdent(name)
+}
+
+// callMethod calls the method (or function) fn with the given arguments; every
+// argument is converted to the type of the respective parameter if possible.
+// If the first parameter is a context.Context, it's passed implicitly (see
+// GoContextKey). Methods may return a single value or (value, error); a
+// non-nil error aborts the execution.
+func callMethod(name string, fn reflect.Value, args []interface{}, ctx *Context) (interface{}, error) {
+	ft := fn.Type()
+
+	if ft.NumIn() > 0 && ft.In(0) == goContextType && (len(args) == 0 || !isGoContext(args[0])) {
+		var go_ctx interface{} = context.Background()
+		if c, has_ctx := (*ctx)[GoContextKey]; has_ctx && c != nil {
+			go_ctx = c
+		}
+		args = append([]interface{}{go_ctx}, args...)
+	}
+
+	if ft.IsVariadic() {
+		if len(args) < ft.NumIn()-1 {
+			return nil, errors.New(fmt.Sprintf("Method '%s' requires at least %d argument(s), %d given.", name, ft.NumIn()-1, len(args)))
+		}
+	} else if len(args) != ft.NumIn() {
+		return nil, errors.New(fmt.Sprintf("Method '%s' requires %d argument(s), %d given.", name, ft.NumIn(), len(args)))
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+	for idx, arg := range args {
+		var t reflect.Type
+		if ft.IsVariadic() && idx >= ft.NumIn()-1 {
+			t = ft.In(ft.NumIn() - 1).Elem()
+		} else {
+			t = ft.In(idx)
+		}
+
+		v, err := convertArgument(arg, t)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Argument %d of method '%s': %s", idx+1, name, err.Error()))
+		}
+		in = append(in, v)
+	}
+
+	results := fn.Call(in)
+	switch {
+	case len(results) == 0:
+		return "", nil
+	case len(results) == 2 && ft.Out(1) == errorType:
+		if err, _ := results[1].Interface().(error); err != nil {
+			return nil, fmt.Errorf("Method '%s' returned an error: %w", name, err)
+		}
+	case len(results) > 1:
+		return nil, errors.New(fmt.Sprintf("Method '%s' returns more than one value (only a single value or (value, error) are supported).", name))
+	}
+	if !results[0].CanInterface() {
+		return "", nil
+	}
+
+	return results[0].Interface(), nil
+}
+
+func isGoContext(value interface{}) bool {
+	_, is_ctx := value.(context.Context)
+	return is_ctx
+}
+
+// convertArgument converts value into a value of type t. Conversions between
+// ints, floats and strings (and bools from/to strings) are allowed as long
+// as no information gets lost.
+func convertArgument(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, errors.New(fmt.Sprintf("nil can't be used as %s", t))
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+
+	mismatch := errors.New(fmt.Sprintf("%v (%T) can't be used as %s", value, value, t))
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > math.MaxInt64 {
+				return reflect.Value{}, mismatch
+			}
+			i = int64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			if rv.Float() != math.Trunc(rv.Float()) {
+				return reflect.Value{}, mismatch
+			}
+			i = int64(rv.Float())
+		case reflect.String:
+			var err error
+			i, err = strconv.ParseInt(rv.String(), 10, 64)
+			if err != nil {
+				return reflect.Value{}, mismatch
+			}
+		default:
+			return reflect.Value{}, mismatch
+		}
+		v := reflect.New(t).Elem()
+		if v.OverflowInt(i) {
+			return reflect.Value{}, mismatch
+		}
+		v.SetInt(i)
+		return v, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if rv.Int() < 0 {
+				return reflect.Value{}, mismatch
+			}
+			u = uint64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u = rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			if rv.Float() < 0 || rv.Float() != math.Trunc(rv.Float()) {
+				return reflect.Value{}, mismatch
+			}
+			u = uint64(rv.Float())
+		case reflect.String:
+			var err error
+			u, err = strconv.ParseUint(rv.String(), 10, 64)
+			if err != nil {
+				return reflect.Value{}, mismatch
+			}
+		default:
+			return reflect.Value{}, mismatch
+		}
+		v := reflect.New(t).Elem()
+		if v.OverflowUint(u) {
+			return reflect.Value{}, mismatch
+		}
+		v.SetUint(u)
+		return v, nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		case reflect.String:
+			var err error
+			f, err = strconv.ParseFloat(rv.String(), 64)
+			if err != nil {
+				return reflect.Value{}, mismatch
+			}
+		default:
+			return reflect.Value{}, mismatch
+		}
+		v := reflect.New(t).Elem()
+		v.SetFloat(f)
+		return v, nil
+
+	case reflect.String:
+		var str string
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			str = strconv.FormatInt(rv.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			str = strconv.FormatUint(rv.Uint(), 10)
+		case reflect.Float32, reflect.Float64:
+			str = strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+		case reflect.Bool:
+			str = strconv.FormatBool(rv.Bool())
+		case reflect.String:
+			str = rv.String()
+		default:
+			return reflect.Value{}, mismatch
+		}
+		v := reflect.New(t).Elem()
+		v.SetString(str)
+		return v, nil
+
+	case reflect.Bool:
+		var b bool
+		switch rv.Kind() {
+		case reflect.Bool:
+			b = rv.Bool()
+		case reflect.String:
+			var err error
+			b, err = strconv.ParseBool(rv.String())
+			if err != nil {
+				return reflect.Value{}, mismatch
+			}
+		default:
+			return reflect.Value{}, mismatch
+		}
+		v := reflect.New(t).Elem()
+		v.SetBool(b)
+		return v, nil
+	}
+
+	if rv.Type().ConvertibleTo(t) && rv.Kind() == t.Kind() {
+		// Named types of the same kind (e. g. a type Names []string)
+		return rv.Convert(t), nil
+	}
+
+	return reflect.Value{}, mismatch
+}
+
+// evalCallArgs evaluates the arguments of a method call.
+func evalCallArgs(arg_exprs []*expr, ctx *Context) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(arg_exprs))
+	for _, e := range arg_exprs {
+		arg, err := e.evalValue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// lookupCallable returns the method name of value (or a function stored in
+// a struct field or a map) to be called.
+func lookupCallable(unresolved_value interface{}, name string) reflect.Value {
+	if unresolved_value == nil {
+		return reflect.Value{}
+	}
+
+	if m := lookupMethod(reflect.ValueOf(unresolved_value), name); m.IsValid() {
+		return m
+	}
+
+	rv := reflect.ValueOf(unresolved_value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+
+	var fn reflect.Value
+	switch rv.Kind() {
+	case reflect.Struct:
+		fn = lookupField(rv, name)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			fn = rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		}
+	}
+	for fn.IsValid() && fn.Kind() == reflect.Interface && !fn.IsNil() {
+		fn = fn.Elem()
+	}
+	if !fn.IsValid() || fn.Kind() != reflect.Func || fn.IsNil() || !fn.CanInterface() {
+		return reflect.Value{}
+	}
+	return fn
+}
+
 type exprFilterFunc struct {
 	name string
 	fn   FilterFunc
@@ -25,6 +437,7 @@ type expr struct {
 	raw string
 
 	root      interface{}
+	ident     []identPart // root split into its parts (if root is an exprIdent)
 	root_args []reflect.Value
 	filters   []exprFilterFunc
 	negate    bool
@@ -33,46 +446,248 @@ type expr struct {
 func resolvePointer(v reflect.Value) reflect.Value {
 	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		e := v.Elem()
-		if e.CanInterface() && e.IsValid() {
+		if e.IsValid() && e.CanInterface() {
 			return e
 		}
-		panic("not implemented - todo")
+		// Nil pointers are kept (a filter like date reports them)
 	}
 	return v
 }
 
+// normalizeIndex turns a negative index (counted from the end) into a
+// positive one and checks whether it's in range.
+func normalizeIndex(idx, length int) (int, bool) {
+	if idx < 0 {
+		idx += length
+	}
+	return idx, idx >= 0 && idx < length
+}
+
+// mapIndex returns the element of the map rv stored under key (which is
+// converted to the map's key type first) or an invalid reflect.Value if
+// there is none.
+func mapIndex(rv reflect.Value, key interface{}) reflect.Value {
+	rkey, err := convertArgument(key, rv.Type().Key())
+	if err != nil {
+		return reflect.Value{}
+	}
+	return rv.MapIndex(rkey)
+}
+
+// indexValue implements subscripts: slices, arrays and strings are indexed by
+// an int (negative indexes count from the end), maps by a key of any type
+// convertible to the map's key type and structs by a field name.
+func indexValue(rv reflect.Value, key interface{}) reflect.Value {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice, reflect.String:
+		rkey, err := convertArgument(key, reflect.TypeOf(0))
+		if err != nil {
+			return reflect.Value{}
+		}
+		idx, in_range := normalizeIndex(int(rkey.Int()), rv.Len())
+		if !in_range {
+			return reflect.Value{}
+		}
+		if rv.Kind() == reflect.String {
+			str := rv.String()
+			return reflect.ValueOf(str[idx : idx+1])
+		}
+		return rv.Index(idx)
+	case reflect.Map:
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		return mapIndex(rv, key)
+	case reflect.Struct:
+		name, is_str := key.(string)
+		if !is_str {
+			return reflect.Value{}
+		}
+		return lookupField(rv, name)
+	}
+	return reflect.Value{}
+}
+
+// lookupRoot resolves name against the root value (see ExecuteValue): a method,
+// a field of a struct or a key of a string-keyed map.
+func lookupRoot(ctx *Context, name string) (value interface{}, is_method bool, found bool) {
+	root, has_root := (*ctx)[rootValueKey]
+	if !has_root || root == nil {
+		return nil, false, false
+	}
+
+	rv := reflect.ValueOf(root)
+	if m := lookupMethod(rv, name); m.IsValid() {
+		return m.Interface(), true, true
+	}
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false, false
+		}
+		rv = rv.Elem()
+	}
+
+	var field reflect.Value
+	switch rv.Kind() {
+	case reflect.Struct:
+		field = lookupField(rv, name)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			field = mapIndex(rv, name)
+		}
+	}
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, false, false
+	}
+	return field.Interface(), false, true
+}
+
 func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
-	parts := strings.Split(string(name), ".")
+	parts, err := splitIdent(name)
+	if err != nil {
+		return nil, err
+	}
+	return resolveIdentParts(name, parts, ctx)
+}
+
+// resolveIdentParts resolves the (already split) identifier name.
+func resolveIdentParts(name exprIdent, parts []identPart, ctx *Context) (interface{}, error) {
+	value, _, err := resolveIdentPointer(name, parts, ctx)
+	return value, err
+}
 
+// resolveIdentPointer resolves the identifier name like resolveIdentParts, but
+// returns the value before dereferencing a pointer (see resolvePointer) as well.
+func resolveIdentPointer(name exprIdent, parts []identPart, ctx *Context) (value interface{}, unresolved interface{}, err error) {
 	if len(parts) == 0 {
-		return nil, errors.New("Identifier is emtpy")
+		return nil, nil, errors.New("Identifier is emtpy")
 	}
 
 	// Get first item from context
-	ctxname := parts[0]
+	root := parts[0]
 	parts = parts[1:]
 
-	var value interface{}
 
-	content, has := (*ctx)[ctxname]
+	sandbox := sandboxOf(ctx)
+	is_method := false
+	is_builtin := false
+	content, has := (*ctx)[root.name]
 	if !has {
-		// If the identifier is not found
-		// TODO add error in strict mode
-		// fmt.Printf("Identifier '%v' NOT found in context (assuming empty string), but continuing. Skipping any further specifier.\n", ctxname)
-		return "", nil
+		content, is_method, has = lookupRoot(ctx, root.name)
+		if !has && root.name == "_" {
+			// Translation function, e. g. {{ _("Hello") }} (see Translator)
+			content, has, is_builtin = translateFunc(ctx), true, true
+		}
+		if !has {
+			// If the identifier is not found
+			// TODO add error in strict mode
+			// fmt.Printf("Identifier '%v' NOT found in context (assuming empty string), but continuing. Skipping any further specifier.\n", root.name)
+			return "", "", nil
+		}
+		if is_method {
+			if err := sandbox.checkCall(reflect.TypeOf((*ctx)[rootValueKey]), root.name); err != nil {
+				return nil, nil, err
+			}
+		}
+		if is_method && !root.is_call {
+			if len(parts) == 0 {
+				// Return the method reference to allow a call with arguments
+				// (see evalValue)
+				return reflect.ValueOf(content), reflect.ValueOf(content), nil
+			}
+			result, err := callMethod(root.name, reflect.ValueOf(content), nil, ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			content = result
+		}
+	}
+	if root.is_call {
+		// Function stored in the Context, e. g. {{ greet("Florian") }}
+		fn := reflect.ValueOf(content)
+		if fn.Kind() != reflect.Func || fn.IsNil() {
+			return nil, nil, errors.New(fmt.Sprintf("'%s' (%T) is not a function and can't be called", root.name, content))
+		}
+		if !is_method && !is_builtin {
+			if err := sandbox.checkCall(nil, root.name); err != nil {
+				return nil, nil, err
+			}
+		}
+		args, err := evalCallArgs(root.args, ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err = callMethod(root.name, fn, args, ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if content == nil {
+		return nil, nil, nil
 	}
 	unresolved_value := content // Is needed for receiver-bounded methods (pointer <-> value)
 	value = resolvePointer(reflect.ValueOf(content)).Interface()
 
-	for idx_specifier, raw_specifier := range parts {
-		if len(strings.TrimSpace(raw_specifier)) == 0 {
-			return nil, errors.New("Specifier is empty!")
+	for idx_specifier, part := range parts {
+		raw_specifier := part.name
+
+		if rv := reflect.ValueOf(unresolved_value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			// Nothing can be looked up on a nil pointer
+			return "", "", nil
 		}
 
-		specifier, err := convertTypeString(raw_specifier)
-		if err != nil {
+		if part.is_subscript {
+			// Subscript, e. g. items[i + 1] or m[key]
+			key, err := part.subscript.eval(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			new_value := indexValue(resolvePointer(reflect.ValueOf(value)), key)
+			if !new_value.IsValid() || !new_value.CanInterface() {
+				// TODO: Index not found? Return empty string. Maybe return an error in a future strict mode.
+				return "", "", nil
+			}
+			unresolved_value = new_value.Interface()
+			if unresolved_value == nil {
+				return nil, nil, nil
+			}
+			value = resolvePointer(new_value).Interface()
+
+			continue // Next specifier
+		}
+
+		if part.is_call {
+			// Explicit method call, e. g. user.Greeting("Hi")
+			fn := lookupCallable(unresolved_value, part.name)
+			if !fn.IsValid() {
+				// TODO: Method not found? Return empty string. Maybe return an error in a future strict mode.
+				return "", "", nil
+			}
+			if err := sandbox.checkCall(reflect.TypeOf(unresolved_value), part.name); err != nil {
+				return nil, nil, err
+			}
+			args, err := evalCallArgs(part.args, ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			result, err := callMethod(part.name, fn, args, ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			if result == nil {
+				return nil, nil, nil
+			}
+			unresolved_value = result
+			value = resolvePointer(reflect.ValueOf(result)).Interface()
+
+			continue // Next specifier
+		}
+
+		specifier := part.specifier
+		if specifier == nil {
 			fmt.Printf("Specifier '%v' not found (in '%s')\n", raw_specifier, string(name))
-			return "", nil // TODO: Specifier not found? Return empty string. Maybe return an error in a future strict mode.
+			return "", "", nil // TODO: Specifier not found? Return empty string. Maybe return an error in a future strict mode.
 		}
 
 		// Depending on the current value only a restrict subset of values is allowed:
@@ -85,9 +700,12 @@ func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
 		// Check for a method on this type and execute it if found
 		attr, is_ident := specifier.(exprIdent)
 		if is_ident && value != nil {
-			m := reflect.ValueOf(unresolved_value).MethodByName(string(attr))
+			m := lookupMethod(reflect.ValueOf(unresolved_value), string(attr))
 			if m.IsValid() {
 				// Method found
+				if err := sandbox.checkCall(reflect.TypeOf(unresolved_value), string(attr)); err != nil {
+					return nil, nil, err
+				}
 
 				// Execute method, if there is one specifier following this method call
 				// otherwise return method reference back to the caller to allow
@@ -96,32 +714,22 @@ func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
 				if idx_specifier+1 < len(parts) {
 					// Call the method to allow following the chain
 
-					// First check whether the function needs an argument, if so
-					// set the return value to an empty string
-					// (this is due here are no arguments allowed)
-
-					if m.Type().NumIn() > 0 {
-						// Arguments required
-						return "", nil
-					}
-
-					results := m.Call(nil) // No function arguments allowed
-					if len(results) > 1 {
-						return nil, errors.New(fmt.Sprintf("Method '%s' returns more than one value, this does not work.", string(attr)))
-					}
-					if len(results) == 0 {
-						return "", nil
+					// Without parentheses no arguments can be passed
+					// (use user.Greeting("Hi").Name instead)
+					result, err := callMethod(string(attr), m, nil, ctx)
+					if err != nil {
+						return nil, nil, err
 					}
-					if !results[0].CanInterface() {
-						return "", nil
+					if result == nil {
+						return nil, nil, nil
 					}
-					unresolved_value = results[0].Interface()
-					value = resolvePointer(results[0]).Interface()
+					unresolved_value = result
+					value = resolvePointer(reflect.ValueOf(result)).Interface()
 
 					continue // Next specifier
 				} else {
 					// We're at the end of the chain, return the reference to the method
-					return m, nil
+					return m, m, nil
 				}
 			}
 		}
@@ -139,17 +747,18 @@ func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
 				idx, is_int = solved_ident.(int)
 				if err != nil || !is_int {
 					fmt.Printf("If you want to access an array/slice, specifier ('%v') must be an integer (will be used as an index).\n", specifier)
-					return "", nil
+					return "", "", nil
 				}
 			}
-			if idx < 0 || idx >= rv.Len() { // out of range
-				return "", nil
+			idx, in_range := normalizeIndex(idx, rv.Len())
+			if !in_range {
+				return "", "", nil
 			}
 			new_value := rv.Index(idx)
 			if !new_value.IsValid() || !new_value.CanInterface() {
-				return "", nil
+				return "", "", nil
 			}
-			unresolved_value = new_value
+			unresolved_value = new_value.Interface()
 			value = resolvePointer(new_value).Interface()
 
 		case reflect.String:
@@ -161,48 +770,50 @@ func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
 				idx, is_int = solved_ident.(int)
 				if err != nil || !is_int {
 					fmt.Printf("If you want to access a string, specifier ('%v') must be an integer (will be used as an index).\n", specifier)
-					return "", nil
+					return "", "", nil
 				}
 			}
-			str, is_str := value.(string)
-			if !is_str { // Should never evaluate to true
-				panic("internal error: detected reflect.String but type assertion to string failed")
-			}
-			if idx < 0 || idx >= len(str) { // out of range
-				return "", nil
+			str := rv.String() // might be a named string type as well
+			idx, in_range := normalizeIndex(idx, len(str))
+			if !in_range {
+				return "", "", nil
 			}
 			value = str[idx : idx+1]
+			unresolved_value = value
 
 		case reflect.Map:
 			if rv.IsNil() { // Is map, == nil?
-				return "", nil
+				return "", "", nil
 			}
 
-			// specifier must be a string
-			attr, is_ident := specifier.(exprIdent)
-			if !is_ident {
-				fmt.Printf("If you want to access a map, specifier ('%v') must be a qualified identifier.\n", specifier)
-				return "", nil
-				//break sw
+			// The specifier is used as key (converted to the map's key type,
+			// so m.42 works for a map[int]...)
+			var key interface{} = specifier
+			if attr, is_ident := specifier.(exprIdent); is_ident {
+				key = string(attr)
 			}
-			mi := rv.MapIndex(reflect.ValueOf(string(attr)))
+			mi := mapIndex(rv, key)
 			if !mi.IsValid() || !mi.CanInterface() {
 				// Map key not found or not interfaceable
 
 				// Maybe we want access the map via a key from the Context
+				_, is_ident := specifier.(exprIdent)
+				if !is_ident {
+					return "", "", nil
+				}
 				solved_ident, err := resolveIdent(exprIdent(raw_specifier), ctx)
 				key, is_str := solved_ident.(string)
 
 				if is_str {
 					// We received a string from the Context, try this as a key for the map
-					mi = rv.MapIndex(reflect.ValueOf(key))
+					mi = mapIndex(rv, key)
 				}
 
 				if err != nil || !is_str || !mi.IsValid() || !mi.CanInterface() {
-					return "", nil
+					return "", "", nil
 				}
 			}
-			unresolved_value = mi
+			unresolved_value = mi.Interface()
 			value = resolvePointer(mi).Interface()
 
 		case reflect.Struct:
@@ -212,7 +823,7 @@ func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
 				fmt.Printf("If you want to access a struct, specifier ('%v') must be a qualified identifier.\n", specifier)
 				break sw
 			}
-			new_value := rv.FieldByName(string(attr))
+			new_value := lookupField(rv, string(attr))
 			if !new_value.IsValid() || !new_value.CanInterface() {
 				// Maybe we want access the struct via a key from the Context
 				solved_ident, err := resolveIdent(exprIdent(raw_specifier), ctx)
@@ -220,26 +831,26 @@ func resolveIdent(name exprIdent, ctx *Context) (interface{}, error) {
 
 				if is_str {
 					// We received a string from the Context, try this as a key for the struct
-					new_value = rv.FieldByName(key)
+					new_value = lookupField(rv, key)
 				}
 
 				if err != nil || !is_str || !new_value.IsValid() || !new_value.CanInterface() {
 					// If new value is not valid (because it does not exist) or is not exported (can not being interfaced)
 					// return an empty string
-					return "", nil
+					return "", "", nil
 				}
 			}
-			unresolved_value = new_value
+			unresolved_value = new_value.Interface()
 			value = resolvePointer(new_value).Interface()
 
 		default:
 			// TODO: Not allowed, return empty string. Maybe return an error in a future strict mode.
 			fmt.Printf("Specifier '%v' not possible in accessing '%v' (of type %T).\n", specifier, value, value)
-			return "", nil
+			return "", "", nil
 		}
 	}
 
-	return value, nil
+	return value, unresolved_value, nil
 }
 
 func newExpr(in *string) (*expr, error) {
@@ -297,9 +908,9 @@ func convertTypeString(in string) (interface{}, error) {
 		}
 	default:
 		// Record the identifier for later lookup in the execution context
-		// Only A-Za-z0-9_ is allowed
-		if !exprIdentChecker.MatchString(in) {
-			return nil, errors.New(fmt.Sprintf("Identifier ('%s') must only contain A-Za-z0-9_", in))
+		// Only A-Za-z0-9_ is allowed (and method calls like Greeting("Hi"))
+		if _, err := splitIdent(exprIdent(in)); err != nil {
+			return nil, err
 		}
 
 		return exprIdent(in), nil
@@ -318,7 +929,7 @@ func (e *expr) parse() error {
 	}
 
 	// Split the string into its parts
-	parts := strings.Split(e.raw, "|")
+	parts := splitOutside(e.raw, '|')
 	if len(parts) == 0 {
 		return errors.New("Expression does not contain any data")
 	}
@@ -331,9 +942,9 @@ func (e *expr) parse() error {
 	}
 
 	// Check if identifier has arguments
-	if !strings.HasPrefix(root, "\"") && strings.Contains(root, ":") {
+	if colon := indexOutside(root, ':'); colon >= 0 && !strings.HasPrefix(root, "\"") {
 		// Has args
-		_args := strings.SplitN(root, ":", 2)
+		_args := []string{root[:colon], root[colon+1:]}
 		root = _args[0]
 
 		_split_args := *splitArgs(&_args[1], ",")
@@ -356,6 +967,12 @@ func (e *expr) parse() error {
 		return err
 	}
 	e.root = id
+	if name, is_ident := id.(exprIdent); is_ident {
+		e.ident, err = splitIdent(name)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Determine all filter functions and their arguments
 	for _, part := range parts[1:] {
@@ -390,6 +1007,11 @@ func (e *expr) parse() error {
 		if !has {
 			return errors.New(fmt.Sprintf("Filter '%s' not found", filtername))
 		}
+		if spec, has_spec := FilterSpecs[filtername]; has_spec {
+			if err := spec.checkArgs(filtername, args); err != nil {
+				return err
+			}
+		}
 
 		eff := exprFilterFunc{
 			name: filtername,
@@ -407,6 +1029,17 @@ func (e *expr) String() string {
 }
 
 func (e *expr) evalValue(ctx *Context) (interface{}, error) {
+	return e.eval(ctx, false)
+}
+
+// evalPointer evaluates the expression like evalValue, but a pointer the
+// identifier resolves to is kept unless filters are applied (like the items of
+// a for-loop), so methods with a pointer receiver can still be called on it.
+func (e *expr) evalPointer(ctx *Context) (interface{}, error) {
+	return e.eval(ctx, true)
+}
+
+func (e *expr) eval(ctx *Context, keep_pointer bool) (interface{}, error) {
 	// Check ctx for nil
 
 	// Execute expression
@@ -414,50 +1047,36 @@ func (e *expr) evalValue(ctx *Context) (interface{}, error) {
 
 	// If value is ident, look it up in context
 	if name, is_ident := value.(exprIdent); is_ident {
-		content, err := resolveIdent(name, ctx)
+		content, unresolved, err := resolveIdentPointer(name, e.ident, ctx)
 		if err != nil {
 			return nil, err
 		}
+		if keep_pointer && len(e.filters) == 0 && !e.negate {
+			if _, is_method := content.(reflect.Value); !is_method {
+				content = unresolved
+			}
+		}
 
 		// resolveIdent only returns a reflect.Value if there is a method to call
 		if method, is_method := content.(reflect.Value); is_method {
-			// Check whether the function gets all its required arguments, if not, set value to 
-			// an empty string (TODO: in strict mode raise an error)
-
-			mt := content.(reflect.Value).Type()
-			if len(e.root_args) != mt.NumIn() {
-				// Wrong argument count
-				// TODO: Return an error in strict mode
-				value = ""
-			} else {
-				// First see if we have to resolve some of the args
-				for idx, arg := range e.root_args {
-					// Example: {{ MsgTo:User,Msg }} with "User" and "Msg" from Context
-					if ident, is_ident := arg.Interface().(exprIdent); is_ident {
-						resolved_ident, err := resolveIdent(ident, ctx)
-						if err != nil {
-							return nil, err
-						}
-						e.root_args[idx] = reflect.ValueOf(resolved_ident)
+			// Call the method with its arguments (converted to the parameter types)
+			// Example: {{ MsgTo:User,Msg }} with "User" and "Msg" from Context
+			args := make([]interface{}, 0, len(e.root_args))
+			for _, arg := range e.root_args {
+				if ident, is_ident := arg.Interface().(exprIdent); is_ident {
+					resolved_ident, err := resolveIdent(ident, ctx)
+					if err != nil {
+						return nil, err
 					}
+					args = append(args, resolved_ident)
+				} else {
+					args = append(args, arg.Interface())
 				}
+			}
 
-				// TODO: Use .In() to see if the given arg types fit in.
-				// TODO: Return an error in strict mode
-
-				results := method.Call(e.root_args)
-				if len(results) > 1 {
-					return nil, errors.New(fmt.Sprintf("Method '%s' returns more than one value, this does not work.", string(name)))
-				}
-				if len(results) == 0 {
-					return "", nil
-				}
-				if !results[0].CanInterface() {
-					return "", nil
-				}
-
-				value = results[0].Interface()
-				//fmt.Printf("result = %v\n", value)
+			value, err = callMethod(string(name), method, args, ctx)
+			if err != nil {
+				return nil, err
 			}
 		} else {
 			value = content
@@ -465,12 +1084,22 @@ func (e *expr) evalValue(ctx *Context) (interface{}, error) {
 	}
 
 	var err error
+	sandbox := sandboxOf(ctx)
 	chainCtx := newFilterChainContext()
+	chainCtx.locale = localeOf(ctx)
+	chainCtx.translator = translatorOf(ctx)
+	chainCtx.sandbox = sandbox
+	chainCtx.state, _ = (*ctx)[renderStateKey].(*renderState)
 	for _, filter := range e.filters {
+		if err := sandbox.checkFilter(filter.name); err != nil {
+			return nil, err
+		}
 		// If there is no filter function, it only wants to be recorded in the chain-context.
 		// For example, "safe" checks whether there is already an "unsafe"-filter (or the safe-filter itself already) applied. 
 		if filter.fn != nil {
 			// Prepare arguments and see if we have one we should resolve from Context
+			// (the parsed arguments are shared by all executions)
+			args, copied := filter.args, false
 			for i := 0; i < len(filter.args); i++ {
 				if ident, is_ident := filter.args[i].(exprIdent); is_ident {
 					// Is ident, resolve it!
@@ -478,16 +1107,34 @@ func (e *expr) evalValue(ctx *Context) (interface{}, error) {
 					if err != nil {
 						return nil, err
 					}
-					filter.args[i] = resolved_ident
+					if !copied {
+						args, copied = append([]interface{}(nil), filter.args...), true
+					}
+					args[i] = resolved_ident
+				}
+			}
+
+			// Filters format their input (in their results or error messages), so
+			// it's checked like printed values; the JSON filters check the methods
+			// called by encoding/json on their own.
+			if filter.name != "json" && filter.name != "json_script" {
+				if err := sandbox.checkFormat(value, false); err != nil {
+					return nil, err
 				}
 			}
+			if err := sandbox.checkFormat(args, false); err != nil {
+				return nil, err
+			}
 
-			value, err = filter.fn(value, filter.args, chainCtx)
+			value, err = filter.fn(value, args, chainCtx)
+			if _, is_limit := err.(*LimitError); is_limit {
+				return nil, err
+			}
 			if err != nil {
 				return nil, errors.New(fmt.Sprintf("Filter '%s' failed: %s", filter.name, err.Error()))
 			}
 		}
-		chainCtx.visitFilter(filter.name)
+		chainCtx.Visit(filter.name)
 	}
 
 	// Check for negation
@@ -515,10 +1162,69 @@ func (e *expr) evalString(ctx *Context) (*string, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := sandboxOf(ctx).checkFormat(out, false); err != nil {
+		return nil, err
+	}
 	outstr := fmt.Sprintf("%v", out)
 	return &outstr, nil
 }
 
+// isConstant returns true if the expression evaluates to the same value
+// regardless of the Context.
+func (e *expr) isConstant() bool {
+	if _, is_ident := e.root.(exprIdent); is_ident {
+		return false
+	}
+	return len(e.filters) == 0
+}
+
+// usesVar returns whether the expression looks up the variable name (also
+// within call arguments, subscripts and filter arguments).
+func (e *expr) usesVar(name string) bool {
+	if _, is_ident := e.root.(exprIdent); is_ident && identUsesVar(e.ident, name) {
+		return true
+	}
+	for _, arg := range e.root_args {
+		if argUsesVar(arg.Interface(), name) {
+			return true
+		}
+	}
+	for _, filter := range e.filters {
+		for _, arg := range filter.args {
+			if argUsesVar(arg, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func identUsesVar(parts []identPart, name string) bool {
+	for idx, part := range parts {
+		if idx == 0 && part.name == name {
+			return true
+		}
+		for _, arg := range part.args {
+			if arg.usesVar(name) {
+				return true
+			}
+		}
+		if part.is_subscript && arithUsesVar(part.subscript, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func argUsesVar(arg interface{}, name string) bool {
+	ident, is_ident := arg.(exprIdent)
+	if !is_ident {
+		return false
+	}
+	parts, err := splitIdent(ident)
+	return err == nil && identUsesVar(parts, name)
+}
+
 func (e *expr) addFilter(name string) (bool, error) {
 	filterfn, has := Filters[name]
 	if !has {
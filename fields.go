@@ -0,0 +1,199 @@
+package pongo
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldLookupOptions configures how an identifier like {{ user.first_name }}
+// is resolved to a struct field.
+//
+// A field can always be accessed by its Go name and by the name given in a
+// `pongo:"name"` struct tag (`pongo:"-"` hides the field from templates).
+type FieldLookupOptions struct {
+	// Use the name of a `json:"name"` struct tag if no field matches exactly.
+	JSONTags bool
+
+	// Match names case-insensitively and ignore underscores if no field
+	// matches exactly, so first_name or firstname resolve to FirstName.
+	IgnoreCase bool
+}
+
+// Field lookup options used by all templates. Change them before executing
+// templates; they are not synchronized.
+var FieldLookup = FieldLookupOptions{}
+
+// fieldTable contains all names under which the fields of a struct type
+// can be accessed.
+type fieldTable struct {
+	exact  map[string][]int // name -> field index
+	folded map[string][]int // foldFieldName(name) -> field index
+}
+
+type fieldTableKey struct {
+	t    reflect.Type
+	opts FieldLookupOptions
+}
+
+var (
+	fieldTables      = make(map[fieldTableKey]*fieldTable)
+	fieldTablesMutex sync.RWMutex
+)
+
+// foldFieldName normalizes a name for case-insensitive lookups
+// (FirstName, firstName and first_name become firstname).
+func foldFieldName(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+func getFieldTable(t reflect.Type) *fieldTable {
+	key := fieldTableKey{t: t, opts: FieldLookup}
+
+	fieldTablesMutex.RLock()
+	table, has_table := fieldTables[key]
+	fieldTablesMutex.RUnlock()
+	if has_table {
+		return table
+	}
+
+	table = newFieldTable(t, key.opts)
+
+	fieldTablesMutex.Lock()
+	fieldTables[key] = table
+	fieldTablesMutex.Unlock()
+
+	return table
+}
+
+func newFieldTable(t reflect.Type, opts FieldLookupOptions) *fieldTable {
+	table := &fieldTable{
+		exact:  make(map[string][]int),
+		folded: make(map[string][]int),
+	}
+
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Tag.Get("pongo") == "-" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	// Fields of embedded structs have a lower priority
+	sort.SliceStable(fields, func(i, j int) bool {
+		return len(fields[i].Index) < len(fields[j].Index)
+	})
+
+	add := func(m map[string][]int, name string, index []int) {
+		if name == "" || name == "-" {
+			return
+		}
+		if _, has_name := m[name]; !has_name {
+			m[name] = index
+		}
+	}
+
+	// Priority: pongo tag, Go name, json tag (if enabled), folded names (if enabled)
+	for _, f := range fields {
+		add(table.exact, f.Tag.Get("pongo"), f.Index)
+	}
+	for _, f := range fields {
+		add(table.exact, f.Name, f.Index)
+	}
+	if opts.JSONTags {
+		for _, f := range fields {
+			add(table.exact, strings.Split(f.Tag.Get("json"), ",")[0], f.Index)
+		}
+	}
+	if opts.IgnoreCase {
+		for _, f := range fields {
+			add(table.folded, foldFieldName(f.Tag.Get("pongo")), f.Index)
+		}
+		for _, f := range fields {
+			add(table.folded, foldFieldName(f.Name), f.Index)
+		}
+		if opts.JSONTags {
+			for _, f := range fields {
+				add(table.folded, foldFieldName(strings.Split(f.Tag.Get("json"), ",")[0]), f.Index)
+			}
+		}
+	}
+
+	return table
+}
+
+// fieldIndex returns the index of the field of the struct type t which is
+// accessible by name (according to FieldLookup).
+func fieldIndex(t reflect.Type, name string) ([]int, bool) {
+	table := getFieldTable(t)
+
+	index, has_field := table.exact[name]
+	if !has_field {
+		index, has_field = table.folded[foldFieldName(name)]
+	}
+	return index, has_field
+}
+
+// lookupField returns the field of the struct rv which is accessible by name
+// (according to FieldLookup) or an invalid reflect.Value if there is none.
+func lookupField(rv reflect.Value, name string) reflect.Value {
+	index, has_field := fieldIndex(rv.Type(), name)
+	if !has_field {
+		return reflect.Value{}
+	}
+
+	// FieldByIndexErr prevents a panic if an embedded struct pointer is nil
+	field, err := rv.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return field
+}
+
+// lookupFieldType is like lookupField, but for a struct type.
+func lookupFieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	index, has_field := fieldIndex(t, name)
+	if !has_field {
+		return nil, false
+	}
+	return t.FieldByIndex(index).Type, true
+}
+
+type methodKey struct {
+	t    reflect.Type
+	name string
+}
+
+var (
+	methodIndexes      = make(map[methodKey]int) // -1 if there's no such method
+	methodIndexesMutex sync.RWMutex
+)
+
+// lookupMethod is like rv.MethodByName(name), but caches the method's index
+// per type.
+func lookupMethod(rv reflect.Value, name string) reflect.Value {
+	if !rv.IsValid() {
+		return reflect.Value{}
+	}
+	key := methodKey{t: rv.Type(), name: name}
+
+	methodIndexesMutex.RLock()
+	index, has_index := methodIndexes[key]
+	methodIndexesMutex.RUnlock()
+	if !has_index {
+		index = -1
+		if m, has_method := key.t.MethodByName(name); has_method {
+			index = m.Index
+		}
+
+		methodIndexesMutex.Lock()
+		methodIndexes[key] = index
+		methodIndexesMutex.Unlock()
+	}
+
+	if index < 0 {
+		return reflect.Value{}
+	}
+	return rv.Method(index)
+}
@@ -7,10 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type FilterFunc func(interface{}, []interface{}, *FilterChainContext) (interface{}, error)
@@ -19,6 +17,26 @@ type FilterChainContext struct {
 	// Store what you want along the filter chain. Every filter has access to this store.
 	Store           map[string]interface{}
 	applied_filters []string
+	locale          *Locale
+	translator      Translator
+	sandbox         *Sandbox
+	state           *renderState
+}
+
+// Locale returns the locale of the execution (see LocaleKey).
+func (ctx *FilterChainContext) Locale() *Locale {
+	if ctx.locale == nil {
+		return Locales[defaultLocale]
+	}
+	return ctx.locale
+}
+
+// Translator returns the Translator of the execution (see TranslatorKey).
+func (ctx *FilterChainContext) Translator() Translator {
+	if ctx.translator == nil {
+		return untranslated{}
+	}
+	return ctx.translator
 }
 
 func (ctx *FilterChainContext) HasVisited(names ...string) bool {
@@ -32,10 +50,19 @@ func (ctx *FilterChainContext) HasVisited(names ...string) bool {
 	return false
 }
 
-func (ctx *FilterChainContext) visitFilter(name string) {
+// Visit records that the filter name was applied to the value (see
+// HasVisited). Templates do this for every filter of a chain; it's only
+// needed when calling filters directly (like code generated by GenerateGo).
+func (ctx *FilterChainContext) Visit(name string) {
 	ctx.applied_filters = append(ctx.applied_filters, name)
 }
 
+// markSafe is used by filters which produce HTML themselves (and escape their
+// input on their own); the result won't be escaped by the safe-filter again.
+func (ctx *FilterChainContext) markSafe() {
+	ctx.Visit("unsafe")
+}
+
 var Filters = map[string]FilterFunc{
 	"safe":        filterSafe,
 	"unsafe":      nil, // It will not be called, just added to visited filters (applied_filters)
@@ -50,6 +77,74 @@ var Filters = map[string]FilterFunc{
 	"time_format": filterTimeFormat,
 	"floatformat": filterFloatFormat,
 
+	// String filters (see filters_strings.go)
+	"title":              filterTitle,
+	"truncatechars":      filterTruncatechars,
+	"truncatewords":      filterTruncatewords,
+	"truncatechars_html": filterTruncatecharsHTML,
+	"wordcount":          filterWordcount,
+	"wordwrap":           filterWordwrap,
+	"center":             filterCenter,
+	"ljust":              filterLjust,
+	"rjust":              filterRjust,
+	"cut":                filterCut,
+	"slugify":            filterSlugify,
+	"linebreaks":         filterLinebreaks,
+	"linebreaksbr":       filterLinebreaksbr,
+	"linenumbers":        filterLinenumbers,
+	"addslashes":         filterAddslashes,
+	"make_list":          filterMakeList,
+	"stringformat":       filterStringformat,
+	"phone2numeric":      filterPhone2numeric,
+
+	// List filters (see filters_collections.go)
+	"first":            filterFirst,
+	"last":             filterLast,
+	"slice":            filterSlice,
+	"reverse":          filterReverse,
+	"sort":             filterSort,
+	"dictsort":         filterDictsort,
+	"dictsortreversed": filterDictsortReversed,
+	"unique":           filterUnique,
+	"random":           filterRandom,
+	"length_is":        filterLengthIs,
+	"attribute":        filterAttribute,
+	"map":              filterMap,
+
+	// Number filters (see filters_numbers.go)
+	"add":            filterAdd,
+	"intcomma":       filterIntcomma,
+	"intword":        filterIntword,
+	"filesizeformat": filterFilesizeformat,
+	"divisibleby":    filterDivisibleby,
+	"pluralize":      filterPluralize,
+	"get_digit":      filterGetDigit,
+
+	// Localization (see locale.go)
+	"currency": filterCurrency,
+
+	// Date filters (see filters_dates.go)
+	"date":        filterDate,
+	"time":        filterTime,
+	"timesince":   filterTimesince,
+	"timeuntil":   filterTimeuntil,
+	"naturalday":  filterNaturalday,
+	"naturaltime": filterNaturaltime,
+	"timezone":    filterTimezone,
+
+	// URL and encoding filters (see filters_encoding.go)
+	"urlencode":   filterUrlencode,
+	"iriencode":   filterIriencode,
+	"urlize":      filterUrlize,
+	"urlizetrunc": filterUrlizetrunc,
+	"base64":      filterBase64,
+	"json":        filterJSON,
+	"json_script": filterJSONScript,
+
+	// HTML filters (see filters_html.go)
+	"sanitize_html": filterSanitizeHTML,
+	"markdown":      filterMarkdown,
+
 	/* TODO:
 	- verbatim
 	- ...
@@ -75,11 +170,14 @@ func filterSafe(value interface{}, args []interface{}, ctx *FilterChainContext)
 		return value, nil
 	}
 
+	return escapeHTML(str), nil
+}
+
+func escapeHTML(str string) string {
 	output := strings.Replace(str, "&", "&amp;", -1)
 	output = strings.Replace(output, ">", "&gt;", -1)
 	output = strings.Replace(output, "<", "&lt;", -1)
-
-	return output, nil
+	return output
 }
 
 func filterLower(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
@@ -91,22 +189,21 @@ func filterLower(value interface{}, args []interface{}, ctx *FilterChainContext)
 }
 
 func filterTimeFormat(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
-	t, is_time := value.(time.Time)
-	if !is_time {
-		return nil, errors.New(fmt.Sprintf("%v (%T) is not of type string", value, value))
+	t, err := timeValue("time_format", value)
+	if err != nil {
+		return nil, err
 	}
 
-	arg := args[0]
-	if arg == nil {
+	if len(args) != 1 || args[0] == nil {
 		return nil, errors.New("time_format requires you pass a format.")
 	}
 
-	format, is_string := arg.(string)
+	format, is_string := args[0].(string)
 	if !is_string {
-		return nil, errors.New(fmt.Sprintf("time_format's format must be a string. %v (%T) passed.", format, format))
+		return nil, errors.New(fmt.Sprintf("time_format's format must be a string. %v (%T) passed.", args[0], args[0]))
 	}
 
-	return t.Format(format), nil
+	return ctx.Locale().formatTime(t, format), nil
 }
 
 func filterUpper(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
@@ -156,6 +253,9 @@ func filterJoin(value interface{}, args []interface{}, ctx *FilterChainContext)
 	rv := reflect.ValueOf(value)
 	switch rv.Kind() {
 	case reflect.Slice, reflect.Array:
+		if err := ctx.sandbox.checkFormat(value, false); err != nil {
+			return nil, err
+		}
 		items := make([]string, 0, rv.Len())
 		for i := 0; i < rv.Len(); i++ {
 			items = append(items, fmt.Sprintf("%v", rv.Index(i).Interface()))
@@ -167,36 +267,6 @@ func filterJoin(value interface{}, args []interface{}, ctx *FilterChainContext)
 	panic("unreachable")
 }
 
-func filterStriptags(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
-	str, is_str := value.(string)
-	if !is_str {
-		return nil, errors.New(fmt.Sprintf("%v is not of type string", value))
-	}
-
-	if len(args) > 1 {
-		return nil, errors.New("Please provide a comma-seperated string with tags (or no string to remove all tags).")
-	}
-
-	if len(args) == 1 {
-		taglist, is_string := args[0].(string)
-		if !is_string {
-			return nil, errors.New(fmt.Sprintf("Taglist must be a string, not %T ('%v')", args[0], args[0]))
-		}
-
-		tags := strings.Split(taglist, ",")
-
-		for _, tag := range tags {
-			re := regexp.MustCompile(fmt.Sprintf("</?%s/?>", tag))
-			str = re.ReplaceAllString(str, "")
-		}
-	} else {
-		re := regexp.MustCompile("<[^>]*?>")
-		str = re.ReplaceAllString(str, "")
-	}
-
-	return strings.TrimSpace(str), nil
-}
-
 func filterDefault(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
 	// Use reflect to check against zero() of type
 
@@ -240,18 +310,18 @@ func filterDefault(value interface{}, args []interface{}, ctx *FilterChainContex
 		{{ 34.00000|floatformat:"-3" }} displays 34
 		{{ 34.26000|floatformat:"-3" }} displays 34.260
 
+		Integers and numeric strings are accepted as well.
+
+		{{ 34|floatformat:2 }} displays 34.00
+		{{ "34.26"|floatformat }} displays 34.3
+
 */
 func filterFloatFormat(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
 
 	// Value to format
-	var floatValue float64
-	switch val := value.(type) {
-	case float32:
-		floatValue = float64(val)
-	case float64:
-		floatValue = val
-	default:
-		return nil, errors.New("Illegal type for floatformat (only float32 and float64 are acceptable)")
+	_, floatValue, _, is_number := numericValue(value)
+	if !is_number {
+		return nil, errors.New(fmt.Sprintf("Illegal type for floatformat (only numbers and numeric strings are acceptable): %v (%T)", value, value))
 	}
 
 	// Default parameters
@@ -288,5 +358,5 @@ func filterFloatFormat(value interface{}, args []interface{}, ctx *FilterChainCo
 			fmtFloat = strconv.Itoa(intVal)
 		}
 	}
-	return fmtFloat, nil
+	return ctx.Locale().formatNumber(fmtFloat, false), nil
 }
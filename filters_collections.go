@@ -0,0 +1,428 @@
+package pongo
+
+// List filters; like filterJoin they accept slices and arrays of any type
+// (and strings, if it makes sense).
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	random_source = rand.New(rand.NewSource(time.Now().UnixNano()))
+	random_mutex  sync.Mutex
+)
+
+// Seeds the random number generator used by the random filter; its results
+// are reproducible afterwards (e. g. in tests).
+func SetRandomSeed(seed int64) {
+	random_mutex.Lock()
+	defer random_mutex.Unlock()
+	random_source = rand.New(rand.NewSource(seed))
+}
+
+func randomIntn(n int) int {
+	random_mutex.Lock()
+	defer random_mutex.Unlock()
+	return random_source.Intn(n)
+}
+
+// listValue returns the slice or array value (after resolving pointers) or
+// an error mentioning the filter name.
+func listValue(name string, value interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(value)
+	for (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv, nil
+	default:
+		return reflect.Value{}, errors.New(fmt.Sprintf("Cannot apply %s to variable of type %T ('%v').", name, value, value))
+	}
+}
+
+// copyList returns a new slice containing the elements of the list rv at the
+// given indexes.
+func copyList(rv reflect.Value, indexes []int) interface{} {
+	list := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), 0, len(indexes))
+	for _, idx := range indexes {
+		list = reflect.Append(list, rv.Index(idx))
+	}
+	return list.Interface()
+}
+
+func rangeIndexes(start, stop, step int) []int {
+	indexes := make([]int, 0, 10)
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func filterFirst(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if str, is_str := value.(string); is_str {
+		runes := []rune(str)
+		if len(runes) == 0 {
+			return "", nil
+		}
+		return string(runes[0]), nil
+	}
+	rv, err := listValue("first", value)
+	if err != nil {
+		return nil, err
+	}
+	if rv.Len() == 0 {
+		return "", nil
+	}
+	return rv.Index(0).Interface(), nil
+}
+
+func filterLast(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if str, is_str := value.(string); is_str {
+		runes := []rune(str)
+		if len(runes) == 0 {
+			return "", nil
+		}
+		return string(runes[len(runes)-1]), nil
+	}
+	rv, err := listValue("last", value)
+	if err != nil {
+		return nil, err
+	}
+	if rv.Len() == 0 {
+		return "", nil
+	}
+	return rv.Index(rv.Len() - 1).Interface(), nil
+}
+
+// parseSlice parses a Python slice (like "1:3", ":-1" or "::2"; a single
+// number is the end) and returns the selected indexes of a list of the
+// given length.
+func parseSlice(slice string, length int) ([]int, error) {
+	parts := strings.Split(slice, ":")
+	if len(parts) == 1 {
+		parts = []string{"", parts[0]}
+	}
+	if len(parts) > 3 {
+		return nil, errors.New(fmt.Sprintf("Invalid slice '%s'.", slice))
+	}
+
+	values := make([]*int, 3)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid slice '%s'.", slice))
+		}
+		values[i] = &value
+	}
+
+	step := 1
+	if values[2] != nil {
+		step = *values[2]
+	}
+	if step == 0 {
+		return nil, errors.New("Slice step cannot be zero.")
+	}
+
+	// Same bounds as Python's slice.indices
+	lower, upper := 0, length
+	if step < 0 {
+		lower, upper = -1, length-1
+	}
+	bound := func(value *int, def int) int {
+		if value == nil {
+			return def
+		}
+		idx := *value
+		if idx < 0 {
+			idx += length
+		}
+		if idx < lower {
+			return lower
+		}
+		if idx > upper {
+			return upper
+		}
+		return idx
+	}
+	if step > 0 {
+		return rangeIndexes(bound(values[0], lower), bound(values[1], upper), step), nil
+	}
+	return rangeIndexes(bound(values[0], upper), bound(values[1], lower), step), nil
+}
+
+// filterSlice returns a slice of a list or string (using Python's syntax):
+//
+//     {{ names|slice:"1:3" }}
+//     {{ "Florian"|slice:"::-1" }} displays nairolF
+func filterSlice(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("slice requires exactly one argument.")
+	}
+	slice := stringValue(args[0])
+
+	if str, is_str := value.(string); is_str {
+		runes := []rune(str)
+		indexes, err := parseSlice(slice, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		sliced := make([]rune, 0, len(indexes))
+		for _, idx := range indexes {
+			sliced = append(sliced, runes[idx])
+		}
+		return string(sliced), nil
+	}
+
+	rv, err := listValue("slice", value)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := parseSlice(slice, rv.Len())
+	if err != nil {
+		return nil, err
+	}
+	return copyList(rv, indexes), nil
+}
+
+func filterReverse(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return filterSlice(value, []interface{}{"::-1"}, ctx)
+}
+
+// attributeValue returns the field or map key name of value; name can be a
+// path (like "author.name" or "tags.0"). Methods aren't called.
+func attributeValue(value interface{}, name string) (interface{}, bool) {
+	for _, part := range strings.Split(name, ".") {
+		rv := reflect.ValueOf(value)
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil, false
+			}
+			rv = rv.Elem()
+		}
+		var key interface{} = part
+		if idx, err := strconv.Atoi(part); err == nil && rv.Kind() != reflect.Struct {
+			key = idx
+		}
+		if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+			key = part
+		}
+		field := indexValue(rv, key)
+		if !field.IsValid() || !field.CanInterface() {
+			return nil, false
+		}
+		value = field.Interface()
+	}
+	return value, true
+}
+
+// compareValues compares numbers, strings, bools and times; it returns -1, 0
+// or 1.
+func compareValues(a, b interface{}) (int, error) {
+	if _, af, _, a_number := toNumber(a); a_number {
+		if _, bf, _, b_number := toNumber(b); b_number {
+			switch {
+			case af < bf:
+				return -1, nil
+			case af > bf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	switch av := a.(type) {
+	case string:
+		if bv, is_str := b.(string); is_str {
+			return strings.Compare(av, bv), nil
+		}
+	case bool:
+		if bv, is_bool := b.(bool); is_bool {
+			switch {
+			case av == bv:
+				return 0, nil
+			case bv:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case time.Time:
+		if bv, is_time := b.(time.Time); is_time {
+			switch {
+			case av.Before(bv):
+				return -1, nil
+			case av.After(bv):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Cannot compare %v (%T) and %v (%T).", a, a, b, b))
+}
+
+// sortList sorts the list rv (stable) by the keys of its elements.
+func sortList(rv reflect.Value, keys []interface{}, reversed bool) (interface{}, error) {
+	indexes := make([]int, rv.Len())
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var err error
+	sort.SliceStable(indexes, func(i, j int) bool {
+		cmp, cmp_err := compareValues(keys[indexes[i]], keys[indexes[j]])
+		if cmp_err != nil && err == nil {
+			err = cmp_err
+		}
+		if reversed {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyList(rv, indexes), nil
+}
+
+func filterSort(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	rv, err := listValue("sort", value)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, rv.Len())
+	for i := range keys {
+		keys[i] = rv.Index(i).Interface()
+	}
+	return sortList(rv, keys, false)
+}
+
+func dictsort(name string, value interface{}, args []interface{}, reversed bool) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New(fmt.Sprintf("%s requires exactly one argument.", name))
+	}
+	key := stringValue(args[0])
+	rv, err := listValue(name, value)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, rv.Len())
+	for i := range keys {
+		item := rv.Index(i).Interface()
+		item_key, found := attributeValue(item, key)
+		if !found {
+			return nil, errors.New(fmt.Sprintf("%v (%T) has no field or key '%s'.", item, item, key))
+		}
+		keys[i] = item_key
+	}
+	return sortList(rv, keys, reversed)
+}
+
+// filterDictsort sorts a list of structs or maps by the given field or key:
+//
+//     {% for user in users|dictsort:"Name" %}
+func filterDictsort(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return dictsort("dictsort", value, args, false)
+}
+
+func filterDictsortReversed(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return dictsort("dictsortreversed", value, args, true)
+}
+
+// filterUnique removes duplicates from a list (keeping the first occurrence).
+func filterUnique(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	rv, err := listValue("unique", value)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]int, 0, rv.Len())
+	seen := make(map[interface{}]bool)
+outer:
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i).Interface()
+		if item != nil && !reflect.TypeOf(item).Comparable() {
+			for _, idx := range indexes {
+				if reflect.DeepEqual(rv.Index(idx).Interface(), item) {
+					continue outer
+				}
+			}
+		} else if seen[item] {
+			continue
+		} else {
+			seen[item] = true
+		}
+		indexes = append(indexes, i)
+	}
+	return copyList(rv, indexes), nil
+}
+
+// filterRandom returns a random element of a list (see SetRandomSeed).
+func filterRandom(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	rv, err := listValue("random", value)
+	if err != nil {
+		return nil, err
+	}
+	if rv.Len() == 0 {
+		return "", nil
+	}
+	return rv.Index(randomIntn(rv.Len())).Interface(), nil
+}
+
+func filterLengthIs(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	length, err := filterIntArg("length_is", args)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := filterLength(value, nil, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return actual == length, nil
+}
+
+// filterAttribute returns a field or key of the value (see attributeValue);
+// unlike the dot-notation the name can be a variable:
+//
+//     {{ user|attribute:column }}
+func filterAttribute(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("attribute requires exactly one argument.")
+	}
+	name := stringValue(args[0])
+	attribute, found := attributeValue(value, name)
+	if !found {
+		return nil, errors.New(fmt.Sprintf("%v (%T) has no field or key '%s'.", value, value, name))
+	}
+	return attribute, nil
+}
+
+// filterMap returns the given field or key of every element of a list:
+//
+//     {{ users|map:"Name"|join:", " }}
+func filterMap(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("map requires exactly one argument.")
+	}
+	rv, err := listValue("map", value)
+	if err != nil {
+		return nil, err
+	}
+	mapped := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		attribute, err := filterAttribute(rv.Index(i).Interface(), args, ctx)
+		if err != nil {
+			return nil, err
+		}
+		mapped = append(mapped, attribute)
+	}
+	return mapped, nil
+}
@@ -0,0 +1,446 @@
+package pongo
+
+// Date filters; they closely follow Django's implementations and accept
+// time.Time, *time.Time and Unix timestamps (seconds since 1970-01-01 UTC).
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Now returns the current time for the relative date filters (like
+// timesince). Replace it to render templates at a fixed time (e. g. in tests);
+// it's not synchronized.
+var Now = time.Now
+
+// timeValue converts value to a time.Time; name is the filter's name.
+func timeValue(name string, value interface{}) (time.Time, error) {
+	switch val := value.(type) {
+	case time.Time:
+		return val, nil
+	case *time.Time:
+		if val != nil {
+			return *val, nil
+		}
+	default:
+		if i, f, is_int, is_number := toNumber(value); is_number {
+			if is_int {
+				return time.Unix(i, 0), nil
+			}
+			sec := int64(f)
+			return time.Unix(sec, int64((f-float64(sec))*1e9)), nil
+		}
+	}
+	return time.Time{}, errors.New(fmt.Sprintf("%s requires a time (time.Time, *time.Time or a Unix timestamp), not %T ('%v').", name, value, value))
+}
+
+// dateFormatArg returns the format given to a date filter or the default one.
+func dateFormatArg(name string, args []interface{}, default_format string) (string, error) {
+	if len(args) > 1 {
+		return "", errors.New(fmt.Sprintf("%s takes at most one argument.", name))
+	} else if len(args) == 0 {
+		return default_format, nil
+	}
+	format, is_string := args[0].(string)
+	if !is_string {
+		return "", errors.New(fmt.Sprintf("%s's format must be a string, not %T ('%v').", name, args[0], args[0]))
+	}
+	return format, nil
+}
+
+// Abbreviations of the months in the Associated Press style (format character
+// N in English)
+var ap_months = [12]string{"Jan.", "Feb.", "March", "April", "May", "June", "July", "Aug.", "Sept.", "Oct.", "Nov.", "Dec."}
+
+// hour12 returns the hour on a 12-hour clock.
+func hour12(t time.Time) int {
+	if h := t.Hour() % 12; h != 0 {
+		return h
+	}
+	return 12
+}
+
+// ordinalSuffix returns the English ordinal suffix of day (like "st" for 1).
+func ordinalSuffix(day int) string {
+	if day >= 11 && day <= 13 {
+		return "th"
+	}
+	switch day % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
+
+// formatDate formats t using Django's format characters (like "D d M Y H:i")
+// and the locale's names of months and days. A backslash escapes the
+// following character; unknown characters are copied.
+func (l *Locale) formatDate(t time.Time, format string) string {
+	parts := make([]string, 0, len(format))
+	escaped := false
+	for _, c := range format {
+		if escaped {
+			parts = append(parts, string(c))
+			escaped = false
+			continue
+		}
+
+		var part string
+		switch c {
+		case '\\':
+			escaped = true
+			continue
+		case 'a':
+			part = "a.m."
+			if t.Hour() >= 12 {
+				part = "p.m."
+			}
+		case 'A':
+			part = "AM"
+			if t.Hour() >= 12 {
+				part = "PM"
+			}
+		case 'b':
+			part = strings.ToLower(l.ShortMonths[t.Month()-1])
+		case 'c':
+			part = t.Format("2006-01-02T15:04:05-07:00")
+			if t.Nanosecond() >= 1000 {
+				part = t.Format("2006-01-02T15:04:05.000000-07:00")
+			}
+		case 'd':
+			part = fmt.Sprintf("%02d", t.Day())
+		case 'D':
+			part = l.ShortDays[t.Weekday()]
+		case 'e':
+			part = t.Location().String()
+		case 'E', 'F':
+			part = l.Months[t.Month()-1]
+		case 'f':
+			part = strconv.Itoa(hour12(t))
+			if t.Minute() != 0 {
+				part += fmt.Sprintf(":%02d", t.Minute())
+			}
+		case 'g':
+			part = strconv.Itoa(hour12(t))
+		case 'G':
+			part = strconv.Itoa(t.Hour())
+		case 'h':
+			part = fmt.Sprintf("%02d", hour12(t))
+		case 'H':
+			part = fmt.Sprintf("%02d", t.Hour())
+		case 'i':
+			part = fmt.Sprintf("%02d", t.Minute())
+		case 'I':
+			part = "0"
+			if t.IsDST() {
+				part = "1"
+			}
+		case 'j':
+			part = strconv.Itoa(t.Day())
+		case 'l':
+			part = l.Days[t.Weekday()]
+		case 'L':
+			year := t.Year()
+			part = "False"
+			if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+				part = "True"
+			}
+		case 'm':
+			part = fmt.Sprintf("%02d", int(t.Month()))
+		case 'M':
+			part = l.ShortMonths[t.Month()-1]
+		case 'n':
+			part = strconv.Itoa(int(t.Month()))
+		case 'N':
+			part = l.ShortMonths[t.Month()-1]
+			if l.Name == "en" {
+				part = ap_months[t.Month()-1]
+			}
+		case 'o':
+			year, _ := t.ISOWeek()
+			part = strconv.Itoa(year)
+		case 'O':
+			part = t.Format("-0700")
+		case 'P':
+			switch {
+			case t.Hour() == 0 && t.Minute() == 0:
+				part = "midnight"
+			case t.Hour() == 12 && t.Minute() == 0:
+				part = "noon"
+			default:
+				part = l.formatDate(t, "f a")
+			}
+		case 'r':
+			// RFC 5322, always in English
+			part = t.Format("Mon, 02 Jan 2006 15:04:05 -0700")
+		case 's':
+			part = fmt.Sprintf("%02d", t.Second())
+		case 'S':
+			part = ordinalSuffix(t.Day())
+		case 't':
+			part = strconv.Itoa(time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day())
+		case 'T':
+			part = t.Format("MST")
+		case 'u':
+			part = fmt.Sprintf("%06d", t.Nanosecond()/1000)
+		case 'U':
+			part = strconv.FormatInt(t.Unix(), 10)
+		case 'w':
+			part = strconv.Itoa(int(t.Weekday()))
+		case 'W':
+			_, week := t.ISOWeek()
+			part = strconv.Itoa(week)
+		case 'y':
+			part = fmt.Sprintf("%02d", t.Year()%100)
+		case 'Y':
+			part = strconv.Itoa(t.Year())
+		case 'z':
+			part = strconv.Itoa(t.YearDay())
+		case 'Z':
+			_, offset := t.Zone()
+			part = strconv.Itoa(offset)
+		default:
+			part = string(c)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "")
+}
+
+// filterDate formats a time using Django's format characters (the locale's
+// DateFormat is used by default):
+//
+//     {{ published|date:"D d M Y" }} displays Tue 05 Mar 2024
+//     {{ published|date:"jS \o\f F" }} displays 5th of March
+func filterDate(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	t, err := timeValue("date", value)
+	if err != nil {
+		return nil, err
+	}
+	locale := ctx.Locale()
+	format, err := dateFormatArg("date", args, locale.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	return locale.formatDate(t, format), nil
+}
+
+// filterTime is like the date filter, but uses the locale's TimeFormat by
+// default:
+//
+//     {{ published|time }} displays 2:30 p.m. (in English)
+//     {{ published|time:"H:i" }} displays 14:30
+func filterTime(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	t, err := timeValue("time", value)
+	if err != nil {
+		return nil, err
+	}
+	locale := ctx.Locale()
+	format, err := dateFormatArg("time", args, locale.TimeFormat)
+	if err != nil {
+		return nil, err
+	}
+	return locale.formatDate(t, format), nil
+}
+
+// Units of timesince (a year has 365 days and a month 30 days like in Django)
+var timesince_units = []struct {
+	seconds  int64
+	singular string
+	plural   string
+}{
+	{60 * 60 * 24 * 365, "%d year", "%d years"},
+	{60 * 60 * 24 * 30, "%d month", "%d months"},
+	{60 * 60 * 24 * 7, "%d week", "%d weeks"},
+	{60 * 60 * 24, "%d day", "%d days"},
+	{60 * 60, "%d hour", "%d hours"},
+	{60, "%d minute", "%d minutes"},
+}
+
+// translateCount translates a message containing the number n (as %d).
+func translateCount(translator Translator, singular string, plural string, n int64) string {
+	msg := translator.NGettext(singular, plural, int(n))
+	return strings.Replace(msg, "%d", strconv.FormatInt(n, 10), 1)
+}
+
+// timesince returns the time from since to until in words, using at most two
+// adjacent units (like "4 days, 6 hours").
+func timesince(since time.Time, until time.Time, translator Translator) string {
+	seconds := int64(until.Sub(since) / time.Second)
+	last := len(timesince_units) - 1
+	if seconds < timesince_units[last].seconds {
+		return translateCount(translator, timesince_units[last].singular, timesince_units[last].plural, 0)
+	}
+
+	for idx, unit := range timesince_units {
+		count := seconds / unit.seconds
+		if count == 0 {
+			continue
+		}
+		result := translateCount(translator, unit.singular, unit.plural, count)
+		if idx < last {
+			next := timesince_units[idx+1]
+			if count2 := (seconds - count*unit.seconds) / next.seconds; count2 != 0 {
+				result += ", " + translateCount(translator, next.singular, next.plural, count2)
+			}
+		}
+		return result
+	}
+	panic("unreachable")
+}
+
+// relativeTimeArgs returns the value and the time it's compared to (the
+// argument or Now) of timesince and timeuntil.
+func relativeTimeArgs(name string, value interface{}, args []interface{}) (time.Time, time.Time, error) {
+	t, err := timeValue(name, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if len(args) > 1 {
+		return time.Time{}, time.Time{}, errors.New(fmt.Sprintf("%s takes at most one argument.", name))
+	} else if len(args) == 0 {
+		return t, Now(), nil
+	}
+	other, err := timeValue(name, args[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return t, other, nil
+}
+
+// filterTimesince returns the time since the value (until now or the given
+// time) in words; times in the future result in "0 minutes":
+//
+//     {{ published|timesince }} displays 4 days, 6 hours
+//     {{ published|timesince:comment.Date }}
+func filterTimesince(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	t, now, err := relativeTimeArgs("timesince", value, args)
+	if err != nil {
+		return nil, err
+	}
+	return timesince(t, now, ctx.Translator()), nil
+}
+
+// filterTimeuntil returns the time until the value (from now or the given
+// time) in words:
+//
+//     {{ conference|timeuntil }} displays 1 week, 2 days
+func filterTimeuntil(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	t, now, err := relativeTimeArgs("timeuntil", value, args)
+	if err != nil {
+		return nil, err
+	}
+	return timesince(now, t, ctx.Translator()), nil
+}
+
+// filterNaturalday returns "today", "tomorrow" or "yesterday" for times
+// of these days (in the value's time zone), other times are formatted like
+// by the date filter:
+//
+//     {{ published|naturalday }} displays yesterday
+//     {{ published|naturalday:"j. F" }} displays 5. March
+func filterNaturalday(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	t, err := timeValue("naturalday", value)
+	if err != nil {
+		return nil, err
+	}
+	locale := ctx.Locale()
+	format, err := dateFormatArg("naturalday", args, locale.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	now := Now().In(t.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch day.Sub(today) / (24 * time.Hour) {
+	case 0:
+		return ctx.Translator().Gettext("today"), nil
+	case 1:
+		return ctx.Translator().Gettext("tomorrow"), nil
+	case -1:
+		return ctx.Translator().Gettext("yesterday"), nil
+	}
+	return locale.formatDate(t, format), nil
+}
+
+// filterNaturaltime returns the time relative to now in words:
+//
+//     {{ comment.Date|naturaltime }} displays 3 minutes ago
+//     {{ deadline|naturaltime }} displays 2 days, 3 hours from now
+func filterNaturaltime(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	t, err := timeValue("naturaltime", value)
+	if err != nil {
+		return nil, err
+	}
+	translator := ctx.Translator()
+
+	now := Now()
+	delta := now.Sub(t)
+	past := delta > 0
+	if !past {
+		delta = -delta
+	}
+	seconds := int64(delta / time.Second)
+
+	var msgs [3]string // singular, plural, relative message of more than a day
+	if past {
+		msgs = [3]string{"a second ago", "%d seconds ago", "%s ago"}
+	} else {
+		msgs = [3]string{"a second from now", "%d seconds from now", "%s from now"}
+	}
+	switch {
+	case seconds == 0:
+		return translator.Gettext("now"), nil
+	case seconds < 60:
+		return translateCount(translator, msgs[0], msgs[1], seconds), nil
+	case seconds < 60*60:
+		if past {
+			return translateCount(translator, "a minute ago", "%d minutes ago", seconds/60), nil
+		}
+		return translateCount(translator, "a minute from now", "%d minutes from now", seconds/60), nil
+	case seconds < 60*60*24:
+		if past {
+			return translateCount(translator, "an hour ago", "%d hours ago", seconds/(60*60)), nil
+		}
+		return translateCount(translator, "an hour from now", "%d hours from now", seconds/(60*60)), nil
+	}
+
+	var since string
+	if past {
+		since = timesince(t, now, translator)
+	} else {
+		since = timesince(now, t, translator)
+	}
+	return strings.Replace(translator.Gettext(msgs[2]), "%s", since, 1), nil
+}
+
+// filterTimezone converts a time to the given time zone (an IANA name like
+// "Europe/Berlin", "UTC" or "Local"):
+//
+//     {{ published|timezone:"Europe/Berlin"|date:"H:i T" }} displays 15:30 CET
+func filterTimezone(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("timezone requires exactly one argument.")
+	}
+	t, err := timeValue("timezone", value)
+	if err != nil {
+		return nil, err
+	}
+	name, is_string := args[0].(string)
+	if !is_string {
+		return nil, errors.New(fmt.Sprintf("timezone's argument must be a string, not %T ('%v').", args[0], args[0]))
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unknown time zone '%s'.", name))
+	}
+	return t.In(location), nil
+}
@@ -0,0 +1,267 @@
+package pongo
+
+// URL and encoding filters; they closely follow Django's implementations.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// quoteURL percent-encodes str like Python's urllib.parse.quote (UTF-8
+// encoded); letters, digits, "_.-~" and the characters of safe aren't
+// encoded.
+func quoteURL(str string, safe string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			strings.IndexByte("_.-~", c) >= 0 || (c < utf8.RuneSelf && strings.IndexByte(safe, c) >= 0) {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// Characters which are not encoded by iriencode
+const iri_safe = "/#%[]=:;$&()+,!?*@'~"
+
+// escapeAttr escapes str for the use in an (quoted) HTML attribute.
+func escapeAttr(str string) string {
+	str = escapeHTML(str)
+	str = strings.Replace(str, "\"", "&quot;", -1)
+	return strings.Replace(str, "'", "&#39;", -1)
+}
+
+// filterUrlencode escapes a value for the use in a URL; slashes aren't
+// escaped unless other safe characters are given:
+//
+//     <a href="/search?q={{ query|urlencode }}">
+//     {{ "https://example.com/?a=b"|urlencode:"" }} displays https%3A%2F%2Fexample.com%2F%3Fa%3Db
+func filterUrlencode(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	safe := "/"
+	if len(args) > 1 {
+		return nil, errors.New("urlencode takes at most one argument.")
+	} else if len(args) == 1 {
+		safe = stringValue(args[0])
+	}
+	return quoteURL(stringValue(value), safe), nil
+}
+
+// filterIriencode converts an IRI (an URL which might contain non-ASCII
+// characters) to an URL:
+//
+//     {{ "/über?q=ä&p=1"|iriencode }} displays /%C3%BCber?q=%C3%A4&amp;p=1
+func filterIriencode(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return quoteURL(stringValue(value), iri_safe), nil
+}
+
+var (
+	re_urlize_words = regexp.MustCompile(`\s+|\S+`)
+	re_urlize_url   = regexp.MustCompile(`(?i)^https?://\[?\w`)
+	re_urlize_www   = regexp.MustCompile(`(?i)^(www\.|[^@:/]+\.(com|edu|gov|int|mil|net|org)($|/))`)
+	re_urlize_email = regexp.MustCompile(`^[^@\s:]+@[^@\s:]+\.[A-Za-z]{2,}$`)
+)
+
+// trimPunctuation splits word into leading punctuation, the (possible) link
+// and trailing punctuation. Closing brackets are only trailing punctuation if
+// they're not part of the link (like in http://example.com/wiki/Go_(game)).
+func trimPunctuation(word string) (string, string, string) {
+	lead, middle, trail := "", word, ""
+	for {
+		trimmed := false
+		if len(middle) > 0 && strings.IndexByte("(<[", middle[0]) >= 0 {
+			lead, middle = lead+middle[:1], middle[1:]
+			trimmed = true
+		}
+		if n := len(middle); n > 0 {
+			c := middle[n-1]
+			is_punct := strings.IndexByte(".,:;!\"'>", c) >= 0
+			if c == ')' {
+				is_punct = strings.Count(middle, ")") > strings.Count(middle, "(")
+			} else if c == ']' {
+				is_punct = strings.Count(middle, "]") > strings.Count(middle, "[")
+			}
+			if is_punct {
+				middle, trail = middle[:n-1], middle[n-1:]+trail
+				trimmed = true
+			}
+		}
+		if !trimmed {
+			return lead, middle, trail
+		}
+	}
+}
+
+// urlize converts URLs and email addresses of text into links whose texts are
+// truncated to limit characters (if limit > 0).
+func urlize(text string, limit int, escape bool) string {
+	maybeEscape := func(str string) string {
+		if escape {
+			return escapeHTML(str)
+		}
+		return str
+	}
+
+	words := re_urlize_words.FindAllString(text, -1)
+	for i, word := range words {
+		if !strings.ContainsAny(word, ".@:") {
+			words[i] = maybeEscape(word)
+			continue
+		}
+
+		lead, middle, trail := trimPunctuation(word)
+		url, rel := "", ` rel="nofollow"`
+		switch {
+		case re_urlize_url.MatchString(middle):
+			url = middle
+		case re_urlize_www.MatchString(middle):
+			url = "http://" + middle
+		case re_urlize_email.MatchString(middle):
+			url, rel = "mailto:"+middle, ""
+		default:
+			words[i] = maybeEscape(word)
+			continue
+		}
+
+		link_text := middle
+		if limit > 0 && utf8.RuneCountInString(link_text) > limit {
+			link_text = string([]rune(link_text)[:limit-1]) + "…"
+		}
+		words[i] = fmt.Sprintf(`%s<a href="%s"%s>%s</a>%s`, maybeEscape(lead), escapeAttr(quoteURL(url, iri_safe)),
+			rel, maybeEscape(link_text), maybeEscape(trail))
+	}
+	return strings.Join(words, "")
+}
+
+// filterUrlize converts URLs (starting with http://, https:// or www.)
+// and email addresses into links; the remaining text is escaped:
+//
+//     {{ "Visit www.example.com."|urlize }} displays Visit <a href="http://www.example.com" rel="nofollow">www.example.com</a>.
+func filterUrlize(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	escape := !ctx.HasVisited("unsafe", "safe")
+	ctx.markSafe()
+	return urlize(stringValue(value), 0, escape), nil
+}
+
+// filterUrlizetrunc is like urlize, but truncates the texts of the links to
+// the given number of characters (including the ellipsis):
+//
+//     {{ "https://example.com/page"|urlizetrunc:15 }} displays <a href="https://example.com/page" rel="nofollow">https://exampl…</a>
+func filterUrlizetrunc(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	limit, err := filterIntArg("urlizetrunc", args)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		return nil, errors.New(fmt.Sprintf("urlizetrunc requires a positive length, not %d.", limit))
+	}
+	escape := !ctx.HasVisited("unsafe", "safe")
+	ctx.markSafe()
+	return urlize(stringValue(value), limit, escape), nil
+}
+
+// filterBase64 encodes a string (or []byte) using base64; with the argument
+// "decode" it decodes a base64 encoded string (URL-safe encodings and missing
+// padding are accepted):
+//
+//     {{ "pongo"|base64 }} displays cG9uZ28=
+//     {{ "cG9uZ28="|base64:"decode" }} displays pongo
+func filterBase64(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	mode := "encode"
+	if len(args) > 1 {
+		return nil, errors.New("base64 takes at most one argument.")
+	} else if len(args) == 1 {
+		mode = stringValue(args[0])
+	}
+
+	var data []byte
+	if b, is_bytes := value.([]byte); is_bytes {
+		data = b
+	} else {
+		data = []byte(stringValue(value))
+	}
+
+	switch mode {
+	case "encode":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "decode":
+		str := strings.TrimRight(strings.TrimSpace(string(data)), "=")
+		for _, encoding := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+			if decoded, err := encoding.DecodeString(str); err == nil {
+				return string(decoded), nil
+			}
+		}
+		return nil, errors.New(fmt.Sprintf("'%s' is not base64 encoded.", data))
+	}
+	return nil, errors.New(fmt.Sprintf("base64's argument must be \"encode\" or \"decode\", not '%s'.", mode))
+}
+
+// jsonValue encodes value as JSON; <, > and & are escaped (as \u003c etc.),
+// so the result can be embedded into HTML and <script> elements. Methods like
+// MarshalJSON are subject to the sandbox.
+func jsonValue(name string, value interface{}, indent int, sandbox *Sandbox) (string, error) {
+	if err := sandbox.checkFormat(value, true); err != nil {
+		return "", err
+	}
+	var data []byte
+	var err error
+	if indent > 0 {
+		data, err = json.MarshalIndent(value, "", strings.Repeat(" ", indent))
+	} else {
+		data, err = json.Marshal(value)
+	}
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("%s can't encode %T: %s", name, value, err))
+	}
+	return string(data), nil
+}
+
+// filterJSON encodes a value as JSON, e. g. to pass it to JavaScript; the
+// argument is the indentation (number of spaces):
+//
+//     <script>var user = {{ user|json }};</script>
+//     <pre>{{ config|json:2 }}</pre>
+func filterJSON(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	indent := 0
+	if len(args) > 0 {
+		var err error
+		indent, err = filterIntArg("json", args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	str, err := jsonValue("json", value, indent, ctx.sandbox)
+	if err != nil {
+		return nil, err
+	}
+	ctx.markSafe()
+	return str, nil
+}
+
+// filterJSONScript encodes a value as JSON and wraps it into a <script>
+// element (with the given id), which can be read by JavaScript:
+//
+//     {{ user|json_script:"user-data" }} displays <script id="user-data" type="application/json">{"name":"Flo"}</script>
+//
+//     JSON.parse(document.getElementById("user-data").textContent)
+func filterJSONScript(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	str, err := jsonValue("json_script", value, 0, ctx.sandbox)
+	if err != nil {
+		return nil, err
+	}
+	ctx.markSafe()
+	if len(args) > 1 {
+		return nil, errors.New("json_script takes at most one argument.")
+	} else if len(args) == 1 && stringValue(args[0]) != "" {
+		return fmt.Sprintf(`<script id="%s" type="application/json">%s</script>`, escapeAttr(stringValue(args[0])), str), nil
+	}
+	return fmt.Sprintf(`<script type="application/json">%s</script>`, str), nil
+}
@@ -0,0 +1,499 @@
+package pongo
+
+// HTML filters: striptags, sanitize_html and markdown (see markdown.go).
+// They use a small HTML tokenizer instead of regular expressions, so
+// attributes containing '>', comments and the contents of script elements
+// are handled like browsers do.
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type htmlTokenType int
+
+const (
+	htmlText htmlTokenType = iota
+	htmlStartTag
+	htmlEndTag
+	htmlComment // comments, doctypes and processing instructions
+)
+
+type htmlAttr struct {
+	name  string // lowercased
+	value string // entities are not decoded
+}
+
+type htmlToken struct {
+	typ          htmlTokenType
+	raw          string // the token as in the source
+	name         string // lowercased tag name
+	attrs        []htmlAttr
+	self_closing bool // like <br/>
+}
+
+// The contents of these elements are text (up to their end tag)
+var html_raw_text_elements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+	"xmp": true, "iframe": true, "noembed": true, "noframes": true,
+}
+
+// htmlTokenizer splits HTML into tokens (like a simplified HTML5 tokenizer).
+// Unclosed tags at the end of the input are dropped.
+type htmlTokenizer struct {
+	in       string
+	pos      int
+	raw_text string // name of the element whose text content is read
+}
+
+func newHTMLTokenizer(in string) *htmlTokenizer {
+	return &htmlTokenizer{in: in}
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// next returns the next token; false at the end of the input.
+func (z *htmlTokenizer) next() (htmlToken, bool) {
+	if z.pos >= len(z.in) {
+		return htmlToken{}, false
+	}
+	start := z.pos
+	rest := z.in[z.pos:]
+
+	if z.raw_text != "" {
+		// Text up to the end tag of the element
+		end := strings.Index(strings.ToLower(rest), "</"+z.raw_text)
+		z.raw_text = ""
+		if end < 0 {
+			end = len(rest)
+		}
+		if end > 0 {
+			z.pos += end
+			return htmlToken{typ: htmlText, raw: rest[:end]}, true
+		}
+	}
+
+	if rest[0] != '<' || len(rest) == 1 {
+		end := strings.IndexByte(rest[1:], '<')
+		if end < 0 {
+			z.pos = len(z.in)
+		} else {
+			z.pos += end + 1
+		}
+		return htmlToken{typ: htmlText, raw: z.in[start:z.pos]}, true
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		end := strings.Index(rest[4:], "-->")
+		if end < 0 {
+			z.pos = len(z.in)
+		} else {
+			z.pos += 4 + end + 3
+		}
+		return htmlToken{typ: htmlComment, raw: z.in[start:z.pos]}, true
+	case rest[1] == '!' || rest[1] == '?':
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			z.pos = len(z.in)
+		} else {
+			z.pos += end + 1
+		}
+		return htmlToken{typ: htmlComment, raw: z.in[start:z.pos]}, true
+	case rest[1] == '/' && len(rest) > 2 && isASCIILetter(rest[2]):
+		z.pos += 2
+		name := z.readName()
+		// Attributes of end tags are ignored
+		z.readAttrs()
+		if z.pos >= len(z.in) {
+			return htmlToken{}, false
+		}
+		z.pos++
+		return htmlToken{typ: htmlEndTag, raw: z.in[start:z.pos], name: name}, true
+	case isASCIILetter(rest[1]):
+		z.pos++
+		name := z.readName()
+		attrs, self_closing := z.readAttrs()
+		if z.pos >= len(z.in) {
+			return htmlToken{}, false
+		}
+		z.pos++
+		if html_raw_text_elements[name] && !self_closing {
+			z.raw_text = name
+		}
+		return htmlToken{typ: htmlStartTag, raw: z.in[start:z.pos], name: name, attrs: attrs, self_closing: self_closing}, true
+	}
+
+	// A '<' which doesn't start a tag is text
+	end := strings.IndexByte(rest[1:], '<')
+	if end < 0 {
+		z.pos = len(z.in)
+	} else {
+		z.pos += end + 1
+	}
+	return htmlToken{typ: htmlText, raw: z.in[start:z.pos]}, true
+}
+
+// readName reads a tag or attribute name (lowercased).
+func (z *htmlTokenizer) readName() string {
+	start := z.pos
+	for z.pos < len(z.in) {
+		c := z.in[z.pos]
+		if isHTMLSpace(c) || c == '/' || c == '>' || (c == '=' && z.pos > start) {
+			break
+		}
+		z.pos++
+	}
+	return strings.ToLower(z.in[start:z.pos])
+}
+
+// readAttrs reads the attributes of a tag up to (but excluding) its '>'.
+func (z *htmlTokenizer) readAttrs() ([]htmlAttr, bool) {
+	var attrs []htmlAttr
+	self_closing := false
+	for z.pos < len(z.in) {
+		c := z.in[z.pos]
+		switch {
+		case c == '>':
+			return attrs, self_closing
+		case isHTMLSpace(c):
+			z.pos++
+			continue
+		case c == '/':
+			z.pos++
+			self_closing = true
+			continue
+		}
+		self_closing = false
+
+		attr := htmlAttr{name: z.readName()}
+		for z.pos < len(z.in) && isHTMLSpace(z.in[z.pos]) {
+			z.pos++
+		}
+		if z.pos < len(z.in) && z.in[z.pos] == '=' {
+			z.pos++
+			for z.pos < len(z.in) && isHTMLSpace(z.in[z.pos]) {
+				z.pos++
+			}
+			attr.value = z.readValue()
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, self_closing
+}
+
+// readValue reads a (quoted or unquoted) attribute value.
+func (z *htmlTokenizer) readValue() string {
+	if z.pos >= len(z.in) {
+		return ""
+	}
+	if quote := z.in[z.pos]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(z.in[z.pos+1:], quote)
+		if end < 0 {
+			value := z.in[z.pos+1:]
+			z.pos = len(z.in)
+			return value
+		}
+		value := z.in[z.pos+1 : z.pos+1+end]
+		z.pos += end + 2
+		return value
+	}
+	start := z.pos
+	for z.pos < len(z.in) && !isHTMLSpace(z.in[z.pos]) && z.in[z.pos] != '>' {
+		z.pos++
+	}
+	return z.in[start:z.pos]
+}
+
+// SanitizeAllowList contains the tags (and their attributes) kept by the
+// sanitize_html filter and by markdown:"sanitize" (unless an allow-list is
+// given). Change it before executing templates; it's not synchronized.
+var SanitizeAllowList = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": {"cite"},
+	"br": nil, "code": {"class"}, "dd": nil, "del": nil, "dl": nil, "dt": nil,
+	"em": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"hr": nil, "i": nil, "img": {"src", "alt", "title", "width", "height"},
+	"li": nil, "ol": {"start"}, "p": nil, "pre": nil, "s": nil, "span": nil,
+	"strong": nil, "sub": nil, "sup": nil, "table": nil, "tbody": nil,
+	"td": {"colspan", "rowspan"}, "th": {"colspan", "rowspan"}, "thead": nil,
+	"tr": nil, "u": nil, "ul": nil,
+}
+
+// The schemes which are allowed in URL attributes (like href) by the
+// sanitizer; relative URLs are always allowed.
+var SanitizeURLSchemes = []string{"http", "https", "mailto", "ftp", "tel"}
+
+// Attributes whose values are URLs
+var html_url_attrs = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true,
+	"poster": true, "background": true, "longdesc": true, "xlink:href": true,
+}
+
+var re_url_scheme = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.\-]*):`)
+
+// isSafeURL returns whether url (entities decoded) is relative or uses one of
+// the SanitizeURLSchemes; browsers ignore control characters and whitespace,
+// so they're ignored here as well (like in "java\tscript:").
+func isSafeURL(url string) bool {
+	url = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, url)
+	m := re_url_scheme.FindStringSubmatch(url)
+	if m == nil {
+		return true
+	}
+	scheme := strings.ToLower(m[1])
+	for _, allowed := range SanitizeURLSchemes {
+		if scheme == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAllowList parses an allow-list like "p,br,a[href title]".
+func parseAllowList(list string) (map[string][]string, error) {
+	allowed := make(map[string][]string)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, attrs := item, ""
+		if idx := strings.IndexByte(item, '['); idx >= 0 {
+			if !strings.HasSuffix(item, "]") {
+				return nil, errors.New(fmt.Sprintf("Invalid allow-list entry '%s' (expected like 'a[href title]').", item))
+			}
+			name, attrs = item[:idx], item[idx+1:len(item)-1]
+		}
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isASCIILetter(byte(r)) && !('0' <= r && r <= '9') }) >= 0 {
+			return nil, errors.New(fmt.Sprintf("Invalid tag name '%s' in the allow-list.", name))
+		}
+		allowed[strings.ToLower(name)] = strings.Fields(strings.ToLower(attrs))
+	}
+	return allowed, nil
+}
+
+// sanitizeHTML removes all tags and attributes which are not part of the
+// allow-list (the contents of removed tags are kept as text, but not those of
+// script and style elements), comments and URLs with disallowed schemes. The
+// result is well-formed: text is escaped and open tags are closed.
+func sanitizeHTML(in string, allowed map[string][]string) string {
+	parts := make([]string, 0, 50)
+	open_tags := make([]string, 0, 10)
+	skip_text := false // the text of a removed script/style element follows
+
+	z := newHTMLTokenizer(in)
+	for {
+		token, ok := z.next()
+		if !ok {
+			break
+		}
+		skip := skip_text
+		skip_text = false
+
+		switch token.typ {
+		case htmlText:
+			if !skip {
+				parts = append(parts, escapeHTML(html.UnescapeString(token.raw)))
+			}
+		case htmlStartTag:
+			attr_names, is_allowed := allowed[token.name]
+			if !is_allowed {
+				skip_text = token.name == "script" || token.name == "style"
+				continue
+			}
+
+			tag := "<" + token.name
+			for _, attr := range token.attrs {
+				if !containsString(attr_names, attr.name) {
+					continue
+				}
+				value := html.UnescapeString(attr.value)
+				if html_url_attrs[attr.name] && !isSafeURL(value) {
+					continue
+				}
+				tag += fmt.Sprintf(` %s="%s"`, attr.name, escapeAttr(value))
+			}
+			parts = append(parts, tag+">")
+			if !html_void_elements[token.name] {
+				open_tags = append(open_tags, token.name)
+			}
+		case htmlEndTag:
+			// Close the tag and all tags opened after it; end tags without
+			// a start tag are removed
+			for i := len(open_tags) - 1; i >= 0; i-- {
+				if open_tags[i] == token.name {
+					for j := len(open_tags) - 1; j >= i; j-- {
+						parts = append(parts, "</"+open_tags[j]+">")
+					}
+					open_tags = open_tags[:i]
+					break
+				}
+			}
+		}
+	}
+
+	for i := len(open_tags) - 1; i >= 0; i-- {
+		parts = append(parts, "</"+open_tags[i]+">")
+	}
+	return strings.Join(parts, "")
+}
+
+// filterSanitizeHTML removes all tags and attributes of a HTML value which
+// are not in the allow-list (SanitizeAllowList by default); the result is
+// safe. URLs are restricted to the SanitizeURLSchemes.
+//
+//     {{ comment.Body|sanitize_html }}
+//     {{ comment.Body|sanitize_html:"p,br,a[href title]" }}
+func filterSanitizeHTML(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	allowed := SanitizeAllowList
+	if len(args) > 1 {
+		return nil, errors.New("sanitize_html takes at most one argument.")
+	} else if len(args) == 1 {
+		var err error
+		allowed, err = parseAllowList(stringValue(args[0]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	ctx.markSafe()
+	return sanitizeHTML(stringValue(value), allowed), nil
+}
+
+// filterMarkdown converts CommonMark to HTML. HTML contained in the value is
+// escaped unless the value is marked as safe; with the argument "sanitize"
+// the HTML is kept, but the result is sanitized (see sanitize_html).
+//
+//     {{ post.Body|markdown }}
+//     {{ post.Body|markdown:"sanitize" }}
+func filterMarkdown(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	sanitize := false
+	if len(args) > 1 {
+		return nil, errors.New("markdown takes at most one argument.")
+	} else if len(args) == 1 {
+		if mode := stringValue(args[0]); mode != "sanitize" {
+			return nil, errors.New(fmt.Sprintf("markdown's argument must be \"sanitize\", not '%s'.", mode))
+		}
+		sanitize = true
+	}
+
+	trusted := sanitize || ctx.HasVisited("unsafe", "safe")
+	output := renderMarkdown(stringValue(value), trusted)
+	if sanitize {
+		output = sanitizeHTML(output, SanitizeAllowList)
+	}
+	ctx.markSafe()
+	return output, nil
+}
+
+// Parsed tag lists of striptags (see stripTagSet)
+var (
+	strip_tag_sets       = make(map[string]map[string]bool)
+	strip_tag_sets_mutex sync.RWMutex
+)
+
+// stripTagSet returns the set of (lowercased) tag names of a comma-separated
+// tag list like "b, em"; the sets are cached as tag lists are usually
+// constants of a template.
+func stripTagSet(taglist string) map[string]bool {
+	strip_tag_sets_mutex.RLock()
+	tags, has_tags := strip_tag_sets[taglist]
+	strip_tag_sets_mutex.RUnlock()
+	if has_tags {
+		return tags
+	}
+
+	tags = make(map[string]bool)
+	for _, tag := range strings.Split(taglist, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags[tag] = true
+		}
+	}
+
+	strip_tag_sets_mutex.Lock()
+	if len(strip_tag_sets) >= 1000 {
+		// Tag lists built at runtime shouldn't fill the memory
+		strip_tag_sets = make(map[string]map[string]bool)
+	}
+	strip_tag_sets[taglist] = tags
+	strip_tag_sets_mutex.Unlock()
+
+	return tags
+}
+
+// stripTags removes the given tags (regardless of their attributes) or all
+// tags and comments if tags is nil. The text is kept, except the contents
+// of removed script and style elements.
+func stripTags(in string, tags map[string]bool) string {
+	parts := make([]string, 0, 16)
+	z := newHTMLTokenizer(in)
+	skip_text := false
+	for {
+		token, ok := z.next()
+		if !ok {
+			break
+		}
+		skip := skip_text
+		skip_text = false
+
+		switch token.typ {
+		case htmlText:
+			if !skip {
+				parts = append(parts, token.raw)
+			}
+		case htmlComment:
+			if tags != nil {
+				parts = append(parts, token.raw)
+			}
+		case htmlStartTag, htmlEndTag:
+			if tags != nil && !tags[token.name] {
+				parts = append(parts, token.raw)
+				continue
+			}
+			skip_text = token.typ == htmlStartTag && (token.name == "script" || token.name == "style")
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// filterStriptags removes all HTML tags (and comments) or only the tags of
+// the given comma-separated list; the contents of removed script and style
+// elements are removed as well:
+//
+//     {{ "<b class=\"x\">Hi</b> <em>there</em>"|striptags }} displays Hi there
+//     {{ "<b class=\"x\">Hi</b> <em>there</em>"|striptags:"b"|unsafe }} displays Hi <em>there</em>
+func filterStriptags(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	str, is_str := value.(string)
+	if !is_str {
+		return nil, errors.New(fmt.Sprintf("%v is not of type string", value))
+	}
+
+	if len(args) > 1 {
+		return nil, errors.New("Please provide a comma-seperated string with tags (or no string to remove all tags).")
+	}
+
+	var tags map[string]bool
+	if len(args) == 1 {
+		taglist, is_string := args[0].(string)
+		if !is_string {
+			return nil, errors.New(fmt.Sprintf("Taglist must be a string, not %T ('%v')", args[0], args[0]))
+		}
+		tags = stripTagSet(taglist)
+	}
+
+	return strings.TrimSpace(stripTags(str, tags)), nil
+}
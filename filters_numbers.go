@@ -0,0 +1,262 @@
+package pongo
+
+// Number filters; they closely follow Django's implementations and accept
+// ints, floats and numeric strings.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// numericValue is like toNumber, but it also converts numeric strings.
+func numericValue(value interface{}) (int64, float64, bool, bool) {
+	if i, f, is_int, is_number := toNumber(value); is_number {
+		return i, f, is_int, true
+	}
+	if str, is_str := value.(string); is_str {
+		str = strings.TrimSpace(str)
+		if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return i, float64(i), true, true
+		}
+		if f, err := strconv.ParseFloat(str, 64); err == nil {
+			return int64(f), f, false, true
+		}
+	}
+	return 0, 0, false, false
+}
+
+// filterAdd adds numbers, concatenates strings and lists:
+//
+//     {{ 5|add:"3" }} displays 8
+//     {{ first|add:second }} displays all elements of the lists first and second
+func filterAdd(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("add requires exactly one argument.")
+	}
+	arg := args[0]
+
+	if vi, vf, v_is_int, v_is_number := numericValue(value); v_is_number {
+		if ai, af, a_is_int, a_is_number := numericValue(arg); a_is_number {
+			if sum := vi + ai; v_is_int && a_is_int && (sum > vi) == (ai > 0) && int64(int(sum)) == sum {
+				return int(sum), nil
+			}
+			return vf + af, nil
+		}
+	}
+
+	if str, is_str := value.(string); is_str {
+		if arg_str, is_str := arg.(string); is_str {
+			return str + arg_str, nil
+		}
+	}
+
+	rv := reflect.ValueOf(value)
+	ra := reflect.ValueOf(arg)
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && (ra.Kind() == reflect.Slice || ra.Kind() == reflect.Array) {
+		list_type := reflect.TypeOf([]interface{}{})
+		if rv.Type().Elem() == ra.Type().Elem() {
+			list_type = reflect.SliceOf(rv.Type().Elem())
+		}
+		list := reflect.MakeSlice(list_type, 0, rv.Len()+ra.Len())
+		for _, l := range []reflect.Value{rv, ra} {
+			for i := 0; i < l.Len(); i++ {
+				list = reflect.Append(list, l.Index(i))
+			}
+		}
+		return list.Interface(), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Cannot add %v (%T) and %v (%T).", value, value, arg, arg))
+}
+
+// filterIntcomma adds commas (or the locale's group separator) every three
+// digits:
+//
+//     {{ 45000|intcomma }} displays 45,000
+//     {{ 1234567.25|intcomma }} displays 1,234,567.25
+func filterIntcomma(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	i, f, is_int, is_number := numericValue(value)
+	if !is_number {
+		return nil, errors.New(fmt.Sprintf("%v (%T) is not a number", value, value))
+	}
+	str := strconv.FormatInt(i, 10)
+	if !is_int {
+		str = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return ctx.Locale().formatNumber(str, true), nil
+}
+
+// groupDigits inserts sep every three digits into the integer part of the
+// formatted number str and replaces its decimal point by decimal.
+func groupDigits(str string, sep string, decimal string) string {
+	sign := ""
+	if strings.HasPrefix(str, "-") {
+		sign, str = "-", str[1:]
+	}
+	fraction := ""
+	if dot := strings.IndexByte(str, '.'); dot >= 0 {
+		str, fraction = str[:dot], decimal+str[dot+1:]
+	}
+	groups := make([]string, 0, len(str)/3+1)
+	for len(str) > 3 {
+		groups = append([]string{str[len(str)-3:]}, groups...)
+		str = str[:len(str)-3]
+	}
+	groups = append([]string{str}, groups...)
+	return sign + strings.Join(groups, sep) + fraction
+}
+
+var intword_units = []struct {
+	exponent int
+	name     string
+}{
+	{6, "million"}, {9, "billion"}, {12, "trillion"}, {15, "quadrillion"},
+	{18, "quintillion"}, {21, "sextillion"}, {24, "septillion"}, {27, "octillion"},
+	{30, "nonillion"}, {33, "decillion"}, {100, "googol"},
+}
+
+// filterIntword converts large numbers to a friendly text (numbers less than
+// a million are returned unchanged):
+//
+//     {{ 1200000|intword }} displays 1.2 million
+func filterIntword(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	i, f, is_int, is_number := numericValue(value)
+	if !is_number {
+		return nil, errors.New(fmt.Sprintf("%v (%T) is not a number", value, value))
+	}
+	abs := math.Abs(f)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return value, nil
+	}
+	if abs < 1e6 {
+		if is_int {
+			return int(i), nil
+		}
+		return f, nil
+	}
+	unit := intword_units[0]
+	for _, next := range intword_units[1:] {
+		if abs < math.Pow10(next.exponent) {
+			break
+		}
+		unit = next
+	}
+	return fmt.Sprintf("%.1f %s", f/math.Pow10(unit.exponent), unit.name), nil
+}
+
+// filterFilesizeformat formats a number of bytes as a human readable size:
+//
+//     {{ 123456789|filesizeformat }} displays 117.7 MB
+func filterFilesizeformat(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	_, bytes, _, is_number := numericValue(value)
+	if !is_number {
+		return nil, errors.New(fmt.Sprintf("%v (%T) is not a number", value, value))
+	}
+	sign := ""
+	if bytes < 0 {
+		sign, bytes = "-", -bytes
+	}
+	if bytes < 1024 {
+		if int64(bytes) == 1 {
+			return sign + "1 byte", nil
+		}
+		return fmt.Sprintf("%s%d bytes", sign, int64(bytes)), nil
+	}
+	size := bytes / 1024
+	for _, unit := range []string{"KB", "MB", "GB", "TB"} {
+		if size < 1024 {
+			return fmt.Sprintf("%s%s %s", sign, ctx.Locale().formatNumber(fmt.Sprintf("%.1f", size), false), unit), nil
+		}
+		size /= 1024
+	}
+	return fmt.Sprintf("%s%s PB", sign, ctx.Locale().formatNumber(fmt.Sprintf("%.1f", size), false)), nil
+}
+
+// filterDivisibleby returns whether the value is divisible by the argument:
+//
+//     {% if forloop.Counter|divisibleby:3 %}
+func filterDivisibleby(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("divisibleby requires exactly one argument.")
+	}
+	i, f, is_int, is_number := numericValue(value)
+	if !is_number {
+		return nil, errors.New(fmt.Sprintf("%v (%T) is not a number", value, value))
+	}
+	divisor, divisor_f, divisor_is_int, is_number := numericValue(args[0])
+	if !is_number || divisor_f == 0 {
+		return nil, errors.New(fmt.Sprintf("divisibleby requires a non-zero number, not %T ('%v').", args[0], args[0]))
+	}
+	if is_int && divisor_is_int {
+		return i%divisor == 0, nil
+	}
+	// Floats aren't truncated (3.5 isn't divisible by 3)
+	return math.Mod(f, divisor_f) == 0, nil
+}
+
+// filterPluralize returns a plural suffix unless the value (a number or the
+// length of a list) is 1. The suffix defaults to "s"; "singular,plural"
+// suffixes can be given as argument:
+//
+//     {{ count }} vote{{ count|pluralize }}
+//     {{ count }} class{{ count|pluralize:"es" }}
+//     {{ count }} cand{{ count|pluralize:"y,ies" }}
+func filterPluralize(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	singular, plural := "", "s"
+	if len(args) > 1 {
+		return nil, errors.New("pluralize takes at most one argument.")
+	} else if len(args) == 1 {
+		suffixes := strings.Split(stringValue(args[0]), ",")
+		switch len(suffixes) {
+		case 1:
+			plural = suffixes[0]
+		case 2:
+			singular, plural = suffixes[0], suffixes[1]
+		default:
+			return nil, errors.New(fmt.Sprintf("Invalid suffixes '%s' (expected 'plural' or 'singular,plural').", args[0]))
+		}
+	}
+
+	var is_one bool
+	if _, f, _, is_number := numericValue(value); is_number {
+		is_one = f == 1
+	} else {
+		length, err := filterLength(value, nil, ctx)
+		if err != nil {
+			return nil, err
+		}
+		is_one = length == 1
+	}
+	if is_one {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+// filterGetDigit returns the digit at the given position, counted from the
+// right (1 is the rightmost digit):
+//
+//     {{ 123456789|get_digit:2 }} displays 8
+func filterGetDigit(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	position, err := filterIntArg("get_digit", args)
+	if err != nil {
+		return nil, err
+	}
+	i, _, is_int, is_number := numericValue(value)
+	if !is_number || !is_int {
+		return nil, errors.New(fmt.Sprintf("%v (%T) is not an integer", value, value))
+	}
+	if position < 1 {
+		return value, nil
+	}
+	digits := strconv.FormatInt(i, 10)
+	digits = strings.TrimPrefix(digits, "-")
+	if position > len(digits) {
+		return 0, nil
+	}
+	return int(digits[len(digits)-position] - '0'), nil
+}
@@ -0,0 +1,243 @@
+package pongo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ArgType is the expected type of a filter argument (see FilterSpec).
+type ArgType int
+
+const (
+	AnyArg    ArgType = iota
+	StringArg         // a string
+	IntArg            // an int, an integral float or a numeric string (like filterIntArg)
+	NumberArg         // an int, a float or a numeric string
+	BoolArg           // true or false
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case StringArg:
+		return "string"
+	case IntArg:
+		return "int"
+	case NumberArg:
+		return "number"
+	case BoolArg:
+		return "bool"
+	}
+	return "value"
+}
+
+// accepts returns whether a literal argument (string, int, float64 or bool)
+// is of type t.
+func (t ArgType) accepts(arg interface{}) bool {
+	switch t {
+	case StringArg:
+		_, is_string := arg.(string)
+		return is_string
+	case IntArg:
+		switch val := arg.(type) {
+		case int:
+			return true
+		case float64:
+			return val == math.Trunc(val)
+		case string:
+			_, err := strconv.Atoi(strings.TrimSpace(val))
+			return err == nil
+		}
+		return false
+	case NumberArg:
+		_, _, _, is_number := numericValue(arg)
+		return is_number
+	case BoolArg:
+		_, is_bool := arg.(bool)
+		return is_bool
+	}
+	return true
+}
+
+// A FilterSpec describes the arguments of a filter. Filters which have a spec
+// (see FilterSpecs and RegisterFilter) are checked when a template is parsed:
+//
+//     {{ name|truncatechars }}          Filter 'truncatechars' takes 1 argument(s), 0 given.
+//     {{ name|truncatechars:"many" }}   Filter 'truncatechars' requires an int as argument 1, not string ('many').
+//
+// Only literal arguments can be type-checked; variables are passed to the
+// filter as they are.
+type FilterSpec struct {
+	MinArgs  int
+	MaxArgs  int       // a negative value means no limit
+	ArgTypes []ArgType // types of the arguments (missing ones are AnyArg)
+
+	// SafePreserving filters don't add HTML special characters to the value,
+	// so the result is safe if the value was (like Django's is_safe).
+	SafePreserving bool
+
+	// Doc describes the filter (used to generate documentation).
+	Doc string
+}
+
+func (spec *FilterSpec) arityString() string {
+	switch {
+	case spec.MinArgs == spec.MaxArgs:
+		return fmt.Sprintf("%d", spec.MinArgs)
+	case spec.MaxArgs < 0:
+		return fmt.Sprintf("at least %d", spec.MinArgs)
+	}
+	return fmt.Sprintf("%d to %d", spec.MinArgs, spec.MaxArgs)
+}
+
+// argType returns the expected type of the i-th argument (0-based).
+func (spec *FilterSpec) argType(i int) ArgType {
+	if i < len(spec.ArgTypes) {
+		return spec.ArgTypes[i]
+	}
+	return AnyArg
+}
+
+// checkArgs checks the number of arguments of filter name and the types of
+// its literal arguments.
+func (spec *FilterSpec) checkArgs(name string, args []interface{}) error {
+	if len(args) < spec.MinArgs || (spec.MaxArgs >= 0 && len(args) > spec.MaxArgs) {
+		return errors.New(fmt.Sprintf("Filter '%s' takes %s argument(s), %d given.", name, spec.arityString(), len(args)))
+	}
+	for i, arg := range args {
+		if _, is_ident := arg.(exprIdent); is_ident {
+			continue
+		}
+		if t := spec.argType(i); !t.accepts(arg) {
+			article := "a"
+			if t == IntArg {
+				article = "an"
+			}
+			return errors.New(fmt.Sprintf("Filter '%s' requires %s %s as argument %d, not %T ('%v').", name, article, t, i+1, arg, arg))
+		}
+	}
+	return nil
+}
+
+// Usage returns how filter name is used, e. g. "truncatechars:<int>",
+// "date[:<string>]" or "concat:<string>[,<string>...]" (no limit).
+func (spec *FilterSpec) Usage(name string) string {
+	max := spec.MaxArgs
+	if max < 0 {
+		max = spec.MinArgs + 1
+	}
+	usage, optional := name, 0
+	for i := 0; i < max; i++ {
+		sep := ","
+		if i == 0 {
+			sep = ":"
+		}
+		if i >= spec.MinArgs {
+			usage += "["
+			optional++
+		}
+		usage += fmt.Sprintf("%s<%s>", sep, spec.argType(i))
+	}
+	if spec.MaxArgs < 0 {
+		usage += "..."
+	}
+	return usage + strings.Repeat("]", optional)
+}
+
+// RegisterFilter adds a filter together with its spec (which may be nil)
+// to Filters and FilterSpecs. Filters can still be added to Filters
+// directly, their arguments are not checked then.
+func RegisterFilter(name string, fn FilterFunc, spec *FilterSpec) error {
+	if name == "" || strings.ContainsAny(name, "|:, ") {
+		return errors.New(fmt.Sprintf("Invalid filter name '%s'.", name))
+	}
+	if spec != nil && spec.MaxArgs >= 0 && spec.MinArgs > spec.MaxArgs {
+		return errors.New(fmt.Sprintf("Filter '%s' can't take %d to %d argument(s).", name, spec.MinArgs, spec.MaxArgs))
+	}
+	Filters[name] = fn
+	if spec != nil {
+		FilterSpecs[name] = spec
+	} else {
+		delete(FilterSpecs, name)
+	}
+	return nil
+}
+
+// FilterSpecs are the specs of the filters (see FilterSpec).
+var FilterSpecs = map[string]*FilterSpec{
+	"safe":        {SafePreserving: true, Doc: "Escapes the value for HTML unless it has been marked as safe; it's applied to every output automatically."},
+	"unsafe":      {SafePreserving: true, Doc: "Marks the value as safe, so it won't be escaped."},
+	"lower":       {SafePreserving: true, Doc: "Converts a string to lowercase."},
+	"upper":       {SafePreserving: true, Doc: "Converts a string to uppercase."},
+	"capitalize":  {SafePreserving: true, Doc: "Capitalizes the first letter of every word."},
+	"default":     {MinArgs: 1, MaxArgs: 1, Doc: "Returns the argument if the value is the zero value of its type."},
+	"trim":        {SafePreserving: true, Doc: "Removes leading and trailing whitespace."},
+	"length":      {SafePreserving: true, Doc: "Returns the length of a string, slice, array or map."},
+	"join":        {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{StringArg}, Doc: "Joins the items of a slice or array with the separator."},
+	"striptags":   {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Removes all HTML tags or the tags of a comma-separated list; the contents of removed script and style elements are removed as well."},
+	"time_format": {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Formats a time using a Go layout (like \"2006-01-02\")."},
+	"floatformat": {MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Rounds a number to the given number of decimal places (1 by default); trailing zeros are removed unless the argument is positive."},
+
+	"title":              {SafePreserving: true, Doc: "Converts a string to titlecase."},
+	"truncatechars":      {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Truncates a string to the given number of characters (including the ellipsis)."},
+	"truncatewords":      {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Truncates a string after the given number of words."},
+	"truncatechars_html": {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Like truncatechars, but HTML tags aren't counted and are closed properly."},
+	"wordcount":          {SafePreserving: true, Doc: "Returns the number of words."},
+	"wordwrap":           {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Wraps words at the given line length."},
+	"center":             {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Centers the value in a field of the given width."},
+	"ljust":              {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Left-aligns the value in a field of the given width."},
+	"rjust":              {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Right-aligns the value in a field of the given width."},
+	"cut":                {MinArgs: 1, MaxArgs: 1, Doc: "Removes all occurrences of the argument."},
+	"slugify":            {SafePreserving: true, Doc: "Converts a string to a URL slug (lowercase ASCII letters, digits and hyphens)."},
+	"linebreaks":         {SafePreserving: true, Doc: "Converts newlines to <br /> and blank lines to paragraphs."},
+	"linebreaksbr":       {SafePreserving: true, Doc: "Converts newlines to <br />."},
+	"linenumbers":        {SafePreserving: true, Doc: "Prefixes every line with its line number."},
+	"addslashes":         {Doc: "Escapes quotes and backslashes with backslashes."},
+	"make_list":          {Doc: "Converts a value to a list of its characters (or digits)."},
+	"stringformat":       {MinArgs: 1, MaxArgs: 1, Doc: "Formats the value using a printf verb without the % (like \"05d\")."},
+	"phone2numeric":      {SafePreserving: true, Doc: "Converts the letters of a phone number to digits."},
+
+	"first":            {Doc: "Returns the first item of a list (or character of a string)."},
+	"last":             {Doc: "Returns the last item of a list (or character of a string)."},
+	"slice":            {MinArgs: 1, MaxArgs: 1, Doc: "Returns a slice of a list or string using Python's slice syntax (like \":2\")."},
+	"reverse":          {Doc: "Reverses a list or string."},
+	"sort":             {Doc: "Sorts a list of strings or numbers."},
+	"dictsort":         {MinArgs: 1, MaxArgs: 1, Doc: "Sorts a list of maps or structs by the given key."},
+	"dictsortreversed": {MinArgs: 1, MaxArgs: 1, Doc: "Like dictsort, but in reverse order."},
+	"unique":           {Doc: "Removes duplicate items of a list."},
+	"random":           {Doc: "Returns a random item of a list."},
+	"length_is":        {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Returns whether the length of the value is the argument."},
+	"attribute":        {MinArgs: 1, MaxArgs: 1, Doc: "Returns a field, method result or map entry of the value."},
+	"map":              {MinArgs: 1, MaxArgs: 1, Doc: "Returns the given attribute of every item of a list."},
+
+	"add":            {MinArgs: 1, MaxArgs: 1, Doc: "Adds numbers or concatenates strings and lists."},
+	"intcomma":       {SafePreserving: true, Doc: "Groups the digits of a number by thousands."},
+	"intword":        {SafePreserving: true, Doc: "Converts a large number to words (like 1.2 million)."},
+	"filesizeformat": {SafePreserving: true, Doc: "Formats a number of bytes as a human-readable file size."},
+	"divisibleby":    {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{NumberArg}, SafePreserving: true, Doc: "Returns whether the value is divisible by the argument."},
+	"pluralize":      {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, Doc: "Returns a plural suffix (\"s\" or the argument like \"y,ies\") unless the value is 1."},
+	"get_digit":      {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Returns the digit at the given position, counted from the right (starting with 1)."},
+
+	"currency": {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Formats an amount in the currency of the locale or the given ISO 4217 code."},
+
+	"date":        {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Formats a date like Django's date filter (the locale's format by default)."},
+	"time":        {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Formats a time like Django's time filter (the locale's format by default)."},
+	"timesince":   {MaxArgs: 1, SafePreserving: true, Doc: "Returns the time since the value (or until the argument), like \"4 days, 6 hours\"."},
+	"timeuntil":   {MaxArgs: 1, SafePreserving: true, Doc: "Returns the time until the value (from now or the argument)."},
+	"naturalday":  {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Returns \"today\", \"tomorrow\" or \"yesterday\", or formats the date like date."},
+	"naturaltime": {SafePreserving: true, Doc: "Returns the time relative to now, like \"3 minutes ago\"."},
+	"timezone":    {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Converts a time to the given IANA time zone."},
+
+	"urlencode":   {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Escapes a value for the use in a URL; the argument are the characters not to escape (\"/\" by default)."},
+	"iriencode":   {SafePreserving: true, Doc: "Converts an IRI to a URL."},
+	"urlize":      {SafePreserving: true, Doc: "Converts URLs and email addresses in text to links."},
+	"urlizetrunc": {MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Like urlize, but truncates the texts of links to the given number of characters."},
+	"base64":      {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, Doc: "Encodes a value using base64 or decodes it with the argument \"decode\"."},
+	"json":        {MaxArgs: 1, ArgTypes: []ArgType{IntArg}, SafePreserving: true, Doc: "Encodes a value as JSON, optionally indented by the given number of spaces."},
+	"json_script": {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Encodes a value as JSON in a <script> element with the given id."},
+
+	"sanitize_html": {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Removes all tags and attributes which are not in the allow-list (like \"p,br,a[href title]\")."},
+	"markdown":      {MaxArgs: 1, ArgTypes: []ArgType{StringArg}, SafePreserving: true, Doc: "Converts CommonMark to HTML; with \"sanitize\" HTML is kept, but the result is sanitized."},
+}
@@ -0,0 +1,474 @@
+package pongo
+
+// String filters; they closely follow Django's implementations. Unlike the
+// basic filters (like lower) they convert non-string values to strings
+// (like Django's stringfilter decorator does).
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stringValue converts value to a string (nil is the empty string).
+func stringValue(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// filterIntArg converts the argument of filter name to an int; ints, integral
+// floats and numeric strings are accepted.
+func filterIntArg(name string, args []interface{}) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New(fmt.Sprintf("%s requires exactly one argument.", name))
+	}
+	switch val := args[0].(type) {
+	case int:
+		return val, nil
+	case int64:
+		return int(val), nil
+	case float64:
+		if val == math.Trunc(val) {
+			return int(val), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(val)); err == nil {
+			return i, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("%s requires an integer argument, not %T ('%v').", name, args[0], args[0]))
+}
+
+var (
+	re_title_apostrophe = regexp.MustCompile("([a-z])'([A-Z])")
+	re_title_digit      = regexp.MustCompile("[0-9][A-Z]")
+)
+
+// filterTitle starts every word with an uppercase character and lowercases
+// the rest:
+//
+//     {{ "my FIRST post"|title }} displays My First Post
+//     {{ "they're 1st"|title }} displays They're 1st
+func filterTitle(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	str := stringValue(value)
+	runes := []rune(str)
+	in_word := false
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			if in_word {
+				runes[i] = unicode.ToLower(r)
+			} else {
+				runes[i] = unicode.ToTitle(r)
+			}
+			in_word = true
+		} else {
+			in_word = false
+		}
+	}
+	str = re_title_apostrophe.ReplaceAllStringFunc(string(runes), strings.ToLower)
+	str = re_title_digit.ReplaceAllStringFunc(str, strings.ToLower)
+	return str, nil
+}
+
+// filterTruncatechars truncates a string to the given number of characters
+// (including the trailing ellipsis):
+//
+//     {{ "Joel is a slug"|truncatechars:7 }} displays Joel i…
+func filterTruncatechars(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	length, err := filterIntArg("truncatechars", args)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(stringValue(value))
+	if len(runes) <= length {
+		return string(runes), nil
+	}
+	if length < 1 {
+		return "…", nil
+	}
+	return string(runes[:length-1]) + "…", nil
+}
+
+// filterTruncatewords truncates a string after the given number of words:
+//
+//     {{ "Joel is a slug"|truncatewords:2 }} displays Joel is …
+func filterTruncatewords(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	length, err := filterIntArg("truncatewords", args)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(stringValue(value))
+	if length < 0 {
+		length = 0
+	}
+	if len(words) > length {
+		return strings.Join(words[:length], " ") + " …", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// Elements which don't have to be closed.
+var html_void_elements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlTagName returns the lowercased name of the tag (like "<p class=x>")
+// and whether it's a closing tag.
+func htmlTagName(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "<")
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+	end := strings.IndexFunc(tag, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == ':')
+	})
+	if end >= 0 {
+		tag = tag[:end]
+	}
+	return strings.ToLower(tag), closing
+}
+
+// filterTruncatecharsHTML is like truncatechars, but it only counts the text
+// (entities count as one character) and closes all tags which are open after
+// the truncation:
+//
+//     {{ "<p>Joel is a slug</p>"|truncatechars_html:7 }} displays <p>Joel i…</p>
+func filterTruncatecharsHTML(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	length, err := filterIntArg("truncatechars_html", args)
+	if err != nil {
+		return nil, err
+	}
+	str := stringValue(value)
+
+	// walk calls fn for every tag and every character (or entity) of the text
+	walk := func(fn func(token string, is_tag bool) bool) {
+		for pos := 0; pos < len(str); {
+			token_len := 0
+			is_tag := false
+			switch str[pos] {
+			case '<':
+				if end := strings.IndexByte(str[pos:], '>'); end > 0 {
+					token_len, is_tag = end+1, true
+				}
+			case '&':
+				if end := strings.IndexByte(str[pos:], ';'); end > 1 && end < 10 && !strings.ContainsAny(str[pos+1:pos+end], " <&") {
+					token_len = end + 1
+				}
+			}
+			if token_len == 0 {
+				_, token_len = utf8.DecodeRuneInString(str[pos:])
+			}
+			if !fn(str[pos:pos+token_len], is_tag) {
+				return
+			}
+			pos += token_len
+		}
+	}
+
+	chars := 0
+	walk(func(token string, is_tag bool) bool {
+		if !is_tag {
+			chars++
+		}
+		return true
+	})
+	if chars <= length {
+		return str, nil
+	}
+
+	output := make([]string, 0, length)
+	open_tags := make([]string, 0, 5)
+	chars = 0
+	walk(func(token string, is_tag bool) bool {
+		if !is_tag {
+			if chars >= length-1 {
+				return false
+			}
+			chars++
+			output = append(output, token)
+			return true
+		}
+		output = append(output, token)
+		name, closing := htmlTagName(token)
+		switch {
+		case name == "" || html_void_elements[name] || strings.HasSuffix(token, "/>"):
+		case closing:
+			// Close the tag and all tags opened after it
+			for i := len(open_tags) - 1; i >= 0; i-- {
+				if open_tags[i] == name {
+					open_tags = open_tags[:i]
+					break
+				}
+			}
+		default:
+			open_tags = append(open_tags, name)
+		}
+		return true
+	})
+	output = append(output, "…")
+	for i := len(open_tags) - 1; i >= 0; i-- {
+		output = append(output, fmt.Sprintf("</%s>", open_tags[i]))
+	}
+	return strings.Join(output, ""), nil
+}
+
+func filterWordcount(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return len(strings.Fields(stringValue(value))), nil
+}
+
+// filterWordwrap wraps the lines after the given number of characters; words
+// longer than that aren't split.
+//
+//     {{ "Joel is a slug"|wordwrap:5 }} displays "Joel\nis a\nslug"
+func filterWordwrap(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	width, err := filterIntArg("wordwrap", args)
+	if err != nil {
+		return nil, err
+	}
+	if width < 1 {
+		return nil, errors.New(fmt.Sprintf("wordwrap requires a positive width, not %d.", width))
+	}
+	lines := strings.Split(stringValue(value), "\n")
+	for i, line := range lines {
+		wrapped := make([]string, 0, 5)
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+				wrapped = append(wrapped, current)
+				current = ""
+			}
+			if current != "" {
+				current += " "
+			}
+			current += word
+		}
+		lines[i] = strings.Join(append(wrapped, current), "\n")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// pad pads the value with spaces to the width given by the argument; fn
+// returns how many of the spaces are added on the left side. Widths exceeding
+// the remaining output of a limited execution are rejected.
+func pad(name string, value interface{}, args []interface{}, ctx *FilterChainContext, fn func(margin, width int) (left int)) (interface{}, error) {
+	width, err := filterIntArg(name, args)
+	if err != nil {
+		return nil, err
+	}
+	str := stringValue(value)
+	margin := width - utf8.RuneCountInString(str)
+	if margin <= 0 {
+		return str, nil
+	}
+	if err := ctx.state.reserve(len(str) + margin); err != nil {
+		return nil, err
+	}
+	left := fn(margin, width)
+	return strings.Repeat(" ", left) + str + strings.Repeat(" ", margin-left), nil
+}
+
+func filterCenter(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	// Same distribution of the spaces as Python's str.center
+	return pad("center", value, args, ctx, func(margin, width int) int { return margin/2 + (margin & width & 1) })
+}
+
+func filterLjust(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return pad("ljust", value, args, ctx, func(margin, width int) int { return 0 })
+}
+
+func filterRjust(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return pad("rjust", value, args, ctx, func(margin, width int) int { return margin })
+}
+
+// filterCut removes all occurrences of the argument:
+//
+//     {{ "String with spaces"|cut:" " }} displays Stringwithspaces
+func filterCut(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("cut requires exactly one argument.")
+	}
+	return strings.Replace(stringValue(value), stringValue(args[0]), "", -1), nil
+}
+
+// Latin characters with diacritics and their ASCII counterparts (used by
+// slugify instead of an unicode normalization).
+var slugify_replacements = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e", 'ğ': "g", 'ì': "i", 'í': "i", 'î': "i",
+	'ï': "i", 'ī': "i", 'į': "i", 'ķ': "k", 'ĺ': "l", 'ľ': "l", 'ļ': "l", 'ñ': "n",
+	'ń': "n", 'ň': "n", 'ņ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o",
+	'ō': "o", 'ő': "o", 'ŕ': "r", 'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ș': "s",
+	'ť': "t", 'ţ': "t", 'ț': "t", 'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u",
+	'ů': "u", 'ű': "u", 'ų': "u", 'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+var (
+	re_slugify_invalid = regexp.MustCompile(`[^\w\s-]`)
+	re_slugify_dashes  = regexp.MustCompile(`[-\s]+`)
+)
+
+// filterSlugify converts a string to ASCII, lowercases it, removes everything
+// except letters, digits, underscores and hyphens and replaces spaces by
+// hyphens:
+//
+//     {{ "Jöel is a slug!"|slugify }} displays joel-is-a-slug
+func filterSlugify(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	str := strings.ToLower(stringValue(value))
+	ascii := make([]string, 0, len(str))
+	for _, r := range str {
+		if r < utf8.RuneSelf {
+			ascii = append(ascii, string(r))
+		} else if replacement, has := slugify_replacements[r]; has {
+			ascii = append(ascii, replacement)
+		}
+	}
+	str = re_slugify_invalid.ReplaceAllString(strings.Join(ascii, ""), "")
+	str = re_slugify_dashes.ReplaceAllString(str, "-")
+	return strings.Trim(str, "-_"), nil
+}
+
+var re_paragraphs = regexp.MustCompile("\n{2,}")
+
+// escapeFilterInput escapes the input of filters producing HTML (unless it's
+// marked as safe already) and marks their result as safe.
+func escapeFilterInput(value interface{}, ctx *FilterChainContext) string {
+	str := strings.Replace(stringValue(value), "\r\n", "\n", -1)
+	str = strings.Replace(str, "\r", "\n", -1)
+	if !ctx.HasVisited("unsafe", "safe") {
+		str = escapeHTML(str)
+	}
+	ctx.markSafe()
+	return str
+}
+
+// filterLinebreaks replaces line breaks with <br> and paragraphs (separated by
+// blank lines) with <p>-tags.
+func filterLinebreaks(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	paragraphs := re_paragraphs.Split(escapeFilterInput(value, ctx), -1)
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = fmt.Sprintf("<p>%s</p>", strings.Replace(paragraph, "\n", "<br>", -1))
+	}
+	return strings.Join(paragraphs, "\n\n"), nil
+}
+
+// filterLinebreaksbr replaces line breaks with <br>.
+func filterLinebreaksbr(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	return strings.Replace(escapeFilterInput(value, ctx), "\n", "<br>", -1), nil
+}
+
+// filterLinenumbers prepends line numbers to the lines:
+//
+//     {{ "one\ntwo"|linenumbers }} displays "1. one\n2. two"
+func filterLinenumbers(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	lines := strings.Split(escapeFilterInput(value, ctx), "\n")
+	width := len(strconv.Itoa(len(lines)))
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%0*d. %s", width, i+1, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// filterAddslashes adds slashes before quotes (e. g. for strings in CSV).
+func filterAddslashes(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	str := strings.Replace(stringValue(value), "\\", "\\\\", -1)
+	str = strings.Replace(str, "\"", "\\\"", -1)
+	str = strings.Replace(str, "'", "\\'", -1)
+	return str, nil
+}
+
+// filterMakeList returns the characters of a string (or the digits of a
+// number) as a list.
+func filterMakeList(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	str := stringValue(value)
+	list := make([]string, 0, len(str))
+	for _, r := range str {
+		list = append(list, string(r))
+	}
+	return list, nil
+}
+
+var re_stringformat = regexp.MustCompile(`^([-+ #0]*)(\d*)(\.\d+)?([sdiuoxXeEfFgGr])$`)
+
+// filterStringformat formats the value according to a Python format
+// specifier (without the leading %):
+//
+//     {{ 10|stringformat:"04d" }} displays 0010
+//     {{ 3.14159|stringformat:".2f" }} displays 3.14
+func filterStringformat(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("stringformat requires exactly one argument.")
+	}
+	spec := stringValue(args[0])
+	parts := re_stringformat.FindStringSubmatch(spec)
+	if parts == nil {
+		return nil, errors.New(fmt.Sprintf("Invalid format specifier '%s'.", spec))
+	}
+
+	verb := parts[4]
+	switch verb {
+	case "s", "r":
+		verb = "v"
+		value = stringValue(value)
+	case "i", "u", "d", "o", "x", "X":
+		if verb == "i" || verb == "u" {
+			verb = "d"
+		}
+		i, f, is_int, is_number := numericValue(value)
+		if !is_number {
+			return nil, errors.New(fmt.Sprintf("%v (%T) is not a number", value, value))
+		}
+		if !is_int {
+			// Floats are truncated (but large ints are kept as they are)
+			i = int64(f)
+		}
+		value = i
+	default:
+		if verb == "F" {
+			verb = "f"
+		}
+		number, err := numberValue(value)
+		if err != nil {
+			return nil, err
+		}
+		value = number
+	}
+	return fmt.Sprintf("%"+parts[1]+parts[2]+parts[3]+verb, value), nil
+}
+
+// numberValue converts ints, floats and numeric strings to a float64.
+func numberValue(value interface{}) (float64, error) {
+	_, f, _, is_number := numericValue(value)
+	if !is_number {
+		return 0, errors.New(fmt.Sprintf("%v (%T) is not a number", value, value))
+	}
+	return f, nil
+}
+
+// filterPhone2numeric converts the letters of a phone number to digits:
+//
+//     {{ "800-COLLECT"|phone2numeric }} displays 800-2655328
+func filterPhone2numeric(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	keypad := []string{"abc", "def", "ghi", "jkl", "mno", "pqrs", "tuv", "wxyz"}
+	return strings.Map(func(r rune) rune {
+		lower := unicode.ToLower(r)
+		for i, letters := range keypad {
+			if strings.ContainsRune(letters, lower) {
+				return rune('2' + i)
+			}
+		}
+		return r
+	}, stringValue(value)), nil
+}
@@ -0,0 +1,1790 @@
+package pongo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Import path of this package (used by generated code)
+var pongoPath = reflect.TypeOf(Template{}).PkgPath()
+
+var renderedBlockType = reflect.TypeOf(renderedBlock(""))
+
+// GenerateGo generates the source of a file of the Go package pkg_name (with
+// the import path pkg_path) which contains a render function for every
+// template in funcs (indexed by the function's name). Every template must be
+// bound to a struct type (see Bind) which becomes the type of the function's
+// data, e. g.
+//
+//     func RenderIndex(go_ctx context.Context, data *Page) (*string, error)
+//
+// The render functions behave like Template.ExecuteContext, but neither parse
+// the templates nor use reflection: identifiers become field accesses and
+// method calls on data, filters are called directly (as registered in the
+// Filters map) and extends, include and blocks are resolved while generating
+// the code. The template's Limits and the FieldLookup options are applied as
+// they are set when generating the code; data must not be nil and panics of
+// methods aren't recovered.
+//
+// Identifiers are verified like Check does. Templates which can't be
+// translated are reported as an error, e. g. lookups on values which are
+// only known at runtime (like interface{}), templates referenced by a name
+// which is only known at runtime, custom tags, translations and templates
+// with a sandbox or a locale. The file isn't built with the tag pongo_gen, so
+// the package can be built without it while (re-)generating it.
+func GenerateGo(pkg_path string, pkg_name string, funcs map[string]*Template) ([]byte, error) {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	file := newGenFile(pkg_path)
+	var code bytes.Buffer
+	for _, name := range names {
+		g := &generator{
+			file:   file,
+			code:   &bytes.Buffer{},
+			blocks: make(blockOverrides),
+			vars:   make(genVars),
+		}
+		if err := g.genFunc(name, funcs[name], &code); err != nil {
+			return nil, err
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by pongo gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "//go:build !pongo_gen\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", pkg_name)
+	src.WriteString(file.importDecl())
+	src.Write(code.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Generated code is invalid (please report this issue): %s", err))
+	}
+	return formatted, nil
+}
+
+// Names of the generated code which can't be used for imported packages
+var genReserved = []string{"data", "go_ctx", "out", "err", "result", "check", "deadline", "output", "iterations", "forContext"}
+
+// Prefixes of the temporary variables of the generated code (followed by a number)
+var genPrefixes = []string{"v", "p", "i", "e", "ok", "n", "items", "keys", "loop", "body", "super", "str", "fctx", "f", "w", "z", "c", "lb", "rb", "lok", "rok"}
+
+// genFile tracks the imports of a generated file.
+type genFile struct {
+	pkg     string            // import path of the generated package
+	imports map[string]string // import path -> package name
+	names   map[string]bool   // used package names
+}
+
+func newGenFile(pkg string) *genFile {
+	f := &genFile{
+		pkg:     pkg,
+		imports: make(map[string]string),
+		names:   make(map[string]bool),
+	}
+	for _, name := range genReserved {
+		f.names[name] = true
+	}
+	return f
+}
+
+// isTempName returns whether name might be the name of a temporary variable.
+func isTempName(name string) bool {
+	for _, prefix := range genPrefixes {
+		if digits := strings.TrimPrefix(name, prefix); digits != name && digits != "" && strings.Trim(digits, "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifier returns the prefix of the identifiers of the package pkg_path
+// (like "strconv."), which is imported if needed.
+func (f *genFile) qualifier(pkg_path string, name string) string {
+	if pkg_path == f.pkg {
+		return ""
+	}
+	if name, has_import := f.imports[pkg_path]; has_import {
+		return name + "."
+	}
+
+	alias := name
+	for i := 2; f.names[alias] || isTempName(alias); i++ {
+		alias = fmt.Sprintf("%s%d", name, i)
+	}
+	f.imports[pkg_path] = alias
+	f.names[alias] = true
+	return alias + "."
+}
+
+func (f *genFile) importDecl() string {
+	var std, other []string
+	for pkg_path, name := range f.imports {
+		spec := strconv.Quote(pkg_path)
+		if name != path.Base(pkg_path) {
+			spec = name + " " + spec
+		}
+		if strings.Contains(strings.SplitN(pkg_path, "/", 2)[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	decl := "import (\n" + strings.Join(std, "\n") + "\n"
+	if len(other) > 0 {
+		decl += "\n" + strings.Join(other, "\n") + "\n"
+	}
+	return decl + ")\n"
+}
+
+// typeName returns the Go syntax of the type t.
+func (f *genFile) typeName(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		switch {
+		case t.PkgPath() == "":
+			// Predeclared type like int or error
+			return t.Name(), nil
+		case strings.Contains(t.Name(), "["):
+			// Instantiated generic type
+		case t.PkgPath() == f.pkg || isExported(t.Name()):
+			pkg_name := strings.TrimSuffix(t.String(), "."+t.Name())
+			return f.qualifier(t.PkgPath(), pkg_name) + t.Name(), nil
+		}
+		return "", errors.New(fmt.Sprintf("Type %s can't be used by the generated code.", t))
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		elem, err := f.typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		switch t.Kind() {
+		case reflect.Ptr:
+			return "*" + elem, nil
+		case reflect.Slice:
+			return "[]" + elem, nil
+		}
+		return fmt.Sprintf("[%d]%s", t.Len(), elem), nil
+	case reflect.Map:
+		key, err := f.typeName(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := f.typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("map[%s]%s", key, elem), nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}", nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("Type %s can't be used by the generated code.", t))
+}
+
+func isExported(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// genValue is a value computed by the generated code.
+type genValue struct {
+	code   string       // Go expression
+	t      reflect.Type // type of the value; nil if it's only known at runtime (code is an interface)
+	lit    interface{}  // value of a literal like "abc" or 42 (code is an untyped constant)
+	nonnil bool         // a pointer which is never nil
+	ptr    bool         // a pointer standing for the value it points to (see resolvePointer)
+	found  string       // bool variable which is false if the value is "" since it wasn't found (see genIdent)
+
+	block     bool     // the variable 'block' (see renderBlock); code is block.super
+	loops     []string // for-contexts of the variable 'forloops'
+	ambiguous string   // set if the variable's value depends on the execution (error message)
+}
+
+func (v *genValue) equals(o *genValue) bool {
+	if len(v.loops) != len(o.loops) {
+		return false
+	}
+	for i := range v.loops {
+		if v.loops[i] != o.loops[i] {
+			return false
+		}
+	}
+	return v.code == o.code && v.t == o.t && v.lit == o.lit && v.nonnil == o.nonnil && v.ptr == o.ptr && v.found == o.found && v.block == o.block && v.ambiguous == o.ambiguous
+}
+
+// genVars are the variables of the Context which are set by tags (like the
+// variables of a for-loop), see generator.
+type genVars map[string]*genValue
+
+func (vars genVars) copy() genVars {
+	c := make(genVars, len(vars))
+	for name, v := range vars {
+		c[name] = v
+	}
+	return c
+}
+
+func (vars genVars) equals(o genVars) bool {
+	if len(vars) != len(o) {
+		return false
+	}
+	for name, v := range vars {
+		if ov, has_var := o[name]; !has_var || !v.equals(ov) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeVars returns the variables after executing one of two alternatives;
+// variables which differ are ambiguous.
+func mergeVars(a, b genVars, where string) genVars {
+	merged := a.copy()
+	for name, v := range b {
+		if av, has_var := a[name]; !has_var || !av.equals(v) {
+			merged[name] = ambiguousVar(name, av, v, where)
+		}
+	}
+	for name, v := range a {
+		if _, has_var := b[name]; !has_var {
+			merged[name] = ambiguousVar(name, v, nil, where)
+		}
+	}
+	return merged
+}
+
+func ambiguousVar(name string, a, b *genValue, where string) *genValue {
+	v := &genValue{ambiguous: fmt.Sprintf("The value of '%s' depends on the execution of %s (not supported by the code generator).", name, where)}
+
+	// forloops is either unset or contains only forloop; both result in the
+	// same forloops of a nested loop (see forArgs.loop)
+	if name != "forloops" {
+		return v
+	}
+	for _, other := range []*genValue{a, b} {
+		if other == nil {
+			continue
+		}
+		if len(other.loops) != 1 || (v.loops != nil && v.loops[0] != other.loops[0]) {
+			v.loops = nil
+			return v
+		}
+		v.loops = other.loops
+	}
+	return v
+}
+
+// generator generates the code of a single render function. It walks the
+// templates like the interpreter executes them; the variables set by tags are
+// tracked in vars (like the interpreter modifies the Context).
+type generator struct {
+	file   *genFile
+	shape  *contextShape
+	limits Limits
+	code   *bytes.Buffer
+	n_vars int
+
+	tpl     *Template // template whose nodes are generated
+	pos     int
+	depth   int // nesting level of bodies (see compiler)
+	loading int // number of templates extending/including the current one
+	blocks  blockOverrides
+
+	vars genVars
+	root bool // whether the fields and methods of data are variables
+
+	uses_output, uses_iterations, uses_loops bool
+	returned                                 bool // whether the code of the current node ends with a return
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.code, format, args...)
+}
+
+// newVar returns the name of a new temporary variable.
+func (g *generator) newVar(prefix string) string {
+	g.n_vars++
+	return fmt.Sprintf("%s%d", prefix, g.n_vars)
+}
+
+// pkg returns the qualifier of a standard package (or pongo).
+func (g *generator) pkg(pkg_path string) string {
+	if pkg_path == pongoPath {
+		return g.file.qualifier(pkg_path, "pongo")
+	}
+	return g.file.qualifier(pkg_path, path.Base(pkg_path))
+}
+
+func (g *generator) nodeError(n node, msg string) error {
+	return errors.New(fmt.Sprintf("[Generation error: %s] [Line %d, Column %d] %s", g.tpl.name, n.getLine(), n.getCol(), msg))
+}
+
+// genFunc generates the render function name of tpl.
+func (g *generator) genFunc(name string, tpl *Template, code *bytes.Buffer) error {
+	switch {
+	case tpl.shape == nil || tpl.shape.fields == nil:
+		return errors.New(fmt.Sprintf("[Generation error: %s] The template must be bound to a struct type (see Bind).", tpl.name))
+	case tpl.sandbox != nil:
+		return errors.New(fmt.Sprintf("[Generation error: %s] Sandboxed templates are not supported by the code generator.", tpl.name))
+	case tpl.locale != "":
+		return errors.New(fmt.Sprintf("[Generation error: %s] Templates with a locale are not supported by the code generator.", tpl.name))
+	}
+	data_type, err := g.file.typeName(tpl.shape.root)
+	if err != nil {
+		return errors.New(fmt.Sprintf("[Generation error: %s] %s", tpl.name, err))
+	}
+	g.shape, g.limits, g.root = tpl.shape, tpl.limits, true
+
+	if err := g.genTemplate(tpl); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(code, "\n// %s renders the template '%s' (see pongo.Template.ExecuteContext).\n", name, tpl.name)
+	fmt.Fprintf(code, "func %s(go_ctx %sContext, data %s) (*string, error) {\n", name, g.pkg("context"), data_type)
+	fmt.Fprintf(code, "if go_ctx == nil {\ngo_ctx = %sBackground()\n}\n", g.pkg("context"))
+	if tpl.shape.root.Kind() == reflect.Ptr {
+		fmt.Fprintf(code, "if data == nil {\nreturn nil, %sNew(%s)\n}\n", g.pkg("errors"), strconv.Quote(fmt.Sprintf("[Error: %s] data must not be nil", tpl.name)))
+	}
+	if g.limits.Timeout > 0 {
+		fmt.Fprintf(code, "deadline := %sNow().Add(%d)\n", g.pkg("time"), g.limits.Timeout)
+	}
+	fmt.Fprintf(code, "check := func() error {\nif err := go_ctx.Err(); err != nil {\nreturn err\n}\n")
+	if g.limits.Timeout > 0 {
+		fmt.Fprintf(code, "if %[1]sNow().After(deadline) {\nreturn &%[2]sLimitError{Limit: \"Timeout\", Max: %[1]sDuration(%[3]d)}\n}\n", g.pkg("time"), g.pkg(pongoPath), g.limits.Timeout)
+	}
+	fmt.Fprintf(code, "return nil\n}\n")
+	if g.uses_output {
+		fmt.Fprintf(code, "output := 0\n")
+	}
+	if g.uses_iterations {
+		fmt.Fprintf(code, "iterations := 0\n")
+	}
+	if g.uses_loops {
+		fmt.Fprintf(code, "type forContext struct {\nCounter, Counter1, Max, Max1 int\nFirst, Last bool\n}\n")
+	}
+	fmt.Fprintf(code, "out := &%sBuilder{}\n", g.pkg("strings"))
+	fmt.Fprintf(code, "if err := func() error {\n%sreturn nil\n}(); err != nil {\nreturn nil, err\n}\n", g.code.Bytes())
+	fmt.Fprintf(code, "result := out.String()\nreturn &result, nil\n}\n")
+	return nil
+}
+
+// genTemplate generates the code of all nodes of tpl.
+func (g *generator) genTemplate(tpl *Template) error {
+	outer_tpl, outer_pos, outer_depth := g.tpl, g.pos, g.depth
+	g.tpl, g.pos, g.depth = tpl, 0, 0
+	defer func() {
+		g.tpl, g.pos, g.depth = outer_tpl, outer_pos, outer_depth
+	}()
+
+	for ; g.pos < len(tpl.nodes); g.pos++ {
+		stop, err := g.genNode()
+		if err != nil {
+			return err
+		}
+		if stop {
+			break
+		}
+	}
+	return nil
+}
+
+// genUntilAnyTagNode generates the code of all nodes following the current one
+// until one of the given tags is reached.
+func (g *generator) genUntilAnyTagNode(nodenames ...string) (*tagNode, error) {
+	start := g.tpl.nodes[g.pos]
+
+	g.depth++
+	defer func() { g.depth-- }()
+
+	for g.pos++; g.pos < len(g.tpl.nodes); g.pos++ {
+		if tn, is_tag := g.tpl.nodes[g.pos].(*tagNode); is_tag {
+			for _, name := range nodenames {
+				if tn.tagname == name {
+					return tn, nil
+				}
+			}
+		}
+		stop, err := g.genNode()
+		if err != nil {
+			return nil, err
+		}
+		if stop {
+			return nil, g.nodeError(g.tpl.nodes[g.pos], "Extends must not be nested in other tags")
+		}
+	}
+
+	return nil, g.nodeError(start, fmt.Sprintf("No end-node (possible nodes: %v) found.", nodenames))
+}
+
+// genNode generates the code of the current node; stop is true if the
+// remaining nodes must not be rendered (after an extends-tag).
+func (g *generator) genNode() (stop bool, err error) {
+	n := g.tpl.nodes[g.pos]
+
+	g.printf("if err := func() error {\nif err := check(); err != nil {\nreturn err\n}\n")
+	switch n := n.(type) {
+	case *contentNode:
+		g.printf("out.WriteString(%s)\n", strconv.Quote(n.content))
+		g.genWritten(strconv.Itoa(len(n.content)))
+	case *filterNode:
+		err = g.genOutput(n)
+	case *tagNode:
+		if n.taghandler == nil || n.taghandler != Tags[n.tagname] {
+			return false, g.nodeError(n, fmt.Sprintf("Tag '%s' is not supported by the code generator", n.tagname))
+		}
+		switch n.tagname {
+		case "if":
+			err = g.genIf(n)
+		case "for":
+			err = g.genFor(n)
+		case "trim", "remove":
+			err = g.genTrimRemove(n)
+		case "block":
+			err = g.genBlock(n)
+		case "extends":
+			err = g.genExtends(n)
+			stop = true
+		case "include":
+			err = g.genInclude(n)
+		default:
+			err = g.nodeError(n, fmt.Sprintf("Tag '%s' is not supported by the code generator", n.tagname))
+		}
+	}
+	if err != nil {
+		if !strings.HasPrefix(err.Error(), "[Generation error:") {
+			err = g.nodeError(n, err.Error())
+		}
+		return false, err
+	}
+
+	// Add position information to errors the same way the interpreter does
+	format := "[Error: %s] [Line %d Col %d (%s)] %w"
+	if g.depth > 0 {
+		format = "[Error in block-execution: %s] [Line %d Col %d (%s)] %w"
+	}
+	if !g.returned {
+		g.printf("return nil\n")
+	}
+	g.returned = false
+	g.printf("}(); err != nil {\nreturn %sErrorf(%s, %s, %d, %d, %s, err)\n}\n",
+		g.pkg("fmt"), strconv.Quote(format), strconv.Quote(g.tpl.name), n.getLine(), n.getCol(), strconv.Quote(*n.getContent()))
+
+	return stop, nil
+}
+
+// genWritten records length bytes of output (see Limits.MaxOutput).
+func (g *generator) genWritten(length string) {
+	if g.limits.MaxOutput <= 0 {
+		return
+	}
+	g.uses_output = true
+	g.printf("output += %s\nif output > %d {\nreturn &%sLimitError{Limit: \"MaxOutput\", Max: %d}\n}\n", length, g.limits.MaxOutput, g.pkg(pongoPath), g.limits.MaxOutput)
+}
+
+func (g *generator) genOutput(fn *filterNode) error {
+	v, err := g.genExpr(fn.e)
+	if err != nil {
+		return err
+	}
+	str, err := g.stringOf(v)
+	if err != nil {
+		return err
+	}
+	if g.limits.MaxOutput <= 0 {
+		g.printf("out.WriteString(%s)\n", str)
+		return nil
+	}
+	s := g.newVar("str")
+	g.printf("%s := %s\nout.WriteString(%s)\n", s, str, s)
+	g.genWritten(fmt.Sprintf("len(%s)", s))
+	return nil
+}
+
+func (g *generator) genIf(tn *tagNode) error {
+	args := strings.TrimSpace(tn.tagargs)
+	if len(args) == 0 {
+		return g.nodeError(tn, "If-argument is empty.")
+	}
+	cond, err := parseCondition(args)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+	v, err := g.genCondition(cond)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+	is_true, err := g.isZero(v, true)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+
+	before := g.vars.copy()
+	g.printf("if %s {\n", is_true)
+	end, err := g.genUntilAnyTagNode("else", "endif")
+	if err != nil {
+		return err
+	}
+	then_vars := g.vars
+	g.vars = before
+	if end.tagname == "else" {
+		g.printf("} else {\n")
+		if _, err := g.genUntilAnyTagNode("endif"); err != nil {
+			return err
+		}
+	}
+	g.printf("}\n")
+	g.vars = mergeVars(then_vars, g.vars, fmt.Sprintf("the if-tag in line %d", tn.line))
+	return nil
+}
+
+func (g *generator) genFor(tn *tagNode) error {
+	fa, err := parseForArgs(tn.tagargs)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+	v, err := g.genExpr(fa.e)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+	if v.found != "" && !v.ptr && v.t != nil && v.t.Kind() != reflect.Int {
+		// Like "", the zero value of a type which can be iterated is empty
+		v = &genValue{code: v.code, t: v.t}
+	}
+	v = g.resolve(v)
+
+	n, loop, idx := g.newVar("n"), g.newVar("loop"), g.newVar("i")
+	var items, keys, key_type string
+	var item *genValue
+	if fa.has_in {
+		if v.t == nil {
+			return g.nodeError(tn, fmt.Sprintf("'%s' is only known at runtime, the code generator can't iterate over it.", fa.e.raw))
+		}
+		items = g.newVar("items")
+		g.printf("%s := %s\n%s := len(%s)\n", items, v.code, n, items)
+		switch v.t.Kind() {
+		case reflect.Slice, reflect.Array:
+			item = &genValue{code: fmt.Sprintf("%s[%s]", items, idx), t: v.t.Elem()}
+		case reflect.String:
+			item = &genValue{code: fmt.Sprintf("string(%s)[%s:%s+1]", items, idx, idx), t: stringType}
+		case reflect.Map:
+			keys = g.newVar("keys")
+			if key_type, err = g.file.typeName(v.t.Key()); err != nil {
+				return g.nodeError(tn, err.Error())
+			}
+			value_type, err := g.file.typeName(v.t.Elem())
+			if err != nil {
+				return g.nodeError(tn, err.Error())
+			}
+			item = &genValue{
+				code: fmt.Sprintf("struct {\nKey %s\nValue %s\n}{%s[%s], %s[%s[%s]]}", key_type, value_type, keys, idx, items, keys, idx),
+				t: reflect.StructOf([]reflect.StructField{
+					{Name: "Key", Type: v.t.Key()},
+					{Name: "Value", Type: v.t.Elem()},
+				}),
+			}
+		default:
+			return g.nodeError(tn, "For-loop 'in'-operator can onl be used for slices/arrays/strings/maps.")
+		}
+	} else {
+		if v.t != intType {
+			return g.nodeError(tn, fmt.Sprintf("For-loop error: Cannot iterate over '%v'.", fa.raw))
+		}
+		g.printf("%s := %s\n", n, v.code)
+	}
+	g.uses_loops = true
+
+	// Variables of the loop (see forArgs.loop); forloops is set once
+	before := g.vars.copy()
+	loops_var, has_loops := before["forloops"]
+	loop_var, has_loop := before["forloop"]
+	var loops, after_loops *genValue
+	switch {
+	case has_loops && loops_var.ambiguous == "":
+		loops = &genValue{loops: append(loops_var.loops[:len(loops_var.loops):len(loops_var.loops)], loop)}
+		after_loops = loops_var
+	case has_loops && len(loops_var.loops) == 1 && has_loop && loop_var.ambiguous == "" && loop_var.code == loops_var.loops[0],
+		!has_loops && has_loop && loop_var.ambiguous == "":
+		loops = &genValue{loops: []string{loop_var.code, loop}}
+		after_loops = &genValue{loops: []string{loop_var.code}}
+	case has_loops:
+		loops, after_loops = loops_var, loops_var
+	case has_loop:
+		loops = &genValue{ambiguous: loop_var.ambiguous}
+		after_loops = loops
+	}
+	for _, l := range []*genValue{loops, after_loops} {
+		if l != nil && l.ambiguous == "" {
+			l.code = fmt.Sprintf("[]*forContext{%s}", strings.Join(l.loops, ", "))
+			l.t = forContextsType
+		}
+	}
+	iteration := func(vars genVars) {
+		if fa.has_in {
+			vars[fa.varname] = item
+		}
+		vars["forloop"] = &genValue{code: loop, t: forContextType, nonnil: true}
+		vars["forcounter"] = &genValue{code: idx, t: intType}
+		vars["forcounter1"] = &genValue{code: fmt.Sprintf("(%s + 1)", idx), t: intType}
+	}
+
+	// The body is generated again if it changes the variables of the
+	// next iteration
+	outer_code, starter_pos := g.code, g.pos
+	start := before.copy()
+	if loops != nil {
+		start["forloops"] = loops
+	}
+	iteration(start)
+	var end *tagNode
+	for {
+		g.code, g.pos, g.vars = &bytes.Buffer{}, starter_pos, start.copy()
+		if end, err = g.genUntilAnyTagNode("else", "endfor"); err != nil {
+			g.code = outer_code
+			return err
+		}
+		next := g.vars.copy()
+		iteration(next)
+		merged := mergeVars(start, next, fmt.Sprintf("the for-loop in line %d", tn.line))
+		if merged.equals(start) {
+			break
+		}
+		start = merged
+	}
+	body := g.code
+	after := g.vars
+	if fa.has_in {
+		delete(after, fa.varname)
+	}
+	delete(after, "forloop")
+	delete(after, "forcounter")
+	delete(after, "forcounter1")
+	if after_loops != nil {
+		after["forloops"] = after_loops
+	}
+
+	g.code, g.vars = outer_code, before
+	g.printf("if %s <= 0 {\n", n)
+	if end.tagname == "else" {
+		if _, err := g.genUntilAnyTagNode("endfor"); err != nil {
+			return err
+		}
+	}
+	g.vars = mergeVars(after, g.vars, fmt.Sprintf("the for-loop in line %d", tn.line))
+
+	g.printf("} else {\n")
+	if keys != "" {
+		g.printf("%[1]s := make([]%[2]s, 0, %[3]s)\nfor key := range %[4]s {\n%[1]s = append(%[1]s, key)\n}\n", keys, key_type, n, items)
+	}
+	g.printf("%s := &forContext{Max: %s - 1, Max1: %s, Counter1: 1, First: true}\n", loop, n, n)
+	g.printf("for %[1]s := 0; %[1]s < %[2]s; %[1]s++ {\n", idx, n)
+	g.printf("if %[1]s == 1 {\n%[2]s.First = false\n}\nif %[1]s == %[3]s-1 {\n%[2]s.Last = true\n}\n", idx, loop, n)
+	if g.limits.MaxIterations > 0 {
+		g.uses_iterations = true
+		g.printf("iterations++\nif iterations > %d {\nreturn &%sLimitError{Limit: \"MaxIterations\", Max: %d}\n}\n", g.limits.MaxIterations, g.pkg(pongoPath), g.limits.MaxIterations)
+	}
+	g.printf("if err := check(); err != nil {\nreturn err\n}\n")
+	g.code.Write(body.Bytes())
+	g.printf("%[1]s.Counter++\n%[1]s.Counter1++\n}\n}\n", loop)
+	return nil
+}
+
+func (g *generator) genTrimRemove(tn *tagNode) error {
+	var patterns []*expr
+	if tn.tagname == "remove" {
+		var err error
+		if patterns, err = parseRemovePatterns(tn.tagargs); err != nil {
+			return g.nodeError(tn, err.Error())
+		}
+	}
+
+	body := g.newVar("body")
+	g.printf("%s := &%sBuilder{}\nif err := func(out *%sBuilder) error {\n", body, g.pkg("strings"), g.pkg("strings"))
+	if _, err := g.genUntilAnyTagNode("end" + tn.tagname); err != nil {
+		return err
+	}
+	g.printf("return nil\n}(%s); err != nil {\nreturn err\n}\n", body)
+
+	if tn.tagname == "trim" {
+		g.printf("out.WriteString(%sTrimSpace(%s.String()))\n", g.pkg("strings"), body)
+		return nil
+	}
+	str := g.newVar("str")
+	g.printf("%s := %s.String()\n", str, body)
+	for _, e := range patterns {
+		v, err := g.genExpr(e)
+		if err != nil {
+			return g.nodeError(tn, err.Error())
+		}
+		pattern, err := g.stringOf(v)
+		if err != nil {
+			return g.nodeError(tn, err.Error())
+		}
+		g.printf("%s = %sReplace(%s, %s, \"\", -1)\n", str, g.pkg("strings"), str, pattern)
+	}
+	g.printf("out.WriteString(%s)\n", str)
+	return nil
+}
+
+func (g *generator) genBlock(tn *tagNode) error {
+	bi, has_block := g.tpl.blocks[tn.tagargs]
+	if !has_block {
+		return g.nodeError(tn, fmt.Sprintf("Block '%s' is not indexed. Please report this issue.", tn.tagargs))
+	}
+
+	if err := g.genBlockDef(g.blocks.chain(g.tpl, bi.name), 0); err != nil {
+		return err
+	}
+
+	// Skip the default content of this block
+	g.pos = bi.end
+	return nil
+}
+
+// genBlockDef generates the code rendering chain[idx] (like renderBlock).
+func (g *generator) genBlockDef(chain []*blockDef, idx int) error {
+	def := chain[idx]
+
+	super := `""`
+	if def.info.uses_super && idx+1 < len(chain) {
+		super = g.newVar("super")
+		g.printf("%s := &%sBuilder{}\nif err := func(out *%sBuilder) error {\n", super, g.pkg("strings"), g.pkg("strings"))
+		if err := g.genBlockDef(chain, idx+1); err != nil {
+			return err
+		}
+		g.printf("return nil\n}(%s); err != nil {\nreturn err\n}\n", super)
+		super += ".String()"
+	}
+
+	// Provide {{ block.super }} and restore the outer block afterwards
+	outer_tpl, outer_pos := g.tpl, g.pos
+	outer_block, has_outer_block := g.vars["block"]
+	g.tpl, g.pos = def.template, def.info.start
+	g.vars["block"] = &genValue{code: super, block: true}
+	defer func() {
+		g.tpl, g.pos = outer_tpl, outer_pos
+		if has_outer_block {
+			g.vars["block"] = outer_block
+		} else {
+			delete(g.vars, "block")
+		}
+	}()
+
+	_, err := g.genUntilAnyTagNode("endblock")
+	return err
+}
+
+// dependency returns the template referenced by a constant name.
+func (g *generator) dependency(tn *tagNode, e *expr) (*Template, bool, error) {
+	if !e.isConstant() {
+		return nil, false, g.nodeError(tn, "Only templates with a constant name can be extended or included by generated code")
+	}
+	name, err := e.evalString(&Context{})
+	if err != nil {
+		return nil, false, g.nodeError(tn, err.Error())
+	}
+	dep_tpl, has_dep := g.tpl.dep_templates[*name]
+	if has_dep && dep_tpl == nil {
+		return nil, false, g.nodeError(tn, fmt.Sprintf("Recursive template '%s' is not supported by the code generator", *name))
+	}
+	return dep_tpl, has_dep, nil
+}
+
+// genMaxDepth generates the error of exceeding the maximum template depth
+// (see executionContext.checkDepth) and returns true if it's exceeded by
+// extending/including a template.
+func (g *generator) genMaxDepth() bool {
+	max := MaxTemplateDepth
+	if g.limits.MaxDepth > 0 {
+		max = g.limits.MaxDepth
+	}
+	if g.loading+1 <= max {
+		return false
+	}
+	g.printf("return &%sLimitError{Limit: \"MaxDepth\", Max: %d}\n", g.pkg(pongoPath), max)
+	g.returned = true
+	return true
+}
+
+func (g *generator) genExtends(tn *tagNode) error {
+	if g.depth > 0 {
+		return g.nodeError(tn, "Extends must not be nested in other tags")
+	}
+	e, _, err := parseExtendsArgs(tn.tagargs)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+	base_tpl, has_base, err := g.dependency(tn, e)
+	if err != nil {
+		return err
+	}
+	if !has_base {
+		return g.nodeError(tn, "Base template was not loaded. Please report this issue.")
+	}
+	if g.genMaxDepth() {
+		return nil
+	}
+
+	g.blocks.register(g.tpl)
+
+	g.loading++
+	defer func() { g.loading-- }()
+	return g.genTemplate(base_tpl)
+}
+
+func (g *generator) genInclude(tn *tagNode) error {
+	ia, err := parseIncludeArgs(tn.tagargs)
+	if err != nil {
+		return g.nodeError(tn, err.Error())
+	}
+	dep_tpl, has_dep, err := g.dependency(tn, ia.name)
+	if err != nil {
+		return err
+	}
+	if !has_dep {
+		// Missing template, ignored during parsing
+		return nil
+	}
+	if g.genMaxDepth() {
+		return nil
+	}
+
+	// See includeArgs.includeContext
+	vars := make(genVars, len(ia.with_keys))
+	if !ia.only {
+		vars = g.vars.copy()
+	}
+	for idx, key := range ia.with_keys {
+		v, err := g.genExpr(ia.with_exprs[idx])
+		if err != nil {
+			return g.nodeError(tn, err.Error())
+		}
+		w := g.newVar("w")
+		g.printf("%s := %s\n_ = %s\n", w, v.code, w)
+		vars[key] = &genValue{code: w, t: v.t, nonnil: v.nonnil, ptr: v.ptr, found: v.found}
+	}
+
+	// An included template has its own blocks
+	outer_vars, outer_root, outer_blocks := g.vars, g.root, g.blocks
+	g.vars, g.root, g.blocks = vars, g.root && !ia.only, make(blockOverrides)
+	g.loading++
+	err = g.genTemplate(dep_tpl)
+	g.loading--
+	g.vars, g.root, g.blocks = outer_vars, outer_root, outer_blocks
+	return err
+}
+
+// literalValue returns the value of a literal of an expression.
+func literalValue(lit interface{}) *genValue {
+	return &genValue{code: goLiteral(reflect.ValueOf(lit)), t: reflect.TypeOf(lit), lit: lit}
+}
+
+// goLiteral returns an untyped constant of the value of rv (a bool, a number
+// or a string).
+func goLiteral(rv reflect.Value) string {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		// The constant must not become an int
+		str := strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+		if !strings.ContainsAny(str, ".e") {
+			str += ".0"
+		}
+		return str
+	}
+	return strconv.Quote(rv.String())
+}
+
+// genExpr generates the evaluation of e (like evalValue).
+func (g *generator) genExpr(e *expr) (*genValue, error) {
+	var v *genValue
+	if name, is_ident := e.root.(exprIdent); is_ident {
+		var err error
+		v, err = g.genIdent(name, e.ident, e.root_args, true)
+		if err != nil {
+			return nil, err
+		}
+		if len(e.filters) > 0 || e.negate {
+			v = g.resolve(v)
+		}
+	} else {
+		v = literalValue(e.root)
+	}
+
+	v, err := g.genFilters(v, e.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.negate {
+		is_zero, err := g.isZero(v, false)
+		if err != nil {
+			return nil, err
+		}
+		v = &genValue{code: is_zero, t: boolType}
+	}
+	return v, nil
+}
+
+// materialize assigns the value to a variable (unless it's one already), so
+// it's evaluated only once.
+func (g *generator) materialize(v *genValue) *genValue {
+	if exprIdentPartChecker.MatchString(v.code) || v.lit != nil {
+		return v
+	}
+	name := g.newVar("v")
+	g.printf("%s := %s\n", name, v.code)
+	m := *v
+	m.code = name
+	return &m
+}
+
+// resolve dereferences a pointer standing for its value (see genValue.ptr);
+// the value is only known at runtime then.
+func (g *generator) resolve(v *genValue) *genValue {
+	if !v.ptr && v.found == "" {
+		return v
+	}
+	r := g.newVar("v")
+	switch {
+	case v.found != "" && v.ptr:
+		g.printf("var %[1]s interface{} = \"\"\nif %[2]s {\n%[1]s = %[3]s\nif %[3]s != nil {\n%[1]s = *%[3]s\n}\n}\n", r, v.found, v.code)
+	case v.found != "":
+		g.printf("var %[1]s interface{} = \"\"\nif %[2]s {\n%[1]s = %[3]s\n}\n", r, v.found, v.code)
+	default:
+		v = g.materialize(v)
+		g.printf("var %[1]s interface{} = %[2]s\nif %[2]s != nil {\n%[1]s = *%[2]s\n}\n", r, v.code)
+	}
+	return &genValue{code: r}
+}
+
+// staticType returns nil for interfaces (their values are only known at runtime).
+func staticType(t reflect.Type) reflect.Type {
+	if t != nil && t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
+
+// genIdent generates the lookup of an identifier like resolveIdentParts. If
+// eval is true, a method at the end of the chain is called with root_args
+// (like evalValue does). Lookups which might not find a value (like on a nil
+// pointer) are generated as nested if-statements; the value is "" then.
+func (g *generator) genIdent(name exprIdent, parts []identPart, root_args []reflect.Value, eval bool) (*genValue, error) {
+	outer_code := g.code
+	g.code = &bytes.Buffer{}
+	defer func() { g.code = outer_code }()
+
+	guards := 0
+	guard := func(cond string) {
+		g.printf("if %s {\n", cond)
+		guards++
+	}
+
+	// Arguments of a method called at the end of the chain
+	var end_args []*expr
+	if eval {
+		for _, arg := range root_args {
+			e := &expr{root: arg.Interface()}
+			if ident, is_ident := e.root.(exprIdent); is_ident {
+				var err error
+				if e.ident, err = splitIdent(ident); err != nil {
+					return nil, err
+				}
+			}
+			end_args = append(end_args, e)
+		}
+	}
+	method_ref := func(name string) error {
+		if !eval {
+			return errors.New(fmt.Sprintf("Method '%s' can't be referenced without calling it by the code generator", name))
+		}
+		return nil
+	}
+
+	root := parts[0]
+	parts = parts[1:]
+	called := false // whether the value is the result of a method called with end_args
+	var cur *genValue
+
+	if v, has_var := g.vars[root.name]; has_var {
+		if v.ambiguous != "" {
+			return nil, errors.New(v.ambiguous)
+		}
+		if v.block {
+			if len(parts) != 1 || parts[0].name != "super" || parts[0].is_call || parts[0].is_subscript || root.is_call {
+				return nil, errors.New("Only block.super is supported by the code generator")
+			}
+			return &genValue{code: v.code, t: renderedBlockType}, nil
+		}
+		if root.is_call {
+			return nil, errors.New(fmt.Sprintf("Calling the variable '%s' is not supported by the code generator", root.name))
+		}
+		cur = v
+		if cur.found != "" && len(parts) > 0 && !cur.ptr {
+			guard(cur.found)
+		}
+	} else if ft, is_method := g.rootMethod(root.name); is_method {
+		// Method of data (see lookupRoot)
+		args := root.args
+		if !root.is_call {
+			args = nil
+			if len(parts) == 0 {
+				if err := method_ref(root.name); err != nil {
+					return nil, err
+				}
+				args, called = end_args, true
+			}
+		}
+		var err error
+		if cur, err = g.genCall("data."+root.name, root.name, ft, args); err != nil {
+			return nil, err
+		}
+	} else if index, has_field := g.rootField(root.name); has_field {
+		// data is never nil (see genFunc)
+		data := &genValue{code: "data", t: g.shape.root, nonnil: true}
+		var err error
+		if cur, err = g.genField(data, index, guard); err != nil {
+			return nil, err
+		}
+		if root.is_call {
+			if cur.t.Kind() != reflect.Func {
+				return nil, errors.New(fmt.Sprintf("'%s' (%s) is not a function and can't be called", root.name, cur.t))
+			}
+			cur = g.materialize(cur)
+			g.printf("if %s == nil {\nreturn %sNew(%s)\n}\n", cur.code, g.pkg("errors"), strconv.Quote(fmt.Sprintf("'%s' (%s) is not a function and can't be called", root.name, cur.t)))
+			if cur, err = g.genCall(cur.code, root.name, cur.t, root.args); err != nil {
+				return nil, err
+			}
+		}
+	} else if root.name == "_" {
+		return nil, errors.New("Translations are not supported by the code generator")
+	} else {
+		return nil, errors.New(fmt.Sprintf("Variable '%s' is not part of the Context.", root.name))
+	}
+
+	for idx, part := range parts {
+		if cur.t == nil {
+			return nil, errors.New(fmt.Sprintf("The value of '%s' is only known at runtime, the code generator can't look up '%s' on it.", name, part.name))
+		}
+		if cur.t.Kind() == reflect.Ptr && !cur.nonnil {
+			// Nothing can be looked up on a nil pointer
+			cur = g.materialize(cur)
+			guard(cur.code + " != nil")
+		}
+
+		var err error
+		switch {
+		case part.is_subscript:
+			var key *genValue
+			if key, err = g.genArith(part.subscript); err == nil {
+				cur, err = g.genIndex(cur, key, guard)
+			}
+		case part.is_call:
+			cur, err = g.genCallable(cur, part, guard)
+		case part.specifier == nil:
+			err = errors.New(fmt.Sprintf("Specifier '%s' is not valid.", part.name))
+		default:
+			if attr, is_ident := part.specifier.(exprIdent); is_ident {
+				if ft, has_method := methodType(cur.t, string(attr)); has_method {
+					// Methods are called without arguments unless they are at
+					// the end of the chain
+					var args []*expr
+					if idx == len(parts)-1 {
+						if err := method_ref(string(attr)); err != nil {
+							return nil, err
+						}
+						args, called = end_args, true
+					}
+					cur, err = g.genCall(cur.code+"."+string(attr), string(attr), ft, args)
+					break
+				}
+			}
+			cur, err = g.genSpecifier(cur, part, guard)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The value is dereferenced (see resolvePointer) unless a method was
+	// called with the arguments of the expression
+	switch {
+	case called:
+	case cur.t == nil:
+		v := g.newVar("v")
+		g.printf("var %s interface{} = %s\n", v, cur.code)
+		g.printf("if rv := %[1]sValueOf(%[2]s); rv.Kind() == %[1]sPtr && !rv.IsNil() && rv.Elem().CanInterface() {\n%[2]s = rv.Elem().Interface()\n}\n", g.pkg("reflect"), v)
+		cur = &genValue{code: v}
+	case cur.t.Kind() == reflect.Ptr && cur.nonnil:
+		cur = &genValue{code: "*" + cur.code, t: cur.t.Elem()}
+	case cur.t.Kind() == reflect.Ptr:
+		// The pointer is kept, so its type is still known (see resolve)
+		cur = &genValue{code: cur.code, t: cur.t, ptr: true}
+	}
+
+	chain := g.code
+	g.code = outer_code
+	if guards == 0 {
+		g.code.Write(chain.Bytes())
+		return cur, nil
+	}
+
+	// The value is "" if it wasn't found
+	v := &genValue{code: g.newVar("v"), t: cur.t, ptr: cur.ptr}
+	switch {
+	case cur.t == stringType:
+		g.printf("var %s string\n", v.code)
+	case cur.t == nil:
+		g.printf("var %s interface{} = \"\"\n", v.code)
+	default:
+		t, err := g.file.typeName(cur.t)
+		if err != nil {
+			return nil, err
+		}
+		v.found = g.newVar("ok")
+		g.printf("var %s %s\n%s := false\n_ = %s\n", v.code, t, v.found, v.found)
+	}
+	g.code.Write(chain.Bytes())
+	if v.found != "" {
+		g.printf("%s, %s = %s, true\n", v.code, v.found, cur.code)
+	} else {
+		g.printf("%s = %s\n", v.code, cur.code)
+	}
+	g.printf("%s", strings.Repeat("}\n", guards))
+	return v, nil
+}
+
+// rootMethod returns the type of the method name of data unless a variable
+// shadows it.
+func (g *generator) rootMethod(name string) (reflect.Type, bool) {
+	if !g.root {
+		return nil, false
+	}
+	return g.shape.method(name)
+}
+
+// rootField returns the index of the field name of data.
+func (g *generator) rootField(name string) ([]int, bool) {
+	if !g.root {
+		return nil, false
+	}
+	return fieldIndex(g.shape.fields, name)
+}
+
+// genField generates the access of the field with the given index of the
+// struct v (or a pointer to it, which must not be nil).
+func (g *generator) genField(v *genValue, index []int, guard func(string)) (*genValue, error) {
+	code, t := v.code, v.t
+	for pos, i := range index {
+		if t.Kind() == reflect.Ptr {
+			if pos > 0 {
+				// Embedded pointer (see lookupField)
+				code = g.materialize(&genValue{code: code}).code
+				guard(code + " != nil")
+			}
+			t = t.Elem()
+		}
+		field := t.Field(i)
+		if !isExported(field.Name) && t.PkgPath() != g.file.pkg {
+			return nil, errors.New(fmt.Sprintf("Field %s of type %s can't be accessed by the generated code.", field.Name, t))
+		}
+		code += "." + field.Name
+		t = field.Type
+	}
+	return &genValue{code: code, t: staticType(t)}, nil
+}
+
+// genCall generates a call of the method (or function) fn of type ft with
+// the given arguments like callMethod.
+func (g *generator) genCall(fn string, name string, ft reflect.Type, arg_exprs []*expr) (*genValue, error) {
+	var args []string
+	params := 0
+	if ft.NumIn() > 0 && ft.In(0) == goContextType {
+		// The context.Context is passed implicitly (unless it's the first argument)
+		if len(arg_exprs) == 0 || !isGoContextExpr(arg_exprs[0]) {
+			args = append(args, "go_ctx")
+			params = 1
+		}
+	}
+
+	given := len(arg_exprs) + params
+	if ft.IsVariadic() {
+		if given < ft.NumIn()-1 {
+			return nil, errors.New(fmt.Sprintf("Method '%s' requires at least %d argument(s), %d given.", name, ft.NumIn()-1, given))
+		}
+	} else if given != ft.NumIn() {
+		return nil, errors.New(fmt.Sprintf("Method '%s' requires %d argument(s), %d given.", name, ft.NumIn(), given))
+	}
+
+	for idx, e := range arg_exprs {
+		if isGoContextExpr(e) && idx+params < ft.NumIn() && ft.In(idx+params) == goContextType {
+			args = append(args, "go_ctx")
+			continue
+		}
+		v, err := g.genExpr(e)
+		if err != nil {
+			return nil, err
+		}
+		v = g.resolve(v)
+		var t reflect.Type
+		if ft.IsVariadic() && idx+params >= ft.NumIn()-1 {
+			t = ft.In(ft.NumIn() - 1).Elem()
+		} else {
+			t = ft.In(idx + params)
+		}
+		arg, err := g.convert(v, t)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Argument %d of method '%s': %s", idx+params+1, name, err.Error()))
+		}
+		args = append(args, arg)
+	}
+
+	call := fmt.Sprintf("%s(%s)", fn, strings.Join(args, ", "))
+	switch {
+	case ft.NumOut() == 0:
+		g.printf("%s\n", call)
+		return literalValue(""), nil
+	case ft.NumOut() == 1:
+		v := g.newVar("v")
+		g.printf("%s := %s\n", v, call)
+		return &genValue{code: v, t: staticType(ft.Out(0))}, nil
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+		v := g.newVar("v")
+		g.printf("%s, err := %s\nif err != nil {\nreturn %sErrorf(%s, err)\n}\n", v, call, g.pkg("fmt"), strconv.Quote(fmt.Sprintf("Method '%s' returned an error: %%w", name)))
+		return &genValue{code: v, t: staticType(ft.Out(0))}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Method '%s' returns more than one value (only a single value or (value, error) are supported).", name))
+}
+
+// isGoContextExpr returns whether the argument e is a context.Context (which
+// isn't passed implicitly then).
+func isGoContextExpr(e *expr) bool {
+	return e.root == exprIdent(GoContextKey)
+}
+
+// convert converts the value v into a value of type t (like convertArgument).
+func (g *generator) convert(v *genValue, t reflect.Type) (string, error) {
+	if v.lit != nil {
+		rv, err := convertArgument(v.lit, t)
+		if err != nil {
+			return "", err
+		}
+		if t.Kind() == reflect.Interface {
+			return v.code, nil
+		}
+		return goLiteral(rv), nil
+	}
+
+	switch {
+	case v.t == nil:
+		if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+			return v.code, nil
+		}
+		return "", errors.New(fmt.Sprintf("a value only known at runtime can't be used as %s by the code generator", t))
+	case v.t.AssignableTo(t):
+		return v.code, nil
+	case v.t.Kind() == t.Kind() && v.t.ConvertibleTo(t):
+		// Named types of the same kind
+		name, err := g.file.typeName(t)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", name, v.code), nil
+	}
+
+	is_int := func(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Int64 }
+	is_float := func(k reflect.Kind) bool { return k == reflect.Float32 || k == reflect.Float64 }
+	from, to := v.t.Kind(), t.Kind()
+	switch {
+	case (is_int(from) && to == reflect.Int64) || ((is_int(from) || is_float(from)) && to == reflect.Float64):
+		// No information gets lost
+		name, err := g.file.typeName(t)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", name, v.code), nil
+	case to == reflect.String && t.Name() == "string":
+		switch {
+		case is_int(from):
+			return fmt.Sprintf("%sFormatInt(int64(%s), 10)", g.pkg("strconv"), v.code), nil
+		case is_float(from):
+			return fmt.Sprintf("%sFormatFloat(float64(%s), 'f', -1, 64)", g.pkg("strconv"), v.code), nil
+		case from == reflect.Bool:
+			return fmt.Sprintf("%sFormatBool(bool(%s))", g.pkg("strconv"), v.code), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("%s can't be used as %s by the code generator", v.t, t))
+}
+
+// genIndex generates the access of an element of v (not a nil pointer) by
+// the given key (like indexValue).
+func (g *generator) genIndex(v *genValue, key *genValue, guard func(string)) (*genValue, error) {
+	t, code := v.t, v.code
+	if t.Kind() == reflect.Ptr {
+		t, code = t.Elem(), "(*"+code+")"
+	}
+
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.String:
+		var idx string
+		switch {
+		case key.lit != nil:
+			rv, err := convertArgument(key.lit, intType)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Index %s can't be used for %s", key.code, t))
+			}
+			idx = goLiteral(rv)
+		case key.t != nil && key.t.Kind() >= reflect.Int && key.t.Kind() <= reflect.Uint64:
+			idx = fmt.Sprintf("int(%s)", key.code)
+		default:
+			return nil, errors.New(fmt.Sprintf("Only integers can be used as index of %s by the code generator", t))
+		}
+		code = g.materialize(&genValue{code: code}).code
+		i := idx
+		if n, is_int := key.lit.(int); is_int && n >= 0 && t.Kind() != reflect.Array {
+			guard(fmt.Sprintf("%s < len(%s)", i, code))
+		} else {
+			i = g.newVar("i")
+			g.printf("%[1]s := %[2]s\nif %[1]s < 0 {\n%[1]s += len(%[3]s)\n}\n", i, idx, code)
+			guard(fmt.Sprintf("%[1]s >= 0 && %[1]s < len(%[2]s)", i, code))
+		}
+		if t.Kind() == reflect.String {
+			return &genValue{code: fmt.Sprintf("string(%[1]s)[%[2]s:%[2]s+1]", code, i), t: stringType}, nil
+		}
+		return &genValue{code: fmt.Sprintf("%s[%s]", code, i), t: staticType(t.Elem())}, nil
+
+	case reflect.Map:
+		k, err := g.convert(key, t.Key())
+		if err != nil {
+			return nil, err
+		}
+		e, ok := g.newVar("e"), g.newVar("ok")
+		g.printf("%s, %s := %s[%s]\n", e, ok, code, k)
+		guard(ok)
+		return &genValue{code: e, t: staticType(t.Elem())}, nil
+
+	case reflect.Struct:
+		if name, is_str := key.lit.(string); is_str {
+			if index, has_field := fieldIndex(t, name); has_field {
+				return g.genField(v, index, guard)
+			}
+		}
+		return nil, errors.New(fmt.Sprintf("Struct %s can't be accessed by index %s.", t, key.code))
+	}
+	return nil, errors.New(fmt.Sprintf("Type %s can't be subscripted.", t))
+}
+
+// genSpecifier generates the access of v (not a nil pointer) by an index,
+// key or field name like user.Name or items.0.
+func (g *generator) genSpecifier(v *genValue, part identPart, guard func(string)) (*genValue, error) {
+	t := v.t
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	attr, is_ident := part.specifier.(exprIdent)
+
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.String:
+		if !is_ident {
+			return g.genIndex(v, literalValue(part.specifier), guard)
+		}
+		// The index is taken from the Context
+		idx, err := g.genIdent(attr, []identPart{{name: string(attr), specifier: attr}}, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		if idx.t != intType {
+			return nil, errors.New(fmt.Sprintf("Index '%s' of %s must be an integer.", attr, t))
+		}
+		return g.genIndex(v, idx, guard)
+
+	case reflect.Map:
+		var key interface{} = part.specifier
+		if is_ident {
+			key = string(attr)
+		}
+		k, err := g.convert(literalValue(key), t.Key())
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Key '%s' can't be used for %s.", part.name, t))
+		}
+		code := v.code
+		if v.t.Kind() == reflect.Ptr {
+			code = "(*" + code + ")"
+		}
+		e, ok := g.newVar("e"), g.newVar("ok")
+		g.printf("%s, %s := %s[%s]\n", e, ok, code, k)
+		if is_ident && t.Key().Kind() == reflect.String {
+			// Maybe the key is taken from the Context (see resolveIdentParts)
+			g.printf("if !%s {\n", ok)
+			fallback, err := g.genIdent(attr, []identPart{{name: string(attr), specifier: attr}}, nil, false)
+			if err != nil {
+				fallback = literalValue("")
+			}
+			if fallback.t == stringType {
+				name, err := g.file.typeName(t.Key())
+				if err != nil {
+					return nil, err
+				}
+				g.printf("%s, %s = %s[%s(%s)]\n", e, ok, code, name, fallback.code)
+			}
+			g.printf("}\n")
+		}
+		guard(ok)
+		return &genValue{code: e, t: staticType(t.Elem())}, nil
+
+	case reflect.Struct:
+		if is_ident {
+			if index, has_field := fieldIndex(t, string(attr)); has_field {
+				return g.genField(v, index, guard)
+			}
+			return nil, errors.New(fmt.Sprintf("Field or method '%s' not found in type %s.", attr, t))
+		}
+		return nil, errors.New(fmt.Sprintf("Struct %s can't be accessed by index %s.", t, part.name))
+	}
+	return nil, errors.New(fmt.Sprintf("Specifier '%s' can't be applied to type %s.", part.name, t))
+}
+
+// genCallable generates an explicit call like user.Greeting("Hi") of a method
+// or a function stored in a struct field (see lookupCallable).
+func (g *generator) genCallable(v *genValue, part identPart, guard func(string)) (*genValue, error) {
+	if ft, has_method := methodType(v.t, part.name); has_method {
+		return g.genCall(v.code+"."+part.name, part.name, ft, part.args)
+	}
+
+	t := v.t
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		if index, has_field := fieldIndex(t, part.name); has_field && t.FieldByIndex(index).Type.Kind() == reflect.Func {
+			fn, err := g.genField(v, index, guard)
+			if err != nil {
+				return nil, err
+			}
+			fn = g.materialize(fn)
+			guard(fn.code + " != nil")
+			return g.genCall(fn.code, part.name, fn.t, part.args)
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Method '%s' not found in type %s.", part.name, v.t))
+}
+
+// genFilters generates the calls of the filters (like evalValue).
+func (g *generator) genFilters(v *genValue, filters []exprFilterFunc) (*genValue, error) {
+	if len(filters) == 0 {
+		return v, nil
+	}
+	if v.t == renderedBlockType {
+		// The rendered block is neither a string nor accessible by filters
+		for _, filter := range filters {
+			if filter.name != "safe" && filter.name != "unsafe" {
+				return nil, errors.New(fmt.Sprintf("Filter '%s' can't be applied to block.super by the code generator", filter.name))
+			}
+		}
+		return v, nil
+	}
+
+	// The chain context is created once it's needed by a filter
+	fctx := ""
+	var visited []string
+	visit := func(name string) {
+		if fctx == "" {
+			visited = append(visited, name)
+		} else {
+			g.printf("%s.Visit(%s)\n", fctx, strconv.Quote(name))
+		}
+	}
+	for _, filter := range filters {
+		if filter.fn == nil {
+			visit(filter.name)
+			continue
+		}
+		if filter.name == "safe" && fctx == "" && v.t != nil && v.t != stringType {
+			// Only strings are escaped
+			visit(filter.name)
+			continue
+		}
+
+		args := "nil"
+		if len(filter.args) > 0 {
+			codes := make([]string, 0, len(filter.args))
+			for _, arg := range filter.args {
+				ident, is_ident := arg.(exprIdent)
+				if !is_ident {
+					codes = append(codes, goLiteral(reflect.ValueOf(arg)))
+					continue
+				}
+				parts, err := splitIdent(ident)
+				if err != nil {
+					return nil, err
+				}
+				arg_v, err := g.genIdent(ident, parts, nil, false)
+				if err != nil {
+					return nil, err
+				}
+				codes = append(codes, arg_v.code)
+			}
+			args = fmt.Sprintf("[]interface{}{%s}", strings.Join(codes, ", "))
+		}
+
+		if fctx == "" {
+			fctx = g.newVar("fctx")
+			g.printf("%s := &%sFilterChainContext{}\n", fctx, g.pkg(pongoPath))
+			for _, name := range visited {
+				visit(name)
+			}
+		}
+		f := g.newVar("f")
+		g.printf("%s, err := %sFilters[%s](%s, %s, %s)\n", f, g.pkg(pongoPath), strconv.Quote(filter.name), v.code, args, fctx)
+		g.printf("if err != nil {\nreturn %sNew(%s + err.Error())\n}\n", g.pkg("errors"), strconv.Quote(fmt.Sprintf("Filter '%s' failed: ", filter.name)))
+		visit(filter.name)
+		v = &genValue{code: f}
+	}
+	return v, nil
+}
+
+// stringOf returns the code formatting v like fmt.Sprintf("%v") does.
+func (g *generator) stringOf(v *genValue) (string, error) {
+	if v.found != "" {
+		found := *v
+		found.found = ""
+		str, err := g.stringOf(&found)
+		if err != nil {
+			return "", err
+		}
+		s := g.newVar("str")
+		g.printf("var %s string\nif %s {\n%s = %s\n}\n", s, v.found, s, str)
+		return s, nil
+	}
+	if v.ptr {
+		v = g.materialize(v)
+		str, err := g.stringOf(&genValue{code: "*" + v.code, t: v.t.Elem()})
+		if err != nil {
+			return "", err
+		}
+		s := g.newVar("str")
+		g.printf("var %s string\nif %s != nil {\n%s = %s\n} else {\n%s = %sSprint(%s)\n}\n", s, v.code, s, str, s, g.pkg("fmt"), v.code)
+		return s, nil
+	}
+	t := v.t
+	switch {
+	case t == stringType, t == renderedBlockType:
+		return v.code, nil
+	case t == nil, t.NumMethod() > 0:
+		// The type might implement fmt.Stringer or error
+		return fmt.Sprintf("%sSprint(%s)", g.pkg("fmt"), v.code), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("string(%s)", v.code), nil
+	case reflect.Bool:
+		return fmt.Sprintf("%sFormatBool(bool(%s))", g.pkg("strconv"), v.code), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%sFormatInt(int64(%s), 10)", g.pkg("strconv"), v.code), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("%sFormatUint(uint64(%s), 10)", g.pkg("strconv"), v.code), nil
+	case reflect.Float32:
+		return fmt.Sprintf("%sFormatFloat(float64(%s), 'g', -1, 32)", g.pkg("strconv"), v.code), nil
+	case reflect.Float64:
+		return fmt.Sprintf("%sFormatFloat(float64(%s), 'g', -1, 64)", g.pkg("strconv"), v.code), nil
+	}
+	return fmt.Sprintf("%sSprint(%s)", g.pkg("fmt"), v.code), nil
+}
+
+// isZero returns the code checking whether v equals the zero value of its type
+// (or the opposite if negate is true), see isTrue.
+func (g *generator) isZero(v *genValue, negate bool) (string, error) {
+	not := func(cond string) string {
+		if negate {
+			return "!(" + cond + ")"
+		}
+		return cond
+	}
+
+	t := v.t
+	if t == nil {
+		z := g.newVar("z")
+		g.printf("%s := false\n", z)
+		g.printf("switch x := interface{}(%s).(type) {\n", v.code)
+		g.printf("case bool:\n%s = !x\ncase string:\n%s = x == \"\"\ncase int:\n%s = x == 0\ncase float64:\n%s = x == 0\n", z, z, z, z)
+		g.printf("default:\n%[2]s = %[1]sZero(%[1]sTypeOf(x)).Interface() == x\n}\n", g.pkg("reflect"), z)
+		return not(z), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if negate {
+			return v.code, nil
+		}
+		return "!" + v.code, nil
+	case reflect.String:
+		return not(v.code + ` == ""`), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return not(v.code + " == 0"), nil
+	case reflect.Ptr, reflect.Chan:
+		return not(v.code + " == nil"), nil
+	case reflect.Struct, reflect.Array:
+		if t.Comparable() {
+			name, err := g.file.typeName(t)
+			if err != nil {
+				return "", err
+			}
+			return not(fmt.Sprintf("%s == (%s{})", v.code, name)), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("Values of type %s can't be compared (as used by conditions).", t))
+}
+
+var genNumberTypes = map[reflect.Type]bool{intType: true, reflect.TypeOf(0.0): true}
+
+// genCondition generates the evaluation of c (like condition.eval).
+func (g *generator) genCondition(c *condition) (*genValue, error) {
+	if c.op == nil {
+		v, err := g.genExpr(c.e)
+		if err != nil {
+			return nil, err
+		}
+		return g.resolve(v), nil
+	}
+
+	l, err := g.genCondition(c.left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := g.genCondition(c.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.op_name {
+	case "&&", "||":
+		if l.t == boolType && r.t == boolType {
+			return &genValue{code: fmt.Sprintf("(%s %s %s)", l.code, c.op_name, r.code), t: boolType}, nil
+		}
+		// Both operands must be bools
+		lb, lok, rb, rok := g.newVar("lb"), g.newVar("lok"), g.newVar("rb"), g.newVar("rok")
+		g.printf("%s, %s := interface{}(%s).(bool)\n", lb, lok, l.code)
+		g.printf("%s, %s := interface{}(%s).(bool)\n", rb, rok, r.code)
+		return &genValue{code: fmt.Sprintf("(%s && %s && (%s %s %s))", lok, rok, lb, c.op_name, rb), t: boolType}, nil
+
+	case "==", "!=", "<>":
+		op := "=="
+		if c.op_name != "==" {
+			op = "!="
+		}
+		if l.t != nil && l.t == r.t && l.t.Kind() != reflect.Struct && l.t.Kind() != reflect.Array && l.t.Comparable() {
+			return &genValue{code: fmt.Sprintf("(%s %s %s)", l.code, op, r.code), t: boolType}, nil
+		}
+		return &genValue{code: fmt.Sprintf("(interface{}(%s) %s interface{}(%s))", l.code, op, r.code), t: boolType}, nil
+	}
+
+	// Numbers (ints and float64s) are compared
+	op := c.op_name
+	if genNumberTypes[l.t] && genNumberTypes[r.t] {
+		if l.t == r.t {
+			return &genValue{code: fmt.Sprintf("(%s %s %s)", l.code, op, r.code), t: boolType}, nil
+		}
+		return &genValue{code: fmt.Sprintf("(float64(%s) %s float64(%s))", l.code, op, r.code), t: boolType}, nil
+	}
+	cmp := g.newVar("c")
+	g.printf("%s := false\n", cmp)
+	g.printf("switch a := interface{}(%s).(type) {\n", l.code)
+	g.printf("case int:\nswitch b := interface{}(%s).(type) {\ncase int:\n%s = a %s b\ncase float64:\n%s = float64(a) %s b\n}\n", r.code, cmp, op, cmp, op)
+	g.printf("case float64:\nswitch b := interface{}(%s).(type) {\ncase int:\n%s = a %s float64(b)\ncase float64:\n%s = a %s b\n}\n}\n", r.code, cmp, op, cmp, op)
+	return &genValue{code: cmp, t: boolType}, nil
+}
+
+// genArith generates the evaluation of the arithmetic expression n (like
+// arithOperation.apply).
+func (g *generator) genArith(n arithNode) (*genValue, error) {
+	switch n := n.(type) {
+	case *arithOperand:
+		v, err := g.genExpr(n.e)
+		if err != nil {
+			return nil, err
+		}
+		return g.resolve(v), nil
+	case *arithNegation:
+		v, err := g.genArith(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		return g.genArithOp('-', literalValue(0), v)
+	case *arithOperation:
+		l, err := g.genArith(n.left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := g.genArith(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return g.genArithOp(n.op, l, r)
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown arithmetic expression %T", n))
+}
+
+func (g *generator) genArithOp(op byte, l, r *genValue) (*genValue, error) {
+	if op == '+' && l.t == stringType && r.t == stringType {
+		return &genValue{code: fmt.Sprintf("(%s + %s)", l.code, r.code), t: stringType}, nil
+	}
+	if l.t == nil || r.t == nil {
+		return nil, errors.New("Arithmetic on values only known at runtime is not supported by the code generator")
+	}
+
+	number := func(t reflect.Type) (is_int bool, ok bool) {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true, true
+		case reflect.Float32, reflect.Float64:
+			return false, true
+		}
+		return false, false
+	}
+	l_is_int, l_ok := number(l.t)
+	r_is_int, r_ok := number(r.t)
+	if !l_ok || !r_ok {
+		return nil, errors.New(fmt.Sprintf("Operator '%c' can't be applied to %s and %s", op, l.t, r.t))
+	}
+
+	conv, result := "float64", reflect.TypeOf(0.0)
+	if l_is_int && r_is_int {
+		conv, result = "int64", intType
+	} else if op == '%' {
+		return nil, errors.New(fmt.Sprintf("Operator '%c' requires integers", op))
+	}
+
+	if op == '/' || op == '%' {
+		g.printf("if %s(%s) == 0 {\nreturn %sNew(\"Division by zero\")\n}\n", conv, r.code, g.pkg("errors"))
+	}
+	code := fmt.Sprintf("(%s(%s) %c %s(%s))", conv, l.code, op, conv, r.code)
+	if result == intType {
+		code = "int" + code
+	}
+	return &genValue{code: code, t: result}, nil
+}
@@ -0,0 +1,3010 @@
+// Code generated by pongo gen. DO NOT EDIT.
+
+//go:build !pongo_gen
+
+package pongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Render0 renders the template 'gen0' (see pongo.Template.ExecuteContext).
+func Render0(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen0] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("Hello ")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen0", 1, 9, "Hello ", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx1 := &FilterChainContext{}
+			f2, err := Filters["capitalize"](data.Name, nil, fctx1)
+			if err != nil {
+				return errors.New("Filter 'capitalize' failed: " + err.Error())
+			}
+			fctx1.Visit("capitalize")
+			f3, err := Filters["safe"](f2, nil, fctx1)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx1.Visit("safe")
+			out.WriteString(fmt.Sprint(f3))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen0", 1, 26, "name|capitalize", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("!")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen0", 1, 28, "!", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render1 renders the template 'gen1' (see pongo.Template.ExecuteContext).
+func Render1(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen1] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx1 := &FilterChainContext{}
+			f2, err := Filters["safe"](data.Html, nil, fctx1)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx1.Visit("safe")
+			out.WriteString(fmt.Sprint(f2))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen1", 1, 9, "html", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen1", 1, 14, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx3 := &FilterChainContext{}
+			fctx3.Visit("unsafe")
+			f4, err := Filters["safe"](data.Html, nil, fctx3)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx3.Visit("safe")
+			out.WriteString(fmt.Sprint(f4))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen1", 1, 27, "html|unsafe", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen1", 1, 32, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx5 := &FilterChainContext{}
+			f6, err := Filters["safe"](data.Html, nil, fctx5)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx5.Visit("safe")
+			f7, err := Filters["safe"](f6, nil, fctx5)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx5.Visit("safe")
+			out.WriteString(fmt.Sprint(f7))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen1", 1, 43, "html|safe", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render10 renders the template 'gen10' (see pongo.Template.ExecuteContext).
+func Render10(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen10] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v2 string
+			v1 := data.User
+			if v1 != nil {
+				v2 = v1.Name
+			}
+			fctx3 := &FilterChainContext{}
+			f4, err := Filters["safe"](v2, nil, fctx3)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx3.Visit("safe")
+			out.WriteString(fmt.Sprint(f4))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 14, "user.Name", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 19, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v8 Message
+			ok9 := false
+			_ = ok9
+			v5 := data.User
+			if v5 != nil {
+				v6 := v5.Greeting("Hi")
+				v7 := v6.Upper()
+				v8, ok9 = v7, true
+			}
+			var v10 interface{} = ""
+			if ok9 {
+				v10 = v8
+			}
+			fctx11 := &FilterChainContext{}
+			f12, err := Filters["safe"](v10, nil, fctx11)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx11.Visit("safe")
+			out.WriteString(fmt.Sprint(f12))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 46, "user.Greeting(\"Hi\").Upper", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 51, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v15 int
+			ok16 := false
+			_ = ok16
+			v13 := data.User
+			if v13 != nil {
+				v14 := v13.AgeIn(2)
+				v15, ok16 = v14, true
+			}
+			var v17 interface{} = ""
+			if ok16 {
+				v17 = v15
+			}
+			fctx18 := &FilterChainContext{}
+			f19, err := Filters["safe"](v17, nil, fctx18)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx18.Visit("safe")
+			out.WriteString(fmt.Sprint(f19))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 66, "user.AgeIn(2)", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 71, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v23 string
+			v20 := data.User
+			if v20 != nil {
+				v21 := v20.Friends
+				if 0 < len(v21) {
+					v22 := v21[0]
+					if v22 != nil {
+						v23 = v22.Name
+					}
+				}
+			}
+			fctx24 := &FilterChainContext{}
+			f25, err := Filters["safe"](v23, nil, fctx24)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx24.Visit("safe")
+			out.WriteString(fmt.Sprint(f25))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 92, "user.Friends.0.Name", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 97, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v27 string
+			v26 := data.Nobody
+			if v26 != nil {
+				v27 = v26.Name
+			}
+			fctx28 := &FilterChainContext{}
+			f29, err := Filters["safe"](v27, nil, fctx28)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx28.Visit("safe")
+			out.WriteString(fmt.Sprint(f29))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 110, "nobody.Name", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 115, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			v30 := data.Title("Page")
+			fctx31 := &FilterChainContext{}
+			f32, err := Filters["safe"](v30, nil, fctx31)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx31.Visit("safe")
+			out.WriteString(fmt.Sprint(f32))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen10", 1, 130, "Title(\"Page\")", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render11 renders the template 'gen11' (see pongo.Template.ExecuteContext).
+func Render11(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen11] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v3 *Person
+			ok4 := false
+			_ = ok4
+			v1 := data.User
+			if v1 != nil {
+				v2, err := v1.Friend("Georg")
+				if err != nil {
+					return fmt.Errorf("Method 'Friend' returned an error: %w", err)
+				}
+				v3, ok4 = v2, true
+			}
+			var v5 interface{} = ""
+			if ok4 {
+				v5 = v3
+				if v3 != nil {
+					v5 = *v3
+				}
+			}
+			fctx6 := &FilterChainContext{}
+			f7, err := Filters["safe"](v5, nil, fctx6)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx6.Visit("safe")
+			out.WriteString(fmt.Sprint(f7))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen11", 1, 25, "user.Friend(\"Georg\")", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render12 renders the template 'gen12' (see pongo.Template.ExecuteContext).
+func Render12(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen12] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v3 float64
+			ok4 := false
+			_ = ok4
+			v1 := data.User
+			if v1 != nil {
+				v2, err := v1.Balance(go_ctx, "default")
+				if err != nil {
+					return fmt.Errorf("Method 'Balance' returned an error: %w", err)
+				}
+				v3, ok4 = v2, true
+			}
+			var v5 interface{} = ""
+			if ok4 {
+				v5 = v3
+			}
+			fctx6 := &FilterChainContext{}
+			f7, err := Filters["safe"](v5, nil, fctx6)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx6.Visit("safe")
+			out.WriteString(fmt.Sprint(f7))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen12", 1, 28, "user.Balance(\"default\")", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render13 renders the template 'gen13' (see pongo.Template.ExecuteContext).
+func Render13(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen13] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v2 []*Person
+			ok3 := false
+			_ = ok3
+			v1 := data.User
+			if v1 != nil {
+				v2, ok3 = v1.Friends, true
+			}
+			items7 := v2
+			n4 := len(items7)
+			if n4 <= 0 {
+			} else {
+				loop5 := &forContext{Max: n4 - 1, Max1: n4, Counter1: 1, First: true}
+				for i6 := 0; i6 < n4; i6++ {
+					if i6 == 1 {
+						loop5.First = false
+					}
+					if i6 == n4-1 {
+						loop5.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString(strconv.FormatInt(int64((i6 + 1)), 10))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 43, "forcounter1", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString(":")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 48, ":", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						var v9 string
+						v8 := items7[i6]
+						if v8 != nil {
+							v9 = v8.Name
+						}
+						fctx10 := &FilterChainContext{}
+						f11, err := Filters["safe"](v9, nil, fctx10)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx10.Visit("safe")
+						out.WriteString(fmt.Sprint(f11))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 56, "f.Name", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						if !loop5.Last {
+							if err := func() error {
+								if err := check(); err != nil {
+									return err
+								}
+								out.WriteString(",")
+								return nil
+							}(); err != nil {
+								return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 83, ",", err)
+							}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 78, "if !forloop.Last", err)
+					}
+					loop5.Counter++
+					loop5.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 26, "for f in user.Friends", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 107, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v14 string
+			v12 := data.User
+			if v12 != nil {
+				v13 := v12.Join("+", "a", "b")
+				v14 = v13
+			}
+			fctx15 := &FilterChainContext{}
+			f16, err := Filters["safe"](v14, nil, fctx15)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx15.Visit("safe")
+			out.WriteString(fmt.Sprint(f16))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen13", 1, 133, "user.Join(\"+\", \"a\", \"b\")", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render14 renders the template 'gen14' (see pongo.Template.ExecuteContext).
+func Render14(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen14] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			items4 := data.M
+			n1 := len(items4)
+			if n1 <= 0 {
+			} else {
+				keys5 := make([]string, 0, n1)
+				for key := range items4 {
+					keys5 = append(keys5, key)
+				}
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						fctx6 := &FilterChainContext{}
+						f7, err := Filters["safe"](struct {
+							Key   string
+							Value string
+						}{keys5[i3], items4[keys5[i3]]}.Key, nil, fctx6)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx6.Visit("safe")
+						out.WriteString(fmt.Sprint(f7))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 28, "kv.Key", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("=")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 33, "=", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						fctx8 := &FilterChainContext{}
+						f9, err := Filters["safe"](struct {
+							Key   string
+							Value string
+						}{keys5[i3], items4[keys5[i3]]}.Value, nil, fctx8)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx8.Visit("safe")
+						out.WriteString(fmt.Sprint(f9))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 43, "kv.Value", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 16, "for kv in m", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 60, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			items13 := data.Name
+			n10 := len(items13)
+			if n10 <= 0 {
+			} else {
+				loop11 := &forContext{Max: n10 - 1, Max1: n10, Counter1: 1, First: true}
+				for i12 := 0; i12 < n10; i12++ {
+					if i12 == 1 {
+						loop11.First = false
+					}
+					if i12 == n10-1 {
+						loop11.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("[")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 80, "[", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						fctx14 := &FilterChainContext{}
+						f15, err := Filters["safe"](string(items13)[i12:i12+1], nil, fctx14)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx14.Visit("safe")
+						out.WriteString(fmt.Sprint(f15))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 83, "c", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("]")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 88, "]", err)
+					}
+					loop11.Counter++
+					loop11.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen14", 1, 75, "for c in name", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render15 renders the template 'gen15' (see pongo.Template.ExecuteContext).
+func Render15(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen15] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			w1 := data.Nobody
+			_ = w1
+			var v3 int
+			ok4 := false
+			_ = ok4
+			v2 := data.User
+			if v2 != nil {
+				v3, ok4 = v2.Age, true
+			}
+			w5 := v3
+			_ = w5
+			var v7 string
+			v6 := data.User
+			if v6 != nil {
+				v7 = v6.Name
+			}
+			w8 := v7
+			_ = w8
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				var v9 interface{} = w1
+				if w1 != nil {
+					v9 = *w1
+				}
+				fctx10 := &FilterChainContext{}
+				f11, err := Filters["safe"](v9, nil, fctx10)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx10.Visit("safe")
+				out.WriteString(fmt.Sprint(f11))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 9, "item", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 14, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				var v12 interface{} = ""
+				if ok4 {
+					v12 = w5
+				}
+				fctx13 := &FilterChainContext{}
+				f14, err := Filters["safe"](v12, nil, fctx13)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx13.Visit("safe")
+				out.WriteString(fmt.Sprint(f14))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 21, "count", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 26, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				fctx15 := &FilterChainContext{}
+				f16, err := Filters["safe"](w8, nil, fctx15)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx15.Visit("safe")
+				out.WriteString(fmt.Sprint(f16))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 32, "name", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 65, "include \"row\" with item=nobody count=user.Age name=user.Name", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 70, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			v18 := data.Nobody
+			var v17 interface{} = v18
+			if v18 != nil {
+				v17 = *v18
+			}
+			fctx19 := &FilterChainContext{}
+			f20, err := Filters["safe"](v17, nil, fctx19)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx19.Visit("safe")
+			out.WriteString(fmt.Sprint(f20))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 78, "nobody", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 83, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v24 string
+			v21 := data.User
+			if v21 != nil {
+				v22 := v21.Friends
+				if 5 < len(v22) {
+					v23 := v22[5]
+					if v23 != nil {
+						v24 = v23.Name
+					}
+				}
+			}
+			fctx25 := &FilterChainContext{}
+			f26, err := Filters["safe"](v24, nil, fctx25)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx25.Visit("safe")
+			out.WriteString(fmt.Sprint(f26))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 104, "user.Friends.5.Name", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 109, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v29 string
+			e27, ok28 := data.M[data.Name]
+			if ok28 {
+				v29 = e27
+			}
+			fctx30 := &FilterChainContext{}
+			f31, err := Filters["safe"](v29, nil, fctx30)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx30.Visit("safe")
+			out.WriteString(fmt.Sprint(f31))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen15", 1, 118, "m[name]", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render16 renders the template 'gen16' (see pongo.Template.ExecuteContext).
+func Render16(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen16] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v1 interface{} = data.Extra
+			if rv := reflect.ValueOf(v1); rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().CanInterface() {
+				v1 = rv.Elem().Interface()
+			}
+			fctx2 := &FilterChainContext{}
+			f3, err := Filters["safe"](v1, nil, fctx2)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx2.Visit("safe")
+			out.WriteString(fmt.Sprint(f3))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen16", 1, 10, "extra", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen16", 1, 15, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx4 := &FilterChainContext{}
+			f5, err := Filters["add"](data.N, []interface{}{2}, fctx4)
+			if err != nil {
+				return errors.New("Filter 'add' failed: " + err.Error())
+			}
+			fctx4.Visit("add")
+			f6, err := Filters["safe"](f5, nil, fctx4)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx4.Visit("safe")
+			out.WriteString(fmt.Sprint(f6))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen16", 1, 24, "n|add:2", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen16", 1, 29, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx7 := &FilterChainContext{}
+			f8, err := Filters["join"](data.Words, []interface{}{", "}, fctx7)
+			if err != nil {
+				return errors.New("Filter 'join' failed: " + err.Error())
+			}
+			fctx7.Visit("join")
+			f9, err := Filters["safe"](f8, nil, fctx7)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx7.Visit("safe")
+			out.WriteString(fmt.Sprint(f9))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen16", 1, 46, "words|join:\", \"", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render17 renders the template 'gen17' (see pongo.Template.ExecuteContext).
+func Render17(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen17] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("<")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 4, "<", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					super1 := &strings.Builder{}
+					if err := func(out *strings.Builder) error {
+						if err := func() error {
+							if err := check(); err != nil {
+								return err
+							}
+							out.WriteString("<i>Base</i>")
+							return nil
+						}(); err != nil {
+							return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 32, "<i>Base</i>", err)
+						}
+						return nil
+					}(super1); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString(super1.String())
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout_child", 1, 75, "block.super", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("+Child")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout_child", 1, 85, "+Child", err)
+					}
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 17, "block title", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("|")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 47, "|", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("[")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 67, "[", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						if err := func() error {
+							if err := check(); err != nil {
+								return err
+							}
+							out.WriteString("child-inner")
+							return nil
+						}(); err != nil {
+							return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout_child", 1, 127, "child-inner", err)
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 80, "block inner", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("]")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 109, "]", err)
+					}
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 62, "block content", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString(">")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 121, ">", err)
+				}
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout_child", 1, 21, "extends \"layout\"", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen17", 1, 27, "extends \"layout_child\"", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render18 renders the template 'gen18' (see pongo.Template.ExecuteContext).
+func Render18(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen18] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("<")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 4, "<", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("<i>Base</i>")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 32, "<i>Base</i>", err)
+				}
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 17, "block title", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("|")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 47, "|", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				super1 := &strings.Builder{}
+				if err := func(out *strings.Builder) error {
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("[")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 67, "[", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						if err := func() error {
+							if err := check(); err != nil {
+								return err
+							}
+							fctx2 := &FilterChainContext{}
+							f3, err := Filters["safe"](data.Name, nil, fctx2)
+							if err != nil {
+								return errors.New("Filter 'safe' failed: " + err.Error())
+							}
+							fctx2.Visit("safe")
+							out.WriteString(fmt.Sprint(f3))
+							return nil
+						}(); err != nil {
+							return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen18", 1, 100, "name", err)
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 80, "block inner", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						out.WriteString("]")
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "layout", 1, 109, "]", err)
+					}
+					return nil
+				}(super1); err != nil {
+					return err
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("(")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen18", 1, 45, "(", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString(super1.String())
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen18", 1, 58, "block.super", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString(")")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen18", 1, 63, ")", err)
+				}
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 62, "block content", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString(">")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout", 1, 121, ">", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen18", 1, 21, "extends \"layout\"", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render19 renders the template 'gen19' (see pongo.Template.ExecuteContext).
+func Render19(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen19] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v2 string
+			v1 := data.Words
+			if 0 < len(v1) {
+				v2 = v1[0]
+			}
+			w3 := v2
+			_ = w3
+			fctx4 := &FilterChainContext{}
+			f5, err := Filters["length"](data.Words, nil, fctx4)
+			if err != nil {
+				return errors.New("Filter 'length' failed: " + err.Error())
+			}
+			fctx4.Visit("length")
+			w6 := f5
+			_ = w6
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				fctx7 := &FilterChainContext{}
+				f8, err := Filters["safe"](w3, nil, fctx7)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx7.Visit("safe")
+				out.WriteString(fmt.Sprint(f8))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 9, "item", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 14, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				var v9 interface{} = w6
+				if rv := reflect.ValueOf(v9); rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().CanInterface() {
+					v9 = rv.Elem().Interface()
+				}
+				fctx10 := &FilterChainContext{}
+				f11, err := Filters["safe"](v9, nil, fctx10)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx10.Visit("safe")
+				out.WriteString(fmt.Sprint(f11))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 21, "count", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 26, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				fctx12 := &FilterChainContext{}
+				f13, err := Filters["safe"](data.Name, nil, fctx12)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx12.Visit("safe")
+				out.WriteString(fmt.Sprint(f13))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 32, "name", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen19", 1, 56, "include \"row\" with item=words[0] count=words|length", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen19", 1, 61, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			w14 := 1
+			_ = w14
+			w15 := 2
+			_ = w15
+			w16 := "x"
+			_ = w16
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString(strconv.FormatInt(int64(w14), 10))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 9, "item", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 14, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString(strconv.FormatInt(int64(w15), 10))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 21, "count", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 26, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				fctx17 := &FilterChainContext{}
+				f18, err := Filters["safe"](w16, nil, fctx17)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx17.Visit("safe")
+				out.WriteString(fmt.Sprint(f18))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 32, "name", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen19", 1, 117, "include static \"row\" with item=1 count=2 name=\"x\" only", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render2 renders the template 'gen2' (see pongo.Template.ExecuteContext).
+func Render2(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen2] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			items4 := data.Words
+			n1 := len(items4)
+			if n1 <= 0 {
+			} else {
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						if loop2.First {
+							if err := func() error {
+								if err := check(); err != nil {
+									return err
+								}
+								out.WriteString("[")
+								return nil
+							}(); err != nil {
+								return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 46, "[", err)
+							}
+						} else {
+							if err := func() error {
+								if err := check(); err != nil {
+									return err
+								}
+								out.WriteString(",")
+								return nil
+							}(); err != nil {
+								return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 57, ",", err)
+							}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 41, "if forloop.First", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						fctx5 := &FilterChainContext{}
+						f6, err := Filters["upper"](items4[i3], nil, fctx5)
+						if err != nil {
+							return errors.New("Filter 'upper' failed: " + err.Error())
+						}
+						fctx5.Visit("upper")
+						f7, err := Filters["safe"](f6, nil, fctx5)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx5.Visit("safe")
+						out.WriteString(fmt.Sprint(f7))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 77, "w|upper", err)
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						if loop2.Last {
+							if err := func() error {
+								if err := check(); err != nil {
+									return err
+								}
+								out.WriteString("]")
+								return nil
+							}(); err != nil {
+								return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 103, "]", err)
+							}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 98, "if forloop.Last", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen2", 1, 19, "for w in words", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render20 renders the template 'gen20' (see pongo.Template.ExecuteContext).
+func Render20(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen20] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen20", 1, 36, "include \"foobar\" ignore missing", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("ok")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen20", 1, 39, "ok", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render21 renders the template 'gen21' (see pongo.Template.ExecuteContext).
+func Render21(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen21] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v3 int
+			ok4 := false
+			_ = ok4
+			if int64(data.Zero) == 0 {
+				return errors.New("Division by zero")
+			}
+			v1 := data.Items
+			i2 := int(int(int64(1) / int64(data.Zero)))
+			if i2 < 0 {
+				i2 += len(v1)
+			}
+			if i2 >= 0 && i2 < len(v1) {
+				v3, ok4 = v1[i2], true
+			}
+			var v5 interface{} = ""
+			if ok4 {
+				v5 = v3
+			}
+			fctx6 := &FilterChainContext{}
+			f7, err := Filters["safe"](v5, nil, fctx6)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx6.Visit("safe")
+			out.WriteString(fmt.Sprint(f7))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen21", 1, 20, "items[1 / zero]", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render22 renders the template 'gen22' (see pongo.Template.ExecuteContext).
+func Render22(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen22] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	output := 0
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			items4 := data.Items
+			n1 := len(items4)
+			if n1 <= 0 {
+			} else {
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						str5 := strconv.FormatInt(int64(items4[i3]), 10)
+						out.WriteString(str5)
+						output += len(str5)
+						if output > 2 {
+							return &LimitError{Limit: "MaxOutput", Max: 2}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen22", 1, 26, "i", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen22", 1, 19, "for i in items", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render23 renders the template 'gen23' (see pongo.Template.ExecuteContext).
+func Render23(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen23] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	iterations := 0
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			n1 := 3
+			if n1 <= 0 {
+			} else {
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					iterations++
+					if iterations > 11 {
+						return &LimitError{Limit: "MaxIterations", Max: 11}
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						n7 := 3
+						if n7 <= 0 {
+						} else {
+							loop8 := &forContext{Max: n7 - 1, Max1: n7, Counter1: 1, First: true}
+							for i9 := 0; i9 < n7; i9++ {
+								if i9 == 1 {
+									loop8.First = false
+								}
+								if i9 == n7-1 {
+									loop8.Last = true
+								}
+								iterations++
+								if iterations > 11 {
+									return &LimitError{Limit: "MaxIterations", Max: 11}
+								}
+								if err := check(); err != nil {
+									return err
+								}
+								loop8.Counter++
+								loop8.Counter1++
+							}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen23", 1, 21, "for 3", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen23", 1, 10, "for 3", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render24 renders the template 'gen24' (see pongo.Template.ExecuteContext).
+func Render24(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen24] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			w1 := 1
+			_ = w1
+			w2 := 2
+			_ = w2
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString(strconv.FormatInt(int64(w1), 10))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 9, "item", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 14, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString(strconv.FormatInt(int64(w2), 10))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 21, "count", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				out.WriteString("-")
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 26, "-", err)
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				fctx3 := &FilterChainContext{}
+				f4, err := Filters["safe"](data.Name, nil, fctx3)
+				if err != nil {
+					return errors.New("Filter 'safe' failed: " + err.Error())
+				}
+				fctx3.Visit("safe")
+				out.WriteString(fmt.Sprint(f4))
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "row", 1, 32, "name", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen24", 1, 38, "include \"row\" with item=1 count=2", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render25 renders the template 'gen25' (see pongo.Template.ExecuteContext).
+func Render25(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen25] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if err := func() error {
+				if err := check(); err != nil {
+					return err
+				}
+				return &LimitError{Limit: "MaxDepth", Max: 1}
+			}(); err != nil {
+				return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "layout_child", 1, 21, "extends \"layout\"", err)
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen25", 1, 27, "extends \"layout_child\"", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render26 renders the template 'gen26' (see pongo.Template.ExecuteContext).
+func Render26(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen26] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			fctx1 := &FilterChainContext{}
+			f2, err := Filters["safe"](data.Name, nil, fctx1)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx1.Visit("safe")
+			out.WriteString(fmt.Sprint(f2))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen26", 1, 9, "name", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render27 renders the template 'gen27' (see pongo.Template.ExecuteContext).
+func Render27(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen27] data must not be nil")
+	}
+	deadline := time.Now().Add(3600000000000)
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return &LimitError{Limit: "Timeout", Max: time.Duration(3600000000000)}
+		}
+		return nil
+	}
+	output := 0
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			items4 := data.Words
+			n1 := len(items4)
+			if n1 <= 0 {
+			} else {
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						fctx5 := &FilterChainContext{}
+						f6, err := Filters["safe"](items4[i3], nil, fctx5)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx5.Visit("safe")
+						str7 := fmt.Sprint(f6)
+						out.WriteString(str7)
+						output += len(str7)
+						if output > 2 {
+							return &LimitError{Limit: "MaxOutput", Max: 2}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen27", 1, 26, "w", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen27", 1, 19, "for w in words", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render3 renders the template 'gen3' (see pongo.Template.ExecuteContext).
+func Render3(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen3] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			items4 := data.Words
+			n1 := len(items4)
+			if n1 <= 0 {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("no words")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen3", 1, 48, "no words", err)
+				}
+			} else {
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						fctx5 := &FilterChainContext{}
+						f6, err := Filters["safe"](items4[i3], nil, fctx5)
+						if err != nil {
+							return errors.New("Filter 'safe' failed: " + err.Error())
+						}
+						fctx5.Visit("safe")
+						out.WriteString(fmt.Sprint(f6))
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen3", 1, 26, "w", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen3", 1, 19, "for w in words", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render4 renders the template 'gen4' (see pongo.Template.ExecuteContext).
+func Render4(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen4] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	type forContext struct {
+		Counter, Counter1, Max, Max1 int
+		First, Last                  bool
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			n1 := 3
+			if n1 <= 0 {
+			} else {
+				loop2 := &forContext{Max: n1 - 1, Max1: n1, Counter1: 1, First: true}
+				for i3 := 0; i3 < n1; i3++ {
+					if i3 == 1 {
+						loop2.First = false
+					}
+					if i3 == n1-1 {
+						loop2.Last = true
+					}
+					if err := check(); err != nil {
+						return err
+					}
+					if err := func() error {
+						if err := check(); err != nil {
+							return err
+						}
+						n21 := 2
+						if n21 <= 0 {
+						} else {
+							loop22 := &forContext{Max: n21 - 1, Max1: n21, Counter1: 1, First: true}
+							for i23 := 0; i23 < n21; i23++ {
+								if i23 == 1 {
+									loop22.First = false
+								}
+								if i23 == n21-1 {
+									loop22.Last = true
+								}
+								if err := check(); err != nil {
+									return err
+								}
+								if err := func() error {
+									if err := check(); err != nil {
+										return err
+									}
+									var v26 int
+									ok27 := false
+									_ = ok27
+									v24 := []*forContext{loop2, loop22}
+									if 0 < len(v24) {
+										v25 := v24[0]
+										if v25 != nil {
+											v26, ok27 = v25.Counter1, true
+										}
+									}
+									var v28 interface{} = ""
+									if ok27 {
+										v28 = v26
+									}
+									fctx29 := &FilterChainContext{}
+									f30, err := Filters["safe"](v28, nil, fctx29)
+									if err != nil {
+										return errors.New("Filter 'safe' failed: " + err.Error())
+									}
+									fctx29.Visit("safe")
+									out.WriteString(fmt.Sprint(f30))
+									return nil
+								}(); err != nil {
+									return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen4", 1, 46, "forloops.0.Counter1", err)
+								}
+								if err := func() error {
+									if err := check(); err != nil {
+										return err
+									}
+									var v33 int
+									ok34 := false
+									_ = ok34
+									v31 := []*forContext{loop2, loop22}
+									if 1 < len(v31) {
+										v32 := v31[1]
+										if v32 != nil {
+											v33, ok34 = v32.Counter1, true
+										}
+									}
+									var v35 interface{} = ""
+									if ok34 {
+										v35 = v33
+									}
+									fctx36 := &FilterChainContext{}
+									f37, err := Filters["safe"](v35, nil, fctx36)
+									if err != nil {
+										return errors.New("Filter 'safe' failed: " + err.Error())
+									}
+									fctx36.Visit("safe")
+									out.WriteString(fmt.Sprint(f37))
+									return nil
+								}(); err != nil {
+									return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen4", 1, 71, "forloops.1.Counter1", err)
+								}
+								if err := func() error {
+									if err := check(); err != nil {
+										return err
+									}
+									out.WriteString(strconv.FormatInt(int64(i23), 10))
+									return nil
+								}(); err != nil {
+									return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen4", 1, 87, "forcounter", err)
+								}
+								if err := func() error {
+									if err := check(); err != nil {
+										return err
+									}
+									out.WriteString(" ")
+									return nil
+								}(); err != nil {
+									return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen4", 1, 92, " ", err)
+								}
+								loop22.Counter++
+								loop22.Counter1++
+							}
+						}
+						return nil
+					}(); err != nil {
+						return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen4", 1, 21, "for 2", err)
+					}
+					loop2.Counter++
+					loop2.Counter1++
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen4", 1, 10, "for 3", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render5 renders the template 'gen5' (see pongo.Template.ExecuteContext).
+func Render5(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen5] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if (data.N > 2) && data.Flag {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("big")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen5", 1, 28, "big", err)
+				}
+			} else {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("small")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen5", 1, 43, "small", err)
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen5", 1, 21, "if n > 2 && flag", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render6 renders the template 'gen6' (see pongo.Template.ExecuteContext).
+func Render6(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen6] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if (data.N > 2) && data.Flag {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("big")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen6", 1, 28, "big", err)
+				}
+			} else {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("small")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen6", 1, 43, "small", err)
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen6", 1, 21, "if n > 2 && flag", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render7 renders the template 'gen7' (see pongo.Template.ExecuteContext).
+func Render7(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen7] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if !data.Flag || (data.Name == "flo") {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("yes")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen7", 1, 37, "yes", err)
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen7", 1, 30, "if !flag || name == \"flo\"", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			if float64(data.N) >= float64(1.5) {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("no")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen7", 1, 67, "no", err)
+				}
+			}
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen7", 1, 61, "if n >= 1.5", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render8 renders the template 'gen8' (see pongo.Template.ExecuteContext).
+func Render8(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen8] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			body1 := &strings.Builder{}
+			if err := func(out *strings.Builder) error {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("   ")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 16, "   ", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					fctx2 := &FilterChainContext{}
+					f3, err := Filters["safe"](data.Name, nil, fctx2)
+					if err != nil {
+						return errors.New("Filter 'safe' failed: " + err.Error())
+					}
+					fctx2.Visit("safe")
+					out.WriteString(fmt.Sprint(f3))
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 22, "name", err)
+				}
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("   ")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 29, "   ", err)
+				}
+				return nil
+			}(body1); err != nil {
+				return err
+			}
+			out.WriteString(strings.TrimSpace(body1.String()))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 9, "trim", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 43, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			body4 := &strings.Builder{}
+			if err := func(out *strings.Builder) error {
+				if err := func() error {
+					if err := check(); err != nil {
+						return err
+					}
+					out.WriteString("banana")
+					return nil
+				}(); err != nil {
+					return fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 69, "banana", err)
+				}
+				return nil
+			}(body4); err != nil {
+				return err
+			}
+			str5 := body4.String()
+			str5 = strings.Replace(str5, "a", "", -1)
+			str5 = strings.Replace(str5, "n", "", -1)
+			out.WriteString(str5)
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen8", 1, 59, "remove \"a\",\"n\"", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+// Render9 renders the template 'gen9' (see pongo.Template.ExecuteContext).
+func Render9(go_ctx context.Context, data *genPage) (*string, error) {
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	if data == nil {
+		return nil, errors.New("[Error: gen9] data must not be nil")
+	}
+	check := func() error {
+		if err := go_ctx.Err(); err != nil {
+			return err
+		}
+		return nil
+	}
+	out := &strings.Builder{}
+	if err := func() error {
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v3 int
+			ok4 := false
+			_ = ok4
+			v1 := data.Items
+			i2 := int(int(int64(data.I) + int64(1)))
+			if i2 < 0 {
+				i2 += len(v1)
+			}
+			if i2 >= 0 && i2 < len(v1) {
+				v3, ok4 = v1[i2], true
+			}
+			var v5 interface{} = ""
+			if ok4 {
+				v5 = v3
+			}
+			fctx6 := &FilterChainContext{}
+			f7, err := Filters["safe"](v5, nil, fctx6)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx6.Visit("safe")
+			out.WriteString(fmt.Sprint(f7))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 17, "items[i + 1]", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 22, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v9 int
+			ok10 := false
+			_ = ok10
+			v8 := data.Items
+			if 5 < len(v8) {
+				v9, ok10 = v8[5], true
+			}
+			var v11 interface{} = ""
+			if ok10 {
+				v11 = v9
+			}
+			fctx12 := &FilterChainContext{}
+			f13, err := Filters["safe"](v11, nil, fctx12)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx12.Visit("safe")
+			out.WriteString(fmt.Sprint(f13))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 32, "items[5]", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 37, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v16 int
+			ok17 := false
+			_ = ok17
+			v14 := data.Items
+			i15 := int(int(int64(0) - int64(1)))
+			if i15 < 0 {
+				i15 += len(v14)
+			}
+			if i15 >= 0 && i15 < len(v14) {
+				v16, ok17 = v14[i15], true
+			}
+			var v18 interface{} = ""
+			if ok17 {
+				v18 = v16
+			}
+			fctx19 := &FilterChainContext{}
+			f20, err := Filters["safe"](v18, nil, fctx19)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx19.Visit("safe")
+			out.WriteString(fmt.Sprint(f20))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 48, "items[-1]", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 53, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v23 string
+			e21, ok22 := data.M["key"]
+			if !ok22 {
+				e21, ok22 = data.M[string("")]
+			}
+			if ok22 {
+				v23 = e21
+			}
+			fctx24 := &FilterChainContext{}
+			f25, err := Filters["safe"](v23, nil, fctx24)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx24.Visit("safe")
+			out.WriteString(fmt.Sprint(f25))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 60, "m.key", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 65, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v28 string
+			e26, ok27 := data.M["missing"]
+			if !ok27 {
+				e26, ok27 = data.M[string("")]
+			}
+			if ok27 {
+				v28 = e26
+			}
+			fctx29 := &FilterChainContext{}
+			f30, err := Filters["safe"](v28, nil, fctx29)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx29.Visit("safe")
+			out.WriteString(fmt.Sprint(f30))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 76, "m.missing", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			out.WriteString("|")
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 81, "|", err)
+		}
+		if err := func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			var v32 string
+			v31 := data.Words
+			if 0 < len(v31) {
+				v32 = v31[0]
+			}
+			fctx33 := &FilterChainContext{}
+			f34, err := Filters["safe"](v32, nil, fctx33)
+			if err != nil {
+				return errors.New("Filter 'safe' failed: " + err.Error())
+			}
+			fctx33.Visit("safe")
+			out.WriteString(fmt.Sprint(f34))
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", "gen9", 1, 90, "words.0", err)
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	result := out.String()
+	return &result, nil
+}
+
+var gen_renders = map[int]func(context.Context, *genPage) (*string, error){
+	0:  Render0,
+	1:  Render1,
+	2:  Render2,
+	3:  Render3,
+	4:  Render4,
+	5:  Render5,
+	6:  Render6,
+	7:  Render7,
+	8:  Render8,
+	9:  Render9,
+	10: Render10,
+	11: Render11,
+	12: Render12,
+	13: Render13,
+	14: Render14,
+	15: Render15,
+	16: Render16,
+	17: Render17,
+	18: Render18,
+	19: Render19,
+	20: Render20,
+	21: Render21,
+	22: Render22,
+	23: Render23,
+	24: Render24,
+	25: Render25,
+	26: Render26,
+	27: Render27,
+}
@@ -16,6 +16,7 @@ func splitArgs(in *string, sep string) *[]string {
 
 	escaped := false
 	in_string := false
+	depth := 0 // nesting level of parentheses/brackets, e. g. in Greeting("Hi", name)
 	pos := 0
 	buf := *in
 	argbuf := ""
@@ -35,28 +36,28 @@ func splitArgs(in *string, sep string) *[]string {
 		}
 
 		if c == "\"" && !escaped {
-			if in_string {
-				// String end
-				in_string = false
-
-				// We go a string, now add it to res
-				argbuf += buf[:pos+1]
-				buf = buf[pos+1:]
-				pos = 0
-			} else {
-				// String found
-				in_string = true
-				pos++
-			}
+			// String start or end; the quotes are kept as part of the arg
+			// (e. g. key="value" stays key="value")
+			in_string = !in_string
+			argbuf += c
+			pos++
 			continue
 		}
 
 		if in_string {
+			argbuf += c
 			pos++
 			continue
 		}
 
-		if c == sep {
+		switch c {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+		}
+
+		if c == sep && depth <= 0 {
 			// seperator found, add new arg
 			res = append(res, argbuf)
 			argbuf = ""
@@ -76,3 +77,85 @@ func splitArgs(in *string, sep string) *[]string {
 
 	return &res
 }
+
+// splitOutside splits in by sep, but only if sep is neither part of a string
+// nor enclosed in parentheses or brackets. Like strings.Split, it returns
+// empty parts as well.
+func splitOutside(in string, sep byte) []string {
+	res := make([]string, 0, 3)
+
+	in_string := false
+	depth := 0
+	start := 0
+
+	for pos := 0; pos < len(in); pos++ {
+		c := in[pos]
+		if in_string {
+			if c == '\\' {
+				pos++ // skip escaped char
+			} else if c == '"' {
+				in_string = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			in_string = true
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				res = append(res, in[start:pos])
+				start = pos + 1
+			}
+		}
+	}
+
+	return append(res, in[start:])
+}
+
+// mentionsVar returns whether in (like the arguments of a tag) refers to the
+// variable name outside of strings, e. g. block in 'if block.super == ""'.
+func mentionsVar(in string, name string) bool {
+	is_ident_char := func(c byte) bool {
+		return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+	}
+
+	for pos := 0; pos < len(in); pos++ {
+		c := in[pos]
+		if c == '"' {
+			// Skip the string
+			for pos++; pos < len(in) && in[pos] != '"'; pos++ {
+				if in[pos] == '\\' {
+					pos++
+				}
+			}
+			continue
+		}
+		if !is_ident_char(c) {
+			continue
+		}
+		end := pos
+		for end < len(in) && is_ident_char(in[end]) {
+			end++
+		}
+		if in[pos:end] == name && (pos == 0 || in[pos-1] != '.') {
+			return true
+		}
+		pos = end - 1
+	}
+	return false
+}
+
+// indexOutside returns the index of the first sep which is neither part of a
+// string nor enclosed in parentheses or brackets (or -1 if there is none).
+func indexOutside(in string, sep byte) int {
+	parts := splitOutside(in, sep)
+	if len(parts) == 1 {
+		return -1
+	}
+	return len(parts[0])
+}
@@ -0,0 +1,470 @@
+package pongo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A Translator translates the messages of the trans- and blocktrans-tags and
+// of the _() function (see Catalog for gettext catalogs).
+type Translator interface {
+	Gettext(msgid string) string
+	NGettext(msgid, msgid_plural string, n int) string
+}
+
+// The Translator of an execution can be stored under this key in the Context.
+// Otherwise the Translator registered for the locale of the execution is
+// used (see LocaleKey and Translators).
+const TranslatorKey = "@translator"
+
+// Translators indexed by the name of their locale (see Locale.Name), like
+//     pongo.Translators["de"], err = pongo.CatalogFromFile("locale/de.po")
+// Register them before executing templates; they are not synchronized.
+var Translators = map[string]Translator{}
+
+// untranslated is used if there's no Translator; it returns the messages
+// unchanged.
+type untranslated struct{}
+
+func (untranslated) Gettext(msgid string) string {
+	return msgid
+}
+
+func (untranslated) NGettext(msgid, msgid_plural string, n int) string {
+	if defaultPluralRule(n) == 0 {
+		return msgid
+	}
+	return msgid_plural
+}
+
+// translatorOf returns the Translator of the execution.
+func translatorOf(ctx *Context) Translator {
+	if translator, is_translator := (*ctx)[TranslatorKey].(Translator); is_translator {
+		return translator
+	}
+	if translator := Translators[localeOf(ctx).Name]; translator != nil {
+		return translator
+	}
+	return untranslated{}
+}
+
+// translateFunc returns the _() function of the execution, like
+//     {{ _("Hello") }}
+func translateFunc(ctx *Context) func(string) string {
+	return translatorOf(ctx).Gettext
+}
+
+var translateFuncType = reflect.TypeOf(func(string) string { return "" })
+
+// transArgs contains the parsed arguments of a trans-tag. Syntax:
+//     {% trans <expr> [noop] %}
+type transArgs struct {
+	e    *expr
+	noop bool // the message is marked for translation, but not translated
+}
+
+func parseTransArgs(args string) (*transArgs, error) {
+	parts := make([]string, 0, 2)
+	for _, part := range *splitArgs(&args, " ") {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("Please provide a message to translate (like {% trans \"Hello\" %}).")
+	}
+
+	ta := &transArgs{}
+	if len(parts) > 1 && parts[len(parts)-1] == "noop" {
+		ta.noop = true
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > 1 {
+		return nil, errors.New(fmt.Sprintf("Unknown trans argument '%s'.", parts[1]))
+	}
+
+	e, err := newExpr(&parts[0])
+	if err != nil {
+		return nil, err
+	}
+	ta.e = e
+	return ta, nil
+}
+
+func tagTransPrepare(tn *tagNode, tpl *Template) error {
+	ta, err := parseTransArgs(tn.tagargs)
+	if err != nil {
+		return err
+	}
+	tpl.cache[fmt.Sprintf("trans_args_%s", tn.tagargs)] = ta
+	return nil
+}
+
+func tagTrans(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
+	var ta *transArgs
+	if _ta, has_args := execCtx.template.cache[fmt.Sprintf("trans_args_%s", *args)]; has_args {
+		ta = _ta.(*transArgs)
+	} else {
+		// Tag was not prepared (e. g. a custom Prepare-function is in place)
+		_ta, err := parseTransArgs(*args)
+		if err != nil {
+			return nil, err
+		}
+		ta = _ta
+	}
+	value, err := ta.e.evalValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg := stringValue(value)
+	if !ta.noop {
+		msg = translatorOf(ctx).Gettext(msg)
+	}
+	if execCtx.template.autosafe && !ta.e.isConstant() {
+		// Only messages given in the template itself are trusted
+		msg = escapeHTML(msg)
+	}
+	return &msg, nil
+}
+
+func tagTransIgnore(args *string, execCtx *executionContext) error {
+	return nil
+}
+
+// blocktransArgs contains the parsed arguments of a blocktrans-tag. Syntax:
+//     {% blocktrans [with key=<expr> ...] [count key=<expr>] [trimmed] %}
+type blocktransArgs struct {
+	with_keys  []string
+	with_exprs []*expr
+	count_key  string
+	count_expr *expr
+	trimmed    bool // whitespace at the beginning and end of lines is removed from the message
+}
+
+func parseBlocktransArgs(args string) (*blocktransArgs, error) {
+	ba := &blocktransArgs{}
+
+	mode := ""
+	for _, part := range *splitArgs(&args, " ") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+			continue
+		case "with", "count":
+			mode = part
+			continue
+		case "trimmed":
+			ba.trimmed = true
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if mode == "" || len(kv) != 2 || !exprIdentPartChecker.MatchString(kv[0]) {
+			return nil, errors.New(fmt.Sprintf("Blocktrans argument '%s' must be of the form key=<expr> (after 'with' or 'count').", part))
+		}
+		e, err := newExpr(&kv[1])
+		if err != nil {
+			return nil, err
+		}
+		if mode == "count" {
+			if ba.count_expr != nil {
+				return nil, errors.New("Blocktrans's 'count' takes exactly one key=<expr> argument.")
+			}
+			ba.count_key, ba.count_expr = kv[0], e
+		} else {
+			ba.with_keys = append(ba.with_keys, kv[0])
+			ba.with_exprs = append(ba.with_exprs, e)
+		}
+	}
+
+	return ba, nil
+}
+
+// trimMessage removes the whitespace at the beginning and end of all lines
+// and joins them by a space.
+func trimMessage(msg string) string {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// blocktransMessage builds the message ids of the blocktrans-tag at node
+// position pos (variables are replaced by %(name)s) and returns the position
+// of its end-tag.
+func blocktransMessage(tpl *Template, pos int, trimmed bool) (singular string, plural string, end int, err error) {
+	parts := make([]string, 0, 5)
+	has_plural := false
+
+	for end = pos + 1; end < len(tpl.nodes); end++ {
+		switch n := tpl.nodes[end].(type) {
+		case *contentNode:
+			parts = append(parts, strings.Replace(n.content, "%", "%%", -1))
+		case *filterNode:
+			if !exprIdentPartChecker.MatchString(n.content) {
+				return "", "", 0, errors.New(fmt.Sprintf("Blocktrans only allows simple variables, not '%s' (use 'with' to assign it to one).", n.content))
+			}
+			parts = append(parts, fmt.Sprintf("%%(%s)s", n.content))
+		case *tagNode:
+			switch {
+			case n.tagname == "plural" && !has_plural:
+				singular = strings.Join(parts, "")
+				parts = parts[:0]
+				has_plural = true
+			case n.tagname == "endblocktrans":
+				if has_plural {
+					plural = strings.Join(parts, "")
+				} else {
+					singular = strings.Join(parts, "")
+				}
+				if trimmed {
+					singular, plural = trimMessage(singular), trimMessage(plural)
+				}
+				return singular, plural, end, nil
+			default:
+				return "", "", 0, errors.New(fmt.Sprintf("Tag '%s' is not allowed within blocktrans.", n.tagname))
+			}
+		}
+	}
+
+	return "", "", 0, errors.New("No end-node (possible nodes: [endblocktrans]) found.")
+}
+
+// messageVars adds the names of the variables (like %(name)s) of msg to vars.
+func messageVars(msg string, vars map[string]bool) {
+	for {
+		idx := strings.IndexByte(msg, '%')
+		if idx < 0 || idx == len(msg)-1 {
+			return
+		}
+		msg = msg[idx:]
+		if msg[1] == '%' {
+			msg = msg[2:]
+			continue
+		}
+		end := strings.Index(msg, ")s")
+		if msg[1] != '(' || end < 0 {
+			msg = msg[1:]
+			continue
+		}
+		vars[msg[2:end]] = true
+		msg = msg[end+2:]
+	}
+}
+
+// interpolate replaces the variables (like %(name)s) of a translated message
+// by their values; %% is replaced by %. Only the variables of the original
+// message (allowed) can be used, so catalogs can't access anything else.
+func interpolate(msg string, allowed map[string]bool, ctx *Context, escape bool) (*string, error) {
+	parts := make([]string, 0, 5)
+	for {
+		idx := strings.IndexByte(msg, '%')
+		if idx < 0 || idx == len(msg)-1 {
+			break
+		}
+		parts = append(parts, msg[:idx])
+		msg = msg[idx:]
+
+		if msg[1] == '%' {
+			parts = append(parts, "%")
+			msg = msg[2:]
+			continue
+		}
+		end := strings.Index(msg, ")s")
+		if msg[1] != '(' || end < 0 {
+			parts = append(parts, "%")
+			msg = msg[1:]
+			continue
+		}
+
+		name := msg[2:end]
+		if !allowed[name] || !exprIdentPartChecker.MatchString(name) {
+			return nil, errors.New(fmt.Sprintf("The translation uses the unknown variable '%s'.", name))
+		}
+		value, err := resolveIdent(exprIdent(name), ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := sandboxOf(ctx).checkFormat(value, false); err != nil {
+			return nil, err
+		}
+		str := stringValue(value)
+		if escape {
+			str = escapeHTML(str)
+		}
+		parts = append(parts, str)
+		msg = msg[end+2:]
+	}
+	output := strings.Join(append(parts, msg), "")
+	return &output, nil
+}
+
+func tagBlocktransPrepare(tn *tagNode, tpl *Template) error {
+	ba, err := parseBlocktransArgs(tn.tagargs)
+	if err != nil {
+		return err
+	}
+	tpl.cache[fmt.Sprintf("blocktrans_args_%s", tn.tagargs)] = ba
+	return nil
+}
+
+func tagBlocktrans(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
+	var ba *blocktransArgs
+	if _ba, has_args := execCtx.template.cache[fmt.Sprintf("blocktrans_args_%s", *args)]; has_args {
+		ba = _ba.(*blocktransArgs)
+	} else {
+		// Tag was not prepared (e. g. a custom Prepare-function is in place)
+		_ba, err := parseBlocktransArgs(*args)
+		if err != nil {
+			return nil, err
+		}
+		ba = _ba
+	}
+	singular, plural, end, err := blocktransMessage(execCtx.template, execCtx.node_pos, ba.trimmed)
+	if err != nil {
+		return nil, err
+	}
+	execCtx.node_pos = end
+
+	// The variables are resolved in a copy of the Context
+	vars_ctx := make(Context, len(*ctx)+len(ba.with_keys)+1)
+	for k, v := range *ctx {
+		vars_ctx[k] = v
+	}
+	for idx, key := range ba.with_keys {
+		value, err := ba.with_exprs[idx].evalValue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		vars_ctx[key] = value
+	}
+
+	translator := translatorOf(ctx)
+	var msg string
+	if ba.count_expr != nil {
+		if plural == "" {
+			return nil, errors.New("Blocktrans with 'count' requires a {% plural %} tag.")
+		}
+		count, err := ba.count_expr.evalValue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		n, _, _, is_number := numericValue(count)
+		if !is_number {
+			return nil, errors.New(fmt.Sprintf("Blocktrans's count must be a number, not %T ('%v').", count, count))
+		}
+		vars_ctx[ba.count_key] = count
+		msg = translator.NGettext(singular, plural, int(n))
+	} else {
+		if plural != "" {
+			return nil, errors.New("The {% plural %} tag requires blocktrans with 'count'.")
+		}
+		msg = translator.Gettext(singular)
+	}
+
+	allowed := make(map[string]bool)
+	messageVars(singular, allowed)
+	messageVars(plural, allowed)
+	return interpolate(msg, allowed, &vars_ctx, execCtx.template.autosafe)
+}
+
+func tagBlocktransIgnore(args *string, execCtx *executionContext) error {
+	_, err := execCtx.ignoreUntilAnyTagNode("endblocktrans")
+	return err
+}
+
+func checkTrans(c *checker, tn *tagNode) {
+	ta, err := parseTransArgs(tn.tagargs)
+	if err != nil {
+		c.report(tn, err)
+		return
+	}
+	if _, err := c.exprType(ta.e); err != nil {
+		c.report(tn, err)
+	}
+}
+
+func checkBlocktrans(c *checker, tn *tagNode) {
+	ba, err := parseBlocktransArgs(tn.tagargs)
+	if err != nil {
+		c.report(tn, err)
+		return
+	}
+	if _, _, _, err := blocktransMessage(c.tpl, c.pos, ba.trimmed); err != nil {
+		c.report(tn, err)
+	}
+
+	scope := make(map[string]reflect.Type, len(ba.with_keys)+1)
+	for idx, key := range ba.with_keys {
+		t, err := c.exprType(ba.with_exprs[idx])
+		if err != nil {
+			c.report(tn, err)
+		}
+		scope[key] = t
+	}
+	if ba.count_expr != nil {
+		t, err := c.exprType(ba.count_expr)
+		if err != nil {
+			c.report(tn, err)
+		}
+		scope[ba.count_key] = t
+	}
+
+	c.pushScope(scope)
+	c.checkUntilAnyTagNode("endblocktrans")
+	c.popScope()
+}
+
+// An ExtractedMessage is a translatable message of a template (see Messages).
+type ExtractedMessage struct {
+	ID     string
+	Plural string // the plural message of a blocktrans-tag with count
+	Line   int
+}
+
+var re_translate_call = regexp.MustCompile(`(^|[^A-Za-z0-9_.])_\(\s*"([^"]*)"\s*\)`)
+
+// Returns the messages of the template's trans- and blocktrans-tags and its
+// _("...") calls (in the order of their appearance), e. g. to create a
+// gettext catalog (see the extract-command of cmd/pongo). Messages which are
+// only known at runtime (like {% trans name %}) are skipped.
+func (tpl *Template) Messages() ([]ExtractedMessage, error) {
+	messages := make([]ExtractedMessage, 0, 10)
+	for pos := 0; pos < len(tpl.nodes); pos++ {
+		n := tpl.nodes[pos]
+		tn, is_tag := n.(*tagNode)
+		switch {
+		case is_tag && tn.tagname == "trans":
+			ta, err := parseTransArgs(tn.tagargs)
+			if err != nil {
+				return nil, tpl.nodeError(tn, err.Error())
+			}
+			if msgid, is_str := ta.e.root.(string); is_str && len(ta.e.filters) == 0 {
+				messages = append(messages, ExtractedMessage{ID: msgid, Line: tn.line})
+			}
+			continue
+		case is_tag && tn.tagname == "blocktrans":
+			ba, err := parseBlocktransArgs(tn.tagargs)
+			if err != nil {
+				return nil, tpl.nodeError(tn, err.Error())
+			}
+			singular, plural, _, err := blocktransMessage(tpl, pos, ba.trimmed)
+			if err != nil {
+				return nil, tpl.nodeError(tn, err.Error())
+			}
+			messages = append(messages, ExtractedMessage{ID: singular, Plural: plural, Line: tn.line})
+		}
+
+		// _() calls in expressions and tag arguments
+		if _, is_content := n.(*contentNode); !is_content {
+			for _, match := range re_translate_call.FindAllStringSubmatch(*n.getContent(), -1) {
+				messages = append(messages, ExtractedMessage{ID: match[2], Line: n.getLine()})
+			}
+		}
+	}
+	return messages, nil
+}
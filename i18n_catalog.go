@@ -0,0 +1,446 @@
+package pongo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A PluralRule returns the index of the plural form used for n items.
+type PluralRule func(n int) int
+
+// Plural rule of catalogs without a Plural-Forms header (like English).
+func defaultPluralRule(n int) int {
+	if n != 1 {
+		return 1
+	}
+	return 0
+}
+
+// A Catalog is a Translator using the messages of a gettext .po or .mo file.
+// Messages which aren't translated (or are marked as fuzzy) are returned
+// untranslated. Messages with a context (msgctxt) are skipped, templates
+// can't specify one.
+type Catalog struct {
+	messages map[string][]string // msgid -> translation or plural forms
+	plural   PluralRule
+}
+
+func newCatalog() *Catalog {
+	return &Catalog{
+		messages: make(map[string][]string),
+		plural:   defaultPluralRule,
+	}
+}
+
+func (c *Catalog) Gettext(msgid string) string {
+	if forms, has_msg := c.messages[msgid]; has_msg && forms[0] != "" {
+		return forms[0]
+	}
+	return msgid
+}
+
+func (c *Catalog) NGettext(msgid, msgid_plural string, n int) string {
+	idx := c.plural(n)
+	if forms, has_msg := c.messages[msgid]; has_msg && idx >= 0 && idx < len(forms) && forms[idx] != "" {
+		return forms[idx]
+	}
+	if defaultPluralRule(n) == 0 {
+		return msgid
+	}
+	return msgid_plural
+}
+
+// Creates a catalog from a .po or a .mo file (depending on the extension).
+func CatalogFromFile(file_path string) (*Catalog, error) {
+	data, err := ioutil.ReadFile(file_path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(file_path)) == ".mo" {
+		return ParseMO(data)
+	}
+	return ParsePO(data)
+}
+
+// addMessage adds a message; the header (empty msgid) sets the plural rule.
+func (c *Catalog) addMessage(msgctxt string, msgid string, forms []string) error {
+	if msgctxt != "" {
+		return nil
+	}
+	if msgid == "" {
+		return c.parseHeader(forms[0])
+	}
+	c.messages[msgid] = forms
+	return nil
+}
+
+// parseHeader parses the plural rule of the Plural-Forms header, like
+//     Plural-Forms: nplurals=2; plural=(n != 1);
+func (c *Catalog) parseHeader(header string) error {
+	for _, line := range strings.Split(header, "\n") {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(strings.ToLower(kv[0])) != "plural-forms" {
+			continue
+		}
+		for _, field := range strings.Split(kv[1], ";") {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "plural=") {
+				rule, err := ParsePluralRule(field[len("plural="):])
+				if err != nil {
+					return err
+				}
+				c.plural = rule
+			}
+		}
+	}
+	return nil
+}
+
+// poEntry collects the keywords of an entry of a .po file.
+type poEntry struct {
+	fuzzy   bool
+	msgctxt string
+	msgid   *string
+	msgstr  []string
+	last    *string // string continued by the following quoted lines
+}
+
+// Parses the content of a gettext .po file.
+func ParsePO(data []byte) (*Catalog, error) {
+	c := newCatalog()
+	entry := &poEntry{}
+
+	// flush adds the current entry to the catalog (if it's complete)
+	flush := func() error {
+		defer func() { entry = &poEntry{} }()
+		if entry.msgid == nil || entry.msgstr == nil || (entry.fuzzy && *entry.msgid != "") {
+			return nil
+		}
+		return c.addMessage(entry.msgctxt, *entry.msgid, entry.msgstr)
+	}
+
+	for idx, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		line_error := func(msg string) error {
+			return errors.New(fmt.Sprintf("[PO error] [Line %d] %s", idx+1, msg))
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if entry.msgstr != nil {
+				if err := flush(); err != nil {
+					return nil, line_error(err.Error())
+				}
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				entry.fuzzy = true
+			}
+			continue
+		}
+
+		keyword := ""
+		if !strings.HasPrefix(line, "\"") {
+			space := strings.IndexByte(line, ' ')
+			if space < 0 {
+				return nil, line_error(fmt.Sprintf("Expected a keyword and a string, got '%s'.", line))
+			}
+			keyword, line = line[:space], strings.TrimSpace(line[space:])
+		}
+		str, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, line_error(fmt.Sprintf("Invalid string %s.", line))
+		}
+
+		if (keyword == "msgctxt" || keyword == "msgid") && entry.msgstr != nil {
+			// A new entry starts
+			if err := flush(); err != nil {
+				return nil, line_error(err.Error())
+			}
+		}
+
+		switch {
+		case keyword == "":
+			if entry.last == nil {
+				return nil, line_error("String without a keyword.")
+			}
+			*entry.last += str
+		case keyword == "msgctxt":
+			entry.msgctxt = str
+			entry.last = &entry.msgctxt
+		case keyword == "msgid":
+			entry.msgid = &str
+			entry.last = entry.msgid
+		case keyword == "msgid_plural":
+			entry.last = &str // lookups only use the singular msgid
+		case keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr["):
+			if entry.msgid == nil {
+				return nil, line_error("msgstr without msgid.")
+			}
+			form := 0
+			if keyword != "msgstr" {
+				form, err = strconv.Atoi(strings.TrimSuffix(keyword[len("msgstr["):], "]"))
+				if err != nil || form < 0 || form > 100 || !strings.HasSuffix(keyword, "]") {
+					return nil, line_error(fmt.Sprintf("Invalid keyword '%s'.", keyword))
+				}
+			}
+			for len(entry.msgstr) <= form {
+				entry.msgstr = append(entry.msgstr, "")
+			}
+			entry.msgstr[form] = str
+			entry.last = &entry.msgstr[form]
+		default:
+			return nil, line_error(fmt.Sprintf("Unknown keyword '%s'.", keyword))
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, errors.New(fmt.Sprintf("[PO error] %s", err))
+	}
+	return c, nil
+}
+
+// Parses the content of a gettext .mo file.
+func ParseMO(data []byte) (*Catalog, error) {
+	if len(data) < 20 {
+		return nil, errors.New("[MO error] File too short.")
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(data) {
+	case 0x950412de:
+		order = binary.LittleEndian
+	case 0xde120495:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("[MO error] Invalid magic number.")
+	}
+
+	count := int(order.Uint32(data[8:]))
+	originals := int(order.Uint32(data[12:]))
+	translations := int(order.Uint32(data[16:]))
+
+	// str returns the string described by the idx-th entry of the table at offset
+	str := func(table, idx int) (string, error) {
+		pos := table + idx*8
+		if pos < 0 || pos+8 > len(data) {
+			return "", errors.New("[MO error] String table out of range.")
+		}
+		length := int(order.Uint32(data[pos:]))
+		offset := int(order.Uint32(data[pos+4:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return "", errors.New("[MO error] String out of range.")
+		}
+		return string(data[offset : offset+length]), nil
+	}
+
+	c := newCatalog()
+	for i := 0; i < count; i++ {
+		original, err := str(originals, i)
+		if err != nil {
+			return nil, err
+		}
+		translation, err := str(translations, i)
+		if err != nil {
+			return nil, err
+		}
+
+		msgctxt := ""
+		if idx := strings.IndexByte(original, '\x04'); idx >= 0 {
+			msgctxt, original = original[:idx], original[idx+1:]
+		}
+		// The plural form follows the msgid (separated by a NUL byte)
+		msgid := strings.SplitN(original, "\x00", 2)[0]
+		if err := c.addMessage(msgctxt, msgid, strings.Split(translation, "\x00")); err != nil {
+			return nil, errors.New(fmt.Sprintf("[MO error] %s", err))
+		}
+	}
+	return c, nil
+}
+
+// pluralParser parses a plural expression of a Plural-Forms header (a C
+// expression of n, like "n==1 ? 0 : n%10>=2 && n%10<=4 ? 1 : 2").
+type pluralParser struct {
+	in  string
+	pos int
+}
+
+// Parses the plural expression of a gettext Plural-Forms header.
+func ParsePluralRule(expression string) (PluralRule, error) {
+	p := &pluralParser{in: expression}
+	rule, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.in) {
+		return nil, errors.New(fmt.Sprintf("Invalid plural expression '%s' (unexpected '%s').", expression, p.in[p.pos:]))
+	}
+	return PluralRule(rule), nil
+}
+
+func (p *pluralParser) skipSpaces() {
+	for p.pos < len(p.in) && (p.in[p.pos] == ' ' || p.in[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// accept consumes one of the operators (the longest matching one).
+func (p *pluralParser) accept(ops ...string) (string, bool) {
+	p.skipSpaces()
+	for _, op := range ops {
+		if strings.HasPrefix(p.in[p.pos:], op) {
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *pluralParser) error() error {
+	return errors.New(fmt.Sprintf("Invalid plural expression '%s' (at position %d).", p.in, p.pos))
+}
+
+func (p *pluralParser) parseTernary() (func(int) int, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, is_ternary := p.accept("?"); !is_ternary {
+		return cond, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if _, has_else := p.accept(":"); !has_else {
+		return nil, p.error()
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return func(n int) int {
+		if cond(n) != 0 {
+			return then(n)
+		}
+		return otherwise(n)
+	}, nil
+}
+
+// Binary operators by precedence (lowest first); longer operators are listed
+// before their prefixes.
+var plural_operators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *pluralParser) parseBinary(level int) (func(int) int, error) {
+	if level == len(plural_operators) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, has_op := p.accept(plural_operators[level]...)
+		if !has_op {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		switch op {
+		case "||":
+			left = func(n int) int { return boolInt(l(n) != 0 || right(n) != 0) }
+		case "&&":
+			left = func(n int) int { return boolInt(l(n) != 0 && right(n) != 0) }
+		case "==":
+			left = func(n int) int { return boolInt(l(n) == right(n)) }
+		case "!=":
+			left = func(n int) int { return boolInt(l(n) != right(n)) }
+		case "<=":
+			left = func(n int) int { return boolInt(l(n) <= right(n)) }
+		case ">=":
+			left = func(n int) int { return boolInt(l(n) >= right(n)) }
+		case "<":
+			left = func(n int) int { return boolInt(l(n) < right(n)) }
+		case ">":
+			left = func(n int) int { return boolInt(l(n) > right(n)) }
+		case "+":
+			left = func(n int) int { return l(n) + right(n) }
+		case "-":
+			left = func(n int) int { return l(n) - right(n) }
+		case "*":
+			left = func(n int) int { return l(n) * right(n) }
+		case "/", "%":
+			is_div := op == "/"
+			left = func(n int) int {
+				r := right(n)
+				if r == 0 {
+					return 0
+				}
+				if is_div {
+					return l(n) / r
+				}
+				return l(n) % r
+			}
+		}
+	}
+}
+
+func (p *pluralParser) parseUnary() (func(int) int, error) {
+	if _, is_not := p.accept("!"); is_not {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n int) int { return boolInt(operand(n) == 0) }, nil
+	}
+	if _, is_paren := p.accept("("); is_paren {
+		inner, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if _, closed := p.accept(")"); !closed {
+			return nil, p.error()
+		}
+		return inner, nil
+	}
+	if _, is_n := p.accept("n"); is_n {
+		return func(n int) int { return n }, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.in) && p.in[p.pos] >= '0' && p.in[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, p.error()
+	}
+	value, err := strconv.Atoi(p.in[start:p.pos])
+	if err != nil {
+		return nil, p.error()
+	}
+	return func(n int) int { return value }, nil
+}
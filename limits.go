@@ -0,0 +1,141 @@
+package pongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Maximum nesting depth of extends/include tags during execution (protects
+// against endless recursive includes); can be lowered per template by
+// Limits.MaxDepth.
+var MaxTemplateDepth = 100
+
+// Limits restrict the execution of a template (see SetLimits). A zero value
+// means there is no limit.
+type Limits struct {
+	MaxOutput     int           // maximum size of the output in bytes
+	MaxIterations int           // maximum number of iterations of all for-loops
+	MaxDepth      int           // maximum nesting depth of extends/include tags (defaults to MaxTemplateDepth)
+	Timeout       time.Duration // maximum duration of the execution
+}
+
+// A LimitError is returned if an execution exceeds one of its Limits.
+type LimitError struct {
+	Limit string      // name of the exceeded limit, like "MaxOutput"
+	Max   interface{} // the limit's value
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "MaxDepth" {
+		return fmt.Sprintf("Maximum template depth of %v reached (endless recursive include?).", e.Max)
+	}
+	return fmt.Sprintf("Limit %s of %v exceeded.", e.Limit, e.Max)
+}
+
+// Sets the limits for every execution of this template (including the
+// templates it extends or includes).
+func (tpl *Template) SetLimits(limits Limits) {
+	tpl.limits = limits
+}
+
+// renderState is shared by all execution contexts of a single execution; it
+// tracks the limits and the cancellation of the execution.
+type renderState struct {
+	go_ctx     context.Context
+	limits     Limits
+	deadline   time.Time
+	output     int
+	iterations int
+}
+
+// newRenderState returns nil if the execution neither can be cancelled nor
+// is limited.
+func (tpl *Template) newRenderState(go_ctx context.Context) *renderState {
+	if go_ctx == nil && tpl.limits == (Limits{}) {
+		return nil
+	}
+	if go_ctx == nil {
+		go_ctx = context.Background()
+	}
+	state := &renderState{
+		go_ctx: go_ctx,
+		limits: tpl.limits,
+	}
+	if tpl.limits.Timeout > 0 {
+		state.deadline = time.Now().Add(tpl.limits.Timeout)
+	}
+	return state
+}
+
+// check returns an error if the execution was cancelled or timed out. It's
+// called before every node is executed.
+func (s *renderState) check() error {
+	if s == nil {
+		return nil
+	}
+	if err := s.go_ctx.Err(); err != nil {
+		return err
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		return &LimitError{Limit: "Timeout", Max: s.limits.Timeout}
+	}
+	return nil
+}
+
+// write records n bytes of output.
+func (s *renderState) write(n int) error {
+	if s == nil {
+		return nil
+	}
+	s.output += n
+	if s.limits.MaxOutput > 0 && s.output > s.limits.MaxOutput {
+		return &LimitError{Limit: "MaxOutput", Max: s.limits.MaxOutput}
+	}
+	return nil
+}
+
+// reserve returns an error if n more bytes of output would exceed MaxOutput;
+// it's used by filters to reject results which can't be written anyway.
+func (s *renderState) reserve(n int) error {
+	if s != nil && s.limits.MaxOutput > 0 && n > s.limits.MaxOutput-s.output {
+		return &LimitError{Limit: "MaxOutput", Max: s.limits.MaxOutput}
+	}
+	return nil
+}
+
+// iterate records a loop iteration.
+func (s *renderState) iterate() error {
+	if s == nil {
+		return nil
+	}
+	s.iterations++
+	if s.limits.MaxIterations > 0 && s.iterations > s.limits.MaxIterations {
+		return &LimitError{Limit: "MaxIterations", Max: s.limits.MaxIterations}
+	}
+	return s.check()
+}
+
+// enterRenderState stores the renderState of the execution in ctx and returns
+// a function restoring ctx.
+func (execCtx *executionContext) enterRenderState(ctx *Context) func() {
+	if execCtx.state == nil {
+		return func() {}
+	}
+	outer, has_outer := (*ctx)[renderStateKey]
+	(*ctx)[renderStateKey] = execCtx.state
+	return func() {
+		if has_outer {
+			(*ctx)[renderStateKey] = outer
+		} else {
+			delete(*ctx, renderStateKey)
+		}
+	}
+}
+
+func (s *renderState) maxDepth() int {
+	if s != nil && s.limits.MaxDepth > 0 {
+		return s.limits.MaxDepth
+	}
+	return MaxTemplateDepth
+}
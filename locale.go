@@ -0,0 +1,224 @@
+package pongo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The locale of an execution can be stored under this key in the Context,
+// either as a name (like "de" or "fr_CH") or as *Locale. It overrides the
+// locale set by Template.SetLocale; unknown names are ignored.
+const LocaleKey = "@locale"
+
+// A Locale contains the data used by the locale-aware filters (floatformat,
+// intcomma, currency and the date filters). The data follows CLDR.
+type Locale struct {
+	Name string // like "de"
+
+	DecimalSeparator string
+	GroupSeparator   string
+
+	Months      [12]string
+	ShortMonths [12]string
+	Days        [7]string // starting with Sunday (like time.Weekday)
+	ShortDays   [7]string
+
+	// Default formats of the date and time filters (Django's format
+	// characters, like "j. F Y")
+	DateFormat string
+	TimeFormat string
+
+	// Currency format, "¤" is replaced by the currency's symbol and "#" by
+	// the number (like "# ¤")
+	CurrencyPattern string
+	Currency        string // ISO 4217 code of the default currency (like "EUR")
+}
+
+// Bundled locales, indexed by their lowercased names. Add your own (or change
+// the bundled ones) before executing templates; they are not synchronized.
+var Locales = map[string]*Locale{
+	"en": {
+		Name:             "en",
+		DecimalSeparator: ".",
+		GroupSeparator:   ",",
+		Months:           [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		ShortMonths:      [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Days:             [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		ShortDays:        [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		DateFormat:       "N j, Y",
+		TimeFormat:       "P",
+		CurrencyPattern:  "¤#",
+		Currency:         "USD",
+	},
+	"de": {
+		Name:             "de",
+		DecimalSeparator: ",",
+		GroupSeparator:   ".",
+		Months:           [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths:      [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		Days:             [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:        [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		DateFormat:       "j. F Y",
+		TimeFormat:       "H:i",
+		CurrencyPattern:  "#\u00a0¤",
+		Currency:         "EUR",
+	},
+	"fr": {
+		Name:             "fr",
+		DecimalSeparator: ",",
+		GroupSeparator:   "\u202f",
+		Months:           [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths:      [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:             [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays:        [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		DateFormat:       "j F Y",
+		TimeFormat:       "H:i",
+		CurrencyPattern:  "#\u00a0¤",
+		Currency:         "EUR",
+	},
+}
+
+// Used if neither the template nor the Context specify a locale.
+const defaultLocale = "en"
+
+type currencyInfo struct {
+	symbol string
+	digits int // number of decimal places
+}
+
+var currencies = map[string]currencyInfo{
+	"EUR": {"€", 2},
+	"USD": {"$", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"CHF": {"CHF", 2},
+}
+
+// findLocale returns the locale with the given name; for names with a region
+// (like "de_AT" or "de-AT") the language's locale is used if there's none for
+// the region.
+func findLocale(name string) (*Locale, bool) {
+	name = strings.ToLower(strings.Replace(name, "-", "_", -1))
+	if locale, has_locale := Locales[name]; has_locale {
+		return locale, true
+	}
+	if idx := strings.IndexByte(name, '_'); idx > 0 {
+		locale, has_locale := Locales[name[:idx]]
+		return locale, has_locale
+	}
+	return nil, false
+}
+
+// Sets the locale used by the locale-aware filters for every execution of
+// this template (unless the Context contains a locale, see LocaleKey).
+func (tpl *Template) SetLocale(name string) error {
+	if _, has_locale := findLocale(name); !has_locale {
+		return errors.New(fmt.Sprintf("Locale '%s' not found.", name))
+	}
+	tpl.locale = name
+	return nil
+}
+
+// localeOf returns the locale of the execution.
+func localeOf(ctx *Context) *Locale {
+	switch locale := (*ctx)[LocaleKey].(type) {
+	case *Locale:
+		return locale
+	case string:
+		if l, has_locale := findLocale(locale); has_locale {
+			return l
+		}
+	}
+	return Locales[defaultLocale]
+}
+
+// enterLocale stores the template's locale in ctx (unless it contains one
+// already) and returns a function restoring ctx.
+func (tpl *Template) enterLocale(ctx *Context) func() {
+	if tpl.locale == "" {
+		return func() {}
+	}
+	if _, has_locale := (*ctx)[LocaleKey]; has_locale {
+		return func() {}
+	}
+	(*ctx)[LocaleKey] = tpl.locale
+	return func() {
+		delete(*ctx, LocaleKey)
+	}
+}
+
+// formatNumber localizes a formatted number (like "-1234.5"); the integer
+// part is grouped if group is set.
+func (l *Locale) formatNumber(str string, group bool) string {
+	if group {
+		return groupDigits(str, l.GroupSeparator, l.DecimalSeparator)
+	}
+	return strings.Replace(str, ".", l.DecimalSeparator, 1)
+}
+
+// formatCurrency formats amount in the given currency (ISO 4217 code).
+func (l *Locale) formatCurrency(amount float64, code string) string {
+	info, has_info := currencies[code]
+	if !has_info {
+		info = currencyInfo{code, 2}
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	number := l.formatNumber(fmt.Sprintf("%.*f", info.digits, amount), true)
+	formatted := strings.Replace(l.CurrencyPattern, "#", number, 1)
+	return sign + strings.Replace(formatted, "¤", info.symbol, 1)
+}
+
+// formatTime is like t.Format(layout), but uses the locale's names of months
+// and days.
+func (l *Locale) formatTime(t time.Time, layout string) string {
+	names := []struct {
+		std  string
+		name string
+	}{
+		{"January", l.Months[t.Month()-1]},
+		{"Monday", l.Days[t.Weekday()]},
+		{"Jan", l.ShortMonths[t.Month()-1]},
+		{"Mon", l.ShortDays[t.Weekday()]},
+	}
+
+	parts := make([]string, 0, 5)
+	start := 0
+outer:
+	for pos := 0; pos < len(layout); pos++ {
+		for _, n := range names {
+			if strings.HasPrefix(layout[pos:], n.std) {
+				parts = append(parts, t.Format(layout[start:pos]), n.name)
+				pos += len(n.std) - 1
+				start = pos + 1
+				continue outer
+			}
+		}
+	}
+	parts = append(parts, t.Format(layout[start:]))
+	return strings.Join(parts, "")
+}
+
+// filterCurrency formats a number as an amount of money in the locale's
+// currency or in the given one (ISO 4217 code):
+//
+//     {{ 1234.5|currency }} displays 1.234,50 € (in German)
+//     {{ 1234.5|currency:"USD" }} displays $1,234.50 (in English)
+func filterCurrency(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	amount, err := numberValue(value)
+	if err != nil {
+		return nil, err
+	}
+	locale := ctx.Locale()
+	code := locale.Currency
+	if len(args) > 1 {
+		return nil, errors.New("currency takes at most one argument.")
+	} else if len(args) == 1 {
+		code = strings.ToUpper(stringValue(args[0]))
+	}
+	return locale.formatCurrency(amount, code), nil
+}
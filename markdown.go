@@ -0,0 +1,1144 @@
+package pongo
+
+// A CommonMark renderer for the markdown filter. It supports the block
+// structure (headings, thematic breaks, code blocks, HTML blocks, block
+// quotes, lists and link reference definitions) and the inline syntax
+// (emphasis, code spans, links, images, autolinks, raw HTML and line
+// breaks) of CommonMark; tables and other extensions are not supported.
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type mdBlockKind int
+
+const (
+	mdParagraph mdBlockKind = iota
+	mdHeading
+	mdCode
+	mdHTML
+	mdThematicBreak
+	mdQuote
+	mdList
+	mdItem
+)
+
+type mdBlock struct {
+	kind     mdBlockKind
+	lines    []string // contents of paragraphs, headings, code and HTML blocks
+	level    int      // of headings
+	info     string   // info string of fenced code blocks
+	children []*mdBlock
+
+	// lists
+	ordered bool
+	start   int
+	loose   bool
+}
+
+type mdLinkRef struct {
+	url   string
+	title string
+}
+
+// mdParser parses the block structure of a document and renders it.
+type mdParser struct {
+	trusted bool // raw HTML is kept (otherwise it's escaped)
+	refs    map[string]mdLinkRef
+}
+
+var (
+	re_md_atx_heading   = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	re_md_setext        = regexp.MustCompile(`^(=+|-+)[ \t]*$`)
+	re_md_thematic      = regexp.MustCompile(`^(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	re_md_fence         = regexp.MustCompile("^(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	re_md_bullet        = regexp.MustCompile(`^([-+*])([ \t]|$)`)
+	re_md_ordered       = regexp.MustCompile(`^([0-9]{1,9})([.)])([ \t]|$)`)
+	re_md_html_raw      = regexp.MustCompile(`(?i)^<(script|pre|style|textarea)(?:[ \t>]|$)`)
+	re_md_html_raw_end  = regexp.MustCompile(`(?i)</(script|pre|style|textarea)>`)
+	re_md_html_block    = regexp.MustCompile(`(?i)^</?(address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[1-6]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:[ \t]|/?>|$)`)
+	re_md_html_tag_line = regexp.MustCompile(`^(?:` + md_open_tag + `|` + md_closing_tag + `)[ \t]*$`)
+)
+
+// expandTabs replaces tabs by spaces (tab stops every 4 columns).
+func expandTabs(line string) string {
+	if strings.IndexByte(line, '\t') < 0 {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 4 - col%4
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		sb.WriteRune(r)
+		col++
+	}
+	return sb.String()
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func leadingSpaces(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// listMarker returns the marker of a list item at the beginning of line
+// (without indentation): its length, the bullet character or delimiter
+// of ordered lists and the start number.
+func listMarker(line string) (length int, delim byte, ordered bool, start int, ok bool) {
+	if m := re_md_bullet.FindStringSubmatch(line); m != nil {
+		return 1, m[1][0], false, 0, true
+	}
+	if m := re_md_ordered.FindStringSubmatch(line); m != nil {
+		start, _ = strconv.Atoi(m[1])
+		return len(m[1]) + 1, m[2][0], true, start, true
+	}
+	return 0, 0, false, 0, false
+}
+
+// startsBlock returns whether line starts a block which interrupts a
+// paragraph.
+func (p *mdParser) startsBlock(line string) bool {
+	if leadingSpaces(line) >= 4 {
+		return false
+	}
+	s := strings.TrimLeft(line, " ")
+	if re_md_atx_heading.MatchString(s) || re_md_thematic.MatchString(s) || re_md_fence.MatchString(s) || strings.HasPrefix(s, ">") {
+		return true
+	}
+	if _, _, ordered, start, ok := listMarker(s); ok && (!ordered || start == 1) && !isBlank(s[1:]) {
+		return true
+	}
+	_, is_html := p.htmlBlockEnd(s, true)
+	return p.trusted && is_html
+}
+
+// htmlBlockEnd returns whether line starts an HTML block and a function
+// checking whether a line ends it (nil if it ends before a blank line).
+// Blocks of the kind which can't interrupt a paragraph are only recognized
+// if interrupt is false.
+func (p *mdParser) htmlBlockEnd(line string, interrupt bool) (func(string) bool, bool) {
+	contains := func(end string) func(string) bool {
+		return func(l string) bool { return strings.Contains(l, end) }
+	}
+	switch {
+	case re_md_html_raw.MatchString(line):
+		return re_md_html_raw_end.MatchString, true
+	case strings.HasPrefix(line, "<!--"):
+		return contains("-->"), true
+	case strings.HasPrefix(line, "<?"):
+		return contains("?>"), true
+	case strings.HasPrefix(line, "<![CDATA["):
+		return contains("]]>"), true
+	case strings.HasPrefix(line, "<!") && len(line) > 2 && isASCIILetter(line[2]):
+		return contains(">"), true
+	case re_md_html_block.MatchString(line), !interrupt && re_md_html_tag_line.MatchString(line):
+		return nil, true
+	}
+	return nil, false
+}
+
+// parseBlocks parses the lines of a container (the document, a block quote or
+// a list item). blank_between is set if blank lines separate its blocks.
+func (p *mdParser) parseBlocks(lines []string) (blocks []*mdBlock, blank_between bool) {
+	var para *mdBlock // the open paragraph
+	saw_blank := false
+	add := func(b *mdBlock) {
+		if saw_blank && len(blocks) > 0 {
+			blank_between = true
+		}
+		saw_blank = false
+		blocks = append(blocks, b)
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			para = nil
+			saw_blank = true
+			i++
+			continue
+		}
+		indent := leadingSpaces(line)
+
+		// Indented code block
+		if indent >= 4 && para == nil {
+			code := &mdBlock{kind: mdCode}
+			for ; i < len(lines) && (isBlank(lines[i]) || leadingSpaces(lines[i]) >= 4); i++ {
+				if isBlank(lines[i]) && len(lines[i]) <= 4 {
+					code.lines = append(code.lines, "")
+				} else {
+					code.lines = append(code.lines, lines[i][4:])
+				}
+			}
+			for len(code.lines) > 0 && isBlank(code.lines[len(code.lines)-1]) {
+				code.lines = code.lines[:len(code.lines)-1]
+				i--
+			}
+			add(code)
+			continue
+		}
+
+		if indent < 4 {
+			s := line[indent:]
+
+			// Fenced code block
+			if m := re_md_fence.FindStringSubmatch(s); m != nil {
+				fence := m[1]
+				code := &mdBlock{kind: mdCode, info: html.UnescapeString(m[2])}
+				for i++; i < len(lines); i++ {
+					l := lines[i]
+					if leadingSpaces(l) < 4 {
+						trimmed := strings.TrimSpace(l)
+						if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+							i++
+							break
+						}
+					}
+					// Remove the indentation of the fence
+					strip := indent
+					if n := leadingSpaces(l); n < strip {
+						strip = n
+					}
+					code.lines = append(code.lines, l[strip:])
+				}
+				para = nil
+				add(code)
+				continue
+			}
+
+			// ATX heading
+			if m := re_md_atx_heading.FindStringSubmatch(s); m != nil {
+				para = nil
+				add(&mdBlock{kind: mdHeading, level: len(m[1]), lines: []string{m[2]}})
+				i++
+				continue
+			}
+
+			// Setext heading
+			if m := re_md_setext.FindStringSubmatch(s); m != nil && para != nil {
+				para.kind = mdHeading
+				para.level = 1
+				if m[1][0] == '-' {
+					para.level = 2
+				}
+				para = nil
+				i++
+				continue
+			}
+
+			// Thematic break
+			if re_md_thematic.MatchString(s) {
+				para = nil
+				add(&mdBlock{kind: mdThematicBreak})
+				i++
+				continue
+			}
+
+			// Block quote
+			if strings.HasPrefix(s, ">") {
+				quote_lines := make([]string, 0, 5)
+				for ; i < len(lines); i++ {
+					l := lines[i]
+					if n := leadingSpaces(l); n < 4 && strings.HasPrefix(l[n:], ">") {
+						l = l[n+1:]
+						if strings.HasPrefix(l, " ") {
+							l = l[1:]
+						}
+						quote_lines = append(quote_lines, l)
+						continue
+					}
+					// Lazy continuation of a paragraph
+					if isBlank(l) || len(quote_lines) == 0 || isBlank(quote_lines[len(quote_lines)-1]) || p.startsBlock(l) {
+						break
+					}
+					quote_lines = append(quote_lines, l)
+				}
+				children, _ := p.parseBlocks(quote_lines)
+				para = nil
+				add(&mdBlock{kind: mdQuote, children: children})
+				continue
+			}
+
+			// List
+			if length, _, ordered, start, ok := listMarker(s); ok {
+				if para == nil || ((!ordered || start == 1) && !isBlank(s[length:])) {
+					var list *mdBlock
+					list, i = p.parseList(lines, i)
+					para = nil
+					add(list)
+					continue
+				}
+			}
+
+			// HTML block
+			if p.trusted {
+				if end, ok := p.htmlBlockEnd(s, para != nil); ok {
+					block := &mdBlock{kind: mdHTML}
+					for ; i < len(lines); i++ {
+						if end == nil && isBlank(lines[i]) {
+							break
+						}
+						block.lines = append(block.lines, lines[i])
+						if end != nil && end(lines[i]) {
+							i++
+							break
+						}
+					}
+					para = nil
+					add(block)
+					continue
+				}
+			}
+		}
+
+		// Paragraph (or its continuation)
+		if para != nil {
+			para.lines = append(para.lines, strings.TrimLeft(line, " "))
+		} else {
+			para = &mdBlock{kind: mdParagraph, lines: []string{strings.TrimLeft(line, " ")}}
+			add(para)
+		}
+		i++
+	}
+	return blocks, blank_between
+}
+
+// parseList parses the list starting at lines[i] and returns the position of
+// the first line after it.
+func (p *mdParser) parseList(lines []string, i int) (*mdBlock, int) {
+	list := &mdBlock{kind: mdList}
+	var list_delim byte
+
+	for i < len(lines) {
+		line := lines[i]
+		indent := leadingSpaces(line)
+		if indent >= 4 {
+			break
+		}
+		length, delim, ordered, start, ok := listMarker(line[indent:])
+		if !ok {
+			break
+		}
+		if len(list.children) == 0 {
+			list.ordered, list.start, list_delim = ordered, start, delim
+		} else if ordered != list.ordered || delim != list_delim {
+			break
+		}
+		if re_md_thematic.MatchString(line[indent:]) && len(list.children) > 0 {
+			break
+		}
+
+		// The content starts after the marker and 1-4 spaces
+		rest := line[indent+length:]
+		spaces := leadingSpaces(rest)
+		offset := indent + length + spaces
+		if spaces > 4 || isBlank(rest) {
+			offset = indent + length + 1
+		}
+		first := ""
+		if offset < len(line) {
+			first = line[offset:]
+		}
+		item_lines := []string{first}
+
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if isBlank(l) {
+				if isBlank(first) && len(item_lines) == 1 {
+					// An item can begin with at most one blank line
+					break
+				}
+				item_lines = append(item_lines, "")
+				continue
+			}
+			if leadingSpaces(l) >= offset {
+				item_lines = append(item_lines, l[offset:])
+				continue
+			}
+			// Lazy continuation of a paragraph
+			last := item_lines[len(item_lines)-1]
+			if !isBlank(last) && !p.startsBlock(l) && !isListItem(l) {
+				item_lines = append(item_lines, strings.TrimLeft(l, " "))
+				continue
+			}
+			break
+		}
+
+		// Trailing blank lines separate items (or end the list)
+		blanks := 0
+		for len(item_lines) > 1 && isBlank(item_lines[len(item_lines)-1]) {
+			item_lines = item_lines[:len(item_lines)-1]
+			blanks++
+		}
+		children, blank_between := p.parseBlocks(item_lines)
+		list.children = append(list.children, &mdBlock{kind: mdItem, children: children})
+		if blank_between {
+			list.loose = true
+		}
+
+		if blanks > 0 {
+			if i < len(lines) && isListItem(lines[i]) && sameList(lines[i], list.ordered, list_delim) {
+				list.loose = true
+				continue
+			}
+			i -= blanks
+			break
+		}
+	}
+	return list, i
+}
+
+func isListItem(line string) bool {
+	indent := leadingSpaces(line)
+	if indent >= 4 {
+		return false
+	}
+	_, _, _, _, ok := listMarker(line[indent:])
+	return ok && !re_md_thematic.MatchString(line[indent:])
+}
+
+func sameList(line string, ordered bool, list_delim byte) bool {
+	_, delim, is_ordered, _, _ := listMarker(line[leadingSpaces(line):])
+	return is_ordered == ordered && delim == list_delim
+}
+
+// normalizeLabel normalizes the label of a link reference.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.ToUpper(strings.Join(strings.Fields(label), " ")))
+}
+
+// parseLinkRefs removes the link reference definitions at the beginning of
+// a paragraph (see parseLinkRef).
+func (p *mdParser) parseLinkRefs(b *mdBlock) {
+	text := strings.Join(b.lines, "\n")
+	for {
+		label, ref, rest, ok := parseLinkRef(text)
+		if !ok {
+			break
+		}
+		if _, has_ref := p.refs[label]; !has_ref {
+			p.refs[label] = ref
+		}
+		text = rest
+	}
+	if text == "" {
+		b.lines = nil
+	} else {
+		b.lines = strings.Split(text, "\n")
+	}
+}
+
+// parseLinkRef parses a link reference definition, like
+//     [label]: /url "title"
+func parseLinkRef(text string) (string, mdLinkRef, string, bool) {
+	if !strings.HasPrefix(text, "[") {
+		return "", mdLinkRef{}, "", false
+	}
+	end := linkLabelEnd(text, 0)
+	if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+		return "", mdLinkRef{}, "", false
+	}
+	label := normalizeLabel(text[1:end])
+	if label == "" {
+		return "", mdLinkRef{}, "", false
+	}
+
+	pos := skipSpaceNewline(text, end+2)
+	url, pos, ok := parseLinkDestination(text, pos)
+	if !ok {
+		return "", mdLinkRef{}, "", false
+	}
+
+	// The title is optional, but the definition has to end with the line
+	after_url := pos
+	title_pos := skipSpaceNewline(text, pos)
+	if title_pos > pos {
+		if title, title_end, ok := parseLinkTitle(text, title_pos); ok {
+			if rest, ok := restOfLine(text, title_end); ok {
+				return label, mdLinkRef{url, title}, rest, true
+			}
+		}
+	}
+	if rest, ok := restOfLine(text, after_url); ok {
+		return label, mdLinkRef{url, ""}, rest, true
+	}
+	return "", mdLinkRef{}, "", false
+}
+
+// restOfLine returns the text after the line containing pos if the line only
+// contains whitespace after pos.
+func restOfLine(text string, pos int) (string, bool) {
+	for ; pos < len(text); pos++ {
+		switch text[pos] {
+		case ' ', '\t':
+			continue
+		case '\n':
+			return text[pos+1:], true
+		}
+		return "", false
+	}
+	return "", true
+}
+
+// skipSpaceNewline skips spaces and tabs including at most one newline.
+func skipSpaceNewline(text string, pos int) int {
+	newline := false
+	for pos < len(text) {
+		switch {
+		case text[pos] == ' ' || text[pos] == '\t':
+		case text[pos] == '\n' && !newline:
+			newline = true
+		default:
+			return pos
+		}
+		pos++
+	}
+	return pos
+}
+
+// linkLabelEnd returns the position of the ']' closing the link label
+// starting at pos (-1 if there's none).
+func linkLabelEnd(text string, pos int) int {
+	for i := pos + 1; i < len(text) && i-pos <= 1000; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			return -1
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeMarkdown removes backslash escapes and decodes entities.
+func unescapeMarkdown(str string) string {
+	if strings.IndexByte(str, '\\') >= 0 {
+		var sb strings.Builder
+		for i := 0; i < len(str); i++ {
+			if str[i] == '\\' && i+1 < len(str) && isASCIIPunct(str[i+1]) {
+				i++
+			}
+			sb.WriteByte(str[i])
+		}
+		str = sb.String()
+	}
+	return html.UnescapeString(str)
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// parseLinkDestination parses a link destination (like </my url> or /url).
+func parseLinkDestination(text string, pos int) (string, int, bool) {
+	if pos < len(text) && text[pos] == '<' {
+		for i := pos + 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '\n', '<':
+				return "", 0, false
+			case '>':
+				return unescapeMarkdown(text[pos+1 : i]), i + 1, true
+			}
+		}
+		return "", 0, false
+	}
+
+	depth := 0
+	i := pos
+loop:
+	for ; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			i++
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case c <= ' ' || c == 0x7f:
+			break loop
+		}
+	}
+	if i == pos || depth != 0 {
+		return "", 0, false
+	}
+	return unescapeMarkdown(text[pos:i]), i, true
+}
+
+// parseLinkTitle parses a link title (like "title", 'title' or (title)).
+func parseLinkTitle(text string, pos int) (string, int, bool) {
+	if pos >= len(text) {
+		return "", 0, false
+	}
+	closing := text[pos]
+	switch closing {
+	case '"', '\'':
+	case '(':
+		closing = ')'
+	default:
+		return "", 0, false
+	}
+	for i := pos + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case closing:
+			return unescapeMarkdown(text[pos+1 : i]), i + 1, true
+		case '(':
+			if closing == ')' {
+				return "", 0, false
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// renderMarkdown converts CommonMark to HTML; raw HTML is escaped unless
+// trusted is set.
+func renderMarkdown(in string, trusted bool) string {
+	in = strings.Replace(in, "\r\n", "\n", -1)
+	in = strings.Replace(in, "\r", "\n", -1)
+	in = strings.Replace(in, "\x00", "\ufffd", -1)
+	lines := strings.Split(in, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	p := &mdParser{trusted: trusted, refs: make(map[string]mdLinkRef)}
+	blocks, _ := p.parseBlocks(lines)
+	p.collectLinkRefs(blocks)
+
+	var sb strings.Builder
+	p.renderBlocks(&sb, blocks, false)
+	return sb.String()
+}
+
+// collectLinkRefs collects the link reference definitions (in the order of
+// the document, the first definition of a label wins).
+func (p *mdParser) collectLinkRefs(blocks []*mdBlock) {
+	for _, b := range blocks {
+		if b.kind == mdParagraph {
+			p.parseLinkRefs(b)
+		}
+		p.collectLinkRefs(b.children)
+	}
+}
+
+func (p *mdParser) renderBlocks(sb *strings.Builder, blocks []*mdBlock, tight bool) {
+	for _, b := range blocks {
+		switch b.kind {
+		case mdParagraph:
+			if len(b.lines) == 0 {
+				continue
+			}
+			text := strings.TrimRight(strings.Join(b.lines, "\n"), " \t")
+			if tight {
+				sb.WriteString(p.renderInline(text))
+			} else {
+				fmt.Fprintf(sb, "<p>%s</p>\n", p.renderInline(text))
+			}
+		case mdHeading:
+			text := strings.TrimSpace(strings.Join(b.lines, "\n"))
+			fmt.Fprintf(sb, "<h%d>%s</h%d>\n", b.level, p.renderInline(text), b.level)
+		case mdThematicBreak:
+			sb.WriteString("<hr />\n")
+		case mdCode:
+			sb.WriteString("<pre><code")
+			if lang := strings.Fields(b.info); len(lang) > 0 {
+				fmt.Fprintf(sb, ` class="language-%s"`, escapeAttr(unescapeMarkdown(lang[0])))
+			}
+			sb.WriteString(">")
+			for _, line := range b.lines {
+				sb.WriteString(escapeHTML(line) + "\n")
+			}
+			sb.WriteString("</code></pre>\n")
+		case mdHTML:
+			sb.WriteString(strings.Join(b.lines, "\n") + "\n")
+		case mdQuote:
+			sb.WriteString("<blockquote>\n")
+			p.renderBlocks(sb, b.children, false)
+			sb.WriteString("</blockquote>\n")
+		case mdList:
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			if b.ordered && b.start != 1 {
+				fmt.Fprintf(sb, "<ol start=\"%d\">\n", b.start)
+			} else {
+				fmt.Fprintf(sb, "<%s>\n", tag)
+			}
+			for _, item := range b.children {
+				sb.WriteString("<li>")
+				for idx, child := range item.children {
+					if !b.loose && child.kind == mdParagraph {
+						p.renderBlocks(sb, []*mdBlock{child}, true)
+						continue
+					}
+					if idx == 0 || (!b.loose && item.children[idx-1].kind == mdParagraph) {
+						sb.WriteString("\n")
+					}
+					p.renderBlocks(sb, []*mdBlock{child}, false)
+				}
+				sb.WriteString("</li>\n")
+			}
+			fmt.Fprintf(sb, "</%s>\n", tag)
+		}
+	}
+}
+
+// Inline raw HTML (see the CommonMark spec)
+const (
+	md_attr        = `(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)`
+	md_open_tag    = `<[A-Za-z][A-Za-z0-9-]*` + md_attr + `*\s*/?>`
+	md_closing_tag = `</[A-Za-z][A-Za-z0-9-]*\s*>`
+)
+
+var (
+	re_md_raw_html      = regexp.MustCompile(`^(?:` + md_open_tag + `|` + md_closing_tag + `|<!---?>|<!--[\s\S]*?-->|<\?[\s\S]*?\?>|<![A-Za-z][^>]*>|<!\[CDATA\[[\s\S]*?\]\]>)`)
+	re_md_autolink      = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.\-]{1,31}:[^<>\x00-\x20]*)>`)
+	re_md_email         = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	re_md_entity        = regexp.MustCompile(`^&(?:[A-Za-z][A-Za-z0-9]{1,31}|#[0-9]{1,7}|#[xX][0-9A-Fa-f]{1,6});`)
+	md_special_chars    = "\\`*_![]<&\n"
+	md_text_replacement = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+)
+
+// mdNode is a piece of rendered inline content; delimiter runs (of emphasis)
+// and brackets (of links) are processed after all nodes are known.
+type mdNode struct {
+	html  string // rendered HTML (if it's no delimiter)
+	plain string // text content (for the alt text of images)
+
+	delim      byte // '*' or '_' for delimiter runs, '[' or '!' for brackets
+	count      int  // remaining delimiter characters
+	orig_count int
+	can_open   bool
+	can_close  bool
+	active     bool
+	open_tags  []string
+	close_tags []string
+	src_pos    int // position after a bracket
+}
+
+// render returns the HTML of the node.
+func (n *mdNode) render() string {
+	switch n.delim {
+	case 0:
+		return n.html
+	case '[':
+		return "["
+	case '!':
+		return "!["
+	}
+	return strings.Join(n.close_tags, "") + strings.Repeat(string(n.delim), n.count) + strings.Join(n.open_tags, "")
+}
+
+func (n *mdNode) text() string {
+	switch n.delim {
+	case 0:
+		return n.plain
+	case '*', '_':
+		return strings.Repeat(string(n.delim), n.count)
+	}
+	return n.render()
+}
+
+// mdInline parses inline content.
+type mdInline struct {
+	p      *mdParser
+	src    string
+	pos    int
+	nodes  []*mdNode
+	active []int // positions of the brackets in nodes which may start links
+}
+
+func (p *mdParser) renderInline(src string) string {
+	in := &mdInline{p: p, src: src}
+	in.parse()
+	in.processEmphasis(0)
+	var sb strings.Builder
+	for _, n := range in.nodes {
+		sb.WriteString(n.render())
+	}
+	return sb.String()
+}
+
+func (in *mdInline) text(str string) {
+	in.nodes = append(in.nodes, &mdNode{html: md_text_replacement.Replace(str), plain: str})
+}
+
+func (in *mdInline) raw(html string, plain string) {
+	in.nodes = append(in.nodes, &mdNode{html: html, plain: plain})
+}
+
+// runeBefore and runeAfter return the characters around a delimiter run
+// (newlines at the beginning and end of the text).
+func (in *mdInline) runeBefore(pos int) rune {
+	if pos == 0 {
+		return '\n'
+	}
+	r, _ := utf8.DecodeLastRuneInString(in.src[:pos])
+	return r
+}
+
+func (in *mdInline) runeAfter(pos int) rune {
+	if pos >= len(in.src) {
+		return '\n'
+	}
+	r, _ := utf8.DecodeRuneInString(in.src[pos:])
+	return r
+}
+
+func isMarkdownPunct(r rune) bool {
+	return (r < utf8.RuneSelf && isASCIIPunct(byte(r))) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func (in *mdInline) parse() {
+	src := in.src
+	for in.pos < len(src) {
+		c := src[in.pos]
+		switch c {
+		case '\\':
+			if in.pos+1 < len(src) && src[in.pos+1] == '\n' {
+				in.trimTrailingSpaces()
+				in.raw("<br />\n", "\n")
+				in.pos += 2
+				in.skipLeadingSpaces()
+			} else if in.pos+1 < len(src) && isASCIIPunct(src[in.pos+1]) {
+				in.text(src[in.pos+1 : in.pos+2])
+				in.pos += 2
+			} else {
+				in.text("\\")
+				in.pos++
+			}
+		case '`':
+			in.parseCodeSpan()
+		case '*', '_':
+			start := in.pos
+			for in.pos < len(src) && src[in.pos] == c {
+				in.pos++
+			}
+			before, after := in.runeBefore(start), in.runeAfter(in.pos)
+			left := !unicode.IsSpace(after) && (!isMarkdownPunct(after) || unicode.IsSpace(before) || isMarkdownPunct(before))
+			right := !unicode.IsSpace(before) && (!isMarkdownPunct(before) || unicode.IsSpace(after) || isMarkdownPunct(after))
+			n := &mdNode{delim: c, count: in.pos - start, orig_count: in.pos - start, active: true}
+			if c == '*' {
+				n.can_open, n.can_close = left, right
+			} else {
+				n.can_open = left && (!right || isMarkdownPunct(before))
+				n.can_close = right && (!left || isMarkdownPunct(after))
+			}
+			in.nodes = append(in.nodes, n)
+		case '!':
+			if in.pos+1 < len(src) && src[in.pos+1] == '[' {
+				in.pos += 2
+				in.active = append(in.active, len(in.nodes))
+				in.nodes = append(in.nodes, &mdNode{delim: '!', active: true, src_pos: in.pos})
+			} else {
+				in.text("!")
+				in.pos++
+			}
+		case '[':
+			in.pos++
+			in.active = append(in.active, len(in.nodes))
+			in.nodes = append(in.nodes, &mdNode{delim: '[', active: true, src_pos: in.pos})
+		case ']':
+			in.parseCloseBracket()
+		case '<':
+			in.parseAngleBracket()
+		case '&':
+			if m := re_md_entity.FindString(src[in.pos:]); m != "" {
+				in.text(html.UnescapeString(m))
+				in.pos += len(m)
+			} else {
+				in.text("&")
+				in.pos++
+			}
+		case '\n':
+			hard := in.trimTrailingSpaces() >= 2
+			if hard {
+				in.raw("<br />\n", "\n")
+			} else {
+				in.raw("\n", "\n")
+			}
+			in.pos++
+			in.skipLeadingSpaces()
+		default:
+			end := strings.IndexAny(src[in.pos:], md_special_chars)
+			if end < 0 {
+				end = len(src) - in.pos
+			}
+			in.text(src[in.pos : in.pos+end])
+			in.pos += end
+		}
+	}
+}
+
+// trimTrailingSpaces removes the spaces at the end of the last text node and
+// returns their number.
+func (in *mdInline) trimTrailingSpaces() int {
+	if len(in.nodes) == 0 {
+		return 0
+	}
+	last := in.nodes[len(in.nodes)-1]
+	if last.delim != 0 {
+		return 0
+	}
+	trimmed := strings.TrimRight(last.html, " ")
+	n := len(last.html) - len(trimmed)
+	last.html, last.plain = trimmed, strings.TrimRight(last.plain, " ")
+	return n
+}
+
+func (in *mdInline) skipLeadingSpaces() {
+	for in.pos < len(in.src) && in.src[in.pos] == ' ' {
+		in.pos++
+	}
+}
+
+func (in *mdInline) parseCodeSpan() {
+	src := in.src
+	start := in.pos
+	for in.pos < len(src) && src[in.pos] == '`' {
+		in.pos++
+	}
+	ticks := in.pos - start
+
+	// Find a closing run of the same length
+	for i := in.pos; i < len(src); {
+		if src[i] != '`' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(src) && src[j] == '`' {
+			j++
+		}
+		if j-i == ticks {
+			code := strings.Replace(src[in.pos:i], "\n", " ", -1)
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			in.raw("<code>"+md_text_replacement.Replace(code)+"</code>", code)
+			in.pos = j
+			return
+		}
+		i = j
+	}
+	in.text(src[start:in.pos])
+}
+
+func (in *mdInline) parseAngleBracket() {
+	rest := in.src[in.pos:]
+	if m := re_md_autolink.FindStringSubmatch(rest); m != nil {
+		in.raw(fmt.Sprintf(`<a href="%s">%s</a>`, in.linkURL(m[1]), md_text_replacement.Replace(m[1])), m[1])
+		in.pos += len(m[0])
+		return
+	}
+	if m := re_md_email.FindStringSubmatch(rest); m != nil {
+		in.raw(fmt.Sprintf(`<a href="mailto:%s">%s</a>`, escapeAttr(quoteURL(m[1], iri_safe)), md_text_replacement.Replace(m[1])), m[1])
+		in.pos += len(m[0])
+		return
+	}
+	if m := re_md_raw_html.FindString(rest); m != "" && in.p.trusted {
+		in.raw(m, "")
+		in.pos += len(m)
+		return
+	}
+	in.text("<")
+	in.pos++
+}
+
+// linkURL returns the URL for a href/src attribute; URLs with unsafe schemes
+// are removed unless the input is trusted.
+func (in *mdInline) linkURL(url string) string {
+	if !in.p.trusted && !isSafeURL(url) {
+		return ""
+	}
+	return escapeAttr(quoteURL(url, iri_safe))
+}
+
+// parseCloseBracket handles a ']' which might end a link or image.
+func (in *mdInline) parseCloseBracket() {
+	src := in.src
+	in.pos++
+	if len(in.active) == 0 {
+		in.text("]")
+		return
+	}
+	opener_idx := in.active[len(in.active)-1]
+	opener := in.nodes[opener_idx]
+	in.active = in.active[:len(in.active)-1]
+	if !opener.active {
+		in.text("]")
+		return
+	}
+
+	var ref mdLinkRef
+	found := false
+	pos := in.pos
+
+	// Inline link: [text](url "title")
+	if pos < len(src) && src[pos] == '(' {
+		p := skipSpaceNewline(src, pos+1)
+		url, title := "", ""
+		ok := true
+		if p < len(src) && src[p] != ')' {
+			url, p, ok = parseLinkDestination(src, p)
+			if ok {
+				if q := skipSpaceNewline(src, p); q > p {
+					if t, end, is_title := parseLinkTitle(src, q); is_title {
+						title, p = t, end
+					}
+				}
+			}
+		}
+		if ok {
+			p = skipSpaceNewline(src, p)
+			if p < len(src) && src[p] == ')' {
+				ref, found, pos = mdLinkRef{url, title}, true, p+1
+			}
+		}
+	}
+
+	// Reference link: [text][label], [label][] or [label]
+	if !found {
+		label := ""
+		if pos < len(src) && src[pos] == '[' {
+			if end := linkLabelEnd(src, pos); end >= 0 {
+				label = src[pos+1 : end]
+				if label != "" {
+					pos = end + 1
+				} else {
+					label = src[opener.src_pos : in.pos-1]
+					pos = end + 1
+				}
+			}
+		}
+		if label == "" {
+			label = src[opener.src_pos : in.pos-1]
+		}
+		if r, has_ref := in.p.refs[normalizeLabel(label)]; has_ref && len(label) <= 999 {
+			ref, found = r, true
+		} else {
+			pos = in.pos
+		}
+	}
+
+	if !found {
+		in.text("]")
+		return
+	}
+	in.pos = pos
+
+	// The nodes after the opener are the link's text
+	in.processEmphasis(opener_idx + 1)
+	var inner, plain strings.Builder
+	for _, n := range in.nodes[opener_idx+1:] {
+		inner.WriteString(n.render())
+		plain.WriteString(n.text())
+	}
+	in.nodes = in.nodes[:opener_idx]
+
+	title := ""
+	if ref.title != "" {
+		title = fmt.Sprintf(` title="%s"`, md_text_replacement.Replace(ref.title))
+	}
+	if opener.delim == '!' {
+		in.raw(fmt.Sprintf(`<img src="%s" alt="%s"%s />`, in.linkURL(ref.url), md_text_replacement.Replace(plain.String()), title), plain.String())
+		return
+	}
+	in.raw(fmt.Sprintf(`<a href="%s"%s>%s</a>`, in.linkURL(ref.url), title, inner.String()), plain.String())
+
+	// No links in links
+	for _, idx := range in.active {
+		if in.nodes[idx].delim == '[' {
+			in.nodes[idx].active = false
+		}
+	}
+}
+
+// processEmphasis matches the delimiter runs in nodes[bottom:] (see the
+// CommonMark spec).
+func (in *mdInline) processEmphasis(bottom int) {
+	nodes := in.nodes
+	for c := bottom; c < len(nodes); c++ {
+		closer := nodes[c]
+		if (closer.delim != '*' && closer.delim != '_') || !closer.active || !closer.can_close {
+			continue
+		}
+
+		for closer.active && closer.count > 0 {
+			// Look for an opener
+			o := c - 1
+			for ; o >= bottom; o-- {
+				opener := nodes[o]
+				if opener.delim != closer.delim || !opener.active || !opener.can_open || opener.count == 0 {
+					continue
+				}
+				if (opener.can_close || closer.can_open) && (opener.orig_count+closer.orig_count)%3 == 0 &&
+					!(opener.orig_count%3 == 0 && closer.orig_count%3 == 0) {
+					continue
+				}
+				break
+			}
+			if o < bottom {
+				if !closer.can_open {
+					closer.active = false
+				}
+				break
+			}
+
+			opener := nodes[o]
+			n, tag := 1, "em"
+			if opener.count >= 2 && closer.count >= 2 {
+				n, tag = 2, "strong"
+			}
+			opener.count -= n
+			closer.count -= n
+			opener.open_tags = append([]string{"<" + tag + ">"}, opener.open_tags...)
+			closer.close_tags = append(closer.close_tags, "</"+tag+">")
+
+			// Delimiters between the opener and closer become text
+			for i := o + 1; i < c; i++ {
+				if nodes[i].delim == '*' || nodes[i].delim == '_' {
+					nodes[i].active = false
+				}
+			}
+			if opener.count == 0 {
+				opener.active = false
+			}
+		}
+	}
+
+	// Remaining delimiters are text
+	for _, n := range nodes[bottom:] {
+		if n.delim == '*' || n.delim == '_' {
+			n.active = false
+		}
+	}
+}
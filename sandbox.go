@@ -0,0 +1,246 @@
+package pongo
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// A Sandbox is a policy for templates which aren't trusted (like templates
+// edited by customers). It applies to all templates created by its FromString
+// and FromFile functions and to all templates they extend or include.
+//
+// Tags and filters which aren't allowed are rejected while parsing; method
+// calls are checked when they are executed. The zero value allows everything.
+type Sandbox struct {
+	// Allowed tags and filters (nil allows all). End-tags (like endif) and
+	// the safe-filter (applied automatically) are always allowed.
+	Tags    []string
+	Filters []string
+
+	// If NoMethodCalls is set, neither methods nor functions are called.
+	// Otherwise, if Types or Methods are given, only the methods of the
+	// listed types (like "*main.User", see reflect.Type.String()) and the
+	// listed methods (like "*main.User.FullName") may be called. Functions
+	// stored in fields or maps are listed like methods, functions stored in
+	// the Context by their name (like "greet"). The methods called implicitly
+	// when formatting values (like String) are checked for printed values and
+	// for the values and arguments of filters.
+	NoMethodCalls bool
+	Types         []string
+	Methods       []string
+
+	// If NoIncludes is set, the include- and extends-tags are not allowed.
+	// Otherwise, if Roots are given, only templates whose (cleaned) name is
+	// within one of the roots (like "mails/") may be included or extended.
+	NoIncludes bool
+	Roots      []string
+
+	// Limits of every execution (see Template.SetLimits)
+	Limits Limits
+}
+
+// Creates a new sandboxed template instance from string (see FromString).
+func (sb *Sandbox) FromString(name string, tplstr *string, locator templateLocator) (*Template, error) {
+	return parseTemplate(name, tplstr, locator, sb)
+}
+
+// Creates a new sandboxed template instance from file (see FromFile).
+func (sb *Sandbox) FromFile(file_path string, locator templateLocator) (*Template, error) {
+	return fromFile(file_path, locator, sb)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (sb *Sandbox) checkTag(name string) error {
+	if sb == nil {
+		return nil
+	}
+	if (sb.NoIncludes && (name == "include" || name == "extends")) || (sb.Tags != nil && !containsString(sb.Tags, name)) {
+		return errors.New(fmt.Sprintf("Tag '%s' is not allowed in the sandbox.", name))
+	}
+	return nil
+}
+
+func (sb *Sandbox) checkFilter(name string) error {
+	if sb == nil || sb.Filters == nil || name == "safe" || containsString(sb.Filters, name) {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("Filter '%s' is not allowed in the sandbox.", name))
+}
+
+// checkCall checks whether the method (or function) name of a value of type
+// recv may be called; recv is nil for functions stored in the Context.
+func (sb *Sandbox) checkCall(recv reflect.Type, name string) error {
+	if sb == nil {
+		return nil
+	}
+	full_name := name
+	if recv != nil {
+		full_name = fmt.Sprintf("%s.%s", recv, name)
+	}
+	if sb.NoMethodCalls {
+		return errors.New(fmt.Sprintf("Calling '%s' is not allowed in the sandbox.", full_name))
+	}
+	if sb.Types == nil && sb.Methods == nil {
+		return nil
+	}
+	if (recv != nil && containsString(sb.Types, recv.String())) || containsString(sb.Methods, full_name) {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("Calling '%s' is not allowed in the sandbox.", full_name))
+}
+
+var (
+	formatterType     = reflect.TypeOf((*fmt.Formatter)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// implicitMethod returns the method fmt (or encoding/json if for_json is set)
+// calls when formatting a value of type t ("" if there's none).
+func implicitMethod(t reflect.Type, for_json bool) string {
+	switch {
+	case for_json && t.Implements(jsonMarshalerType):
+		return "MarshalJSON"
+	case for_json && t.Implements(textMarshalerType):
+		return "MarshalText"
+	case for_json:
+		return ""
+	case t.Implements(formatterType):
+		return "Format"
+	case t.Implements(errorType):
+		return "Error"
+	case t.Implements(stringerType):
+		return "String"
+	}
+	return ""
+}
+
+// checkFormat checks whether the methods which are called implicitly when
+// value is formatted by fmt (like String or Error) or encoded by encoding/json
+// (like MarshalJSON) may be called in the sandbox.
+func (sb *Sandbox) checkFormat(value interface{}, for_json bool) error {
+	if sb == nil || (!sb.NoMethodCalls && sb.Types == nil && sb.Methods == nil) {
+		return nil
+	}
+	return sb.checkFormatValue(reflect.ValueOf(value), 0, for_json)
+}
+
+func (sb *Sandbox) checkFormatValue(v reflect.Value, depth int, for_json bool) error {
+	// Like fmt and encoding/json, give up on deeply nested (or cyclic) values
+	if !v.IsValid() || depth > 100 {
+		return nil
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		return sb.checkFormatValue(v.Elem(), depth, for_json)
+	}
+
+	// Methods of unexported fields aren't called
+	if depth == 0 || v.CanInterface() {
+		if name := implicitMethod(v.Type(), for_json); name != "" {
+			if err := sb.checkCall(v.Type(), name); err != nil {
+				return err
+			}
+		}
+		if for_json && v.CanAddr() {
+			if name := implicitMethod(reflect.PtrTo(v.Type()), true); name != "" {
+				if err := sb.checkCall(reflect.PtrTo(v.Type()), name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		// fmt only follows the outermost pointer
+		if !v.IsNil() && (depth == 0 || for_json) {
+			return sb.checkFormatValue(v.Elem(), depth+1, for_json)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if for_json && v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := sb.checkFormatValue(v.Field(i), depth+1, for_json); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := sb.checkFormatValue(v.Index(i), depth+1, for_json); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if err := sb.checkFormatValue(key, depth+1, for_json); err != nil {
+				return err
+			}
+			if err := sb.checkFormatValue(v.MapIndex(key), depth+1, for_json); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTemplate checks whether the template name may be included or extended.
+func (sb *Sandbox) checkTemplate(name string) error {
+	if sb == nil {
+		return nil
+	}
+	if sb.NoIncludes {
+		return errors.New(fmt.Sprintf("Template '%s' can't be loaded in the sandbox.", name))
+	}
+	if sb.Roots == nil {
+		return nil
+	}
+	cleaned := path.Clean(strings.Replace(name, "\\", "/", -1))
+	for _, root := range sb.Roots {
+		root = path.Clean(root)
+		if cleaned == root || strings.HasPrefix(cleaned, root+"/") {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Template '%s' is outside of the sandbox's roots.", name))
+}
+
+// sandboxOf returns the Sandbox of the executed template (if any).
+func sandboxOf(ctx *Context) *Sandbox {
+	sb, _ := (*ctx)[sandboxKey].(*Sandbox)
+	return sb
+}
+
+// enterSandbox stores the template's sandbox in ctx (so it's checked while
+// evaluating expressions) and returns a function restoring ctx.
+func (tpl *Template) enterSandbox(ctx *Context) func() {
+	if tpl.sandbox == nil {
+		return func() {}
+	}
+	outer, has_outer := (*ctx)[sandboxKey]
+	(*ctx)[sandboxKey] = tpl.sandbox
+	return func() {
+		if has_outer {
+			(*ctx)[sandboxKey] = outer
+		} else {
+			delete(*ctx, sandboxKey)
+		}
+	}
+}
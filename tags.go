@@ -11,22 +11,28 @@ type TagHandler struct {
 	Execute func(*string, *executionContext, *Context) (*string, error)
 	Ignore  func(*string, *executionContext) error
 	Prepare func(*tagNode, *Template) error
+	Compile func(*compiler, *tagNode) compiledNode // optional; without it the tag is interpreted (see program)
+	Check   func(*checker, *tagNode)               // optional; without it the tag's arguments aren't checked (see Template.Check)
 }
 
 var Tags = map[string]*TagHandler{
-	"if":        &TagHandler{Execute: tagIf, Ignore: tagIfIgnore},
-	"else":      nil, // Only a placeholder for the (if|for)-statement
-	"endif":     nil, // Only a placeholder for the if-statement
-	"for":       &TagHandler{Execute: tagFor, Ignore: tagForIgnore},
-	"endfor":    nil,
-	"block":     &TagHandler{Execute: tagBlock}, // Needs no Ignore-function because nested-blocks aren't allowed
-	"endblock":  nil,
-	"extends":   &TagHandler{},
-	"include":   &TagHandler{},
-	"trim":      &TagHandler{Execute: tagTrim, Ignore: tagTrimIgnore},
-	"endtrim":   nil,
-	"remove":    &TagHandler{Execute: tagRemove, Ignore: tagRemoveIgnore},
-	"endremove": nil,
+	"if":            &TagHandler{Execute: tagIf, Ignore: tagIfIgnore, Compile: compileIf, Check: checkIf},
+	"else":          nil, // Only a placeholder for the (if|for)-statement
+	"endif":         nil, // Only a placeholder for the if-statement
+	"for":           &TagHandler{Execute: tagFor, Ignore: tagForIgnore, Compile: compileFor, Check: checkFor},
+	"endfor":        nil,
+	"block":         &TagHandler{Execute: tagBlock, Ignore: tagBlockIgnore, Compile: compileBlock, Check: checkBlock},
+	"endblock":      nil,
+	"extends":       &TagHandler{},
+	"include":       &TagHandler{},
+	"trim":          &TagHandler{Execute: tagTrim, Ignore: tagTrimIgnore, Compile: compileTrim},
+	"endtrim":       nil,
+	"remove":        &TagHandler{Execute: tagRemove, Ignore: tagRemoveIgnore, Compile: compileRemove, Check: checkRemove},
+	"endremove":     nil,
+	"trans":         &TagHandler{Execute: tagTrans, Ignore: tagTransIgnore, Prepare: tagTransPrepare, Check: checkTrans},
+	"blocktrans":    &TagHandler{Execute: tagBlocktrans, Ignore: tagBlocktransIgnore, Prepare: tagBlocktransPrepare, Check: checkBlocktrans},
+	"plural":        nil, // Only a placeholder for the blocktrans-statement
+	"endblocktrans": nil,
 	/*"catch": tagCatch, // catches any panics and prints them
 	"endcatch": nil,*/
 
@@ -42,10 +48,14 @@ func init() {
 	if tag, has_extends := Tags["extends"]; has_extends && tag.Execute == nil && tag.Prepare == nil {
 		Tags["extends"].Prepare = tagExtendsPrepare
 		Tags["extends"].Execute = tagExtends
+		Tags["extends"].Check = checkExtends
+		Tags["extends"].Compile = compileSingle
 	}
 	if tag, has_include := Tags["include"]; has_include && tag.Execute == nil && tag.Prepare == nil {
 		Tags["include"].Prepare = tagIncludePrepare
 		Tags["include"].Execute = tagInclude
+		Tags["include"].Check = checkInclude
+		Tags["include"].Compile = compileSingle
 	}
 }
 
@@ -256,27 +266,101 @@ func evalCondArg(ctx *Context, in *string) (interface{}, error) {
 	panic("unreachable")
 }
 
-func tagIf(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
-	renderedStrings := make([]string, 0, len(execCtx.template.nodes)-execCtx.node_pos)
+// condition is a parsed if-argument; it's evaluated the same way as
+// evalCondArg does, but without parsing the argument on every execution.
+type condition struct {
+	op          compareFunc // nil if the condition is a single expression
+	op_name     string      // e. g. "=="
+	left, right *condition
+	e           *expr
+}
 
-	*args = strings.TrimSpace(*args)
-	if len(*args) == 0 {
-		return nil, errors.New("If-argument is empty.")
+func parseCondition(in string) (*condition, error) {
+	var ops []string
+	switch {
+	case containsAnyOperator(in, "&&", "||"):
+		ops = []string{"&&", "||"}
+	case containsAnyOperator(in, "==", "!=", "<>", ">=", "<=", ">", "<"):
+		ops = []string{"==", "!=", "<>", ">=", "<=", ">", "<"}
+	default:
+		e, err := newExpr(&in)
+		if err != nil {
+			return nil, err
+		}
+		return &condition{e: e}, nil
 	}
 
-	evaled, err := evalCondArg(ctx, args)
+	var op string
+	for _, _op := range ops {
+		if strings.Contains(in, _op) {
+			op = _op
+			break
+		}
+	}
+
+	args := strings.SplitN(in, op, 2)
+	if len(args) != 2 {
+		return nil, errors.New(fmt.Sprintf("%s-operator must have 2 operands (like X and Y).", op))
+	}
+
+	left, err := parseCondition(args[0])
 	if err != nil {
 		return nil, err
 	}
+	right, err := parseCondition(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	op_func, has_op := compMap[op]
+	if !has_op {
+		return nil, errors.New(fmt.Sprintf("Operator-handler for '%s' not found.", op))
+	}
+
+	return &condition{op: op_func, op_name: op, left: left, right: right}, nil
+}
+
+func (c *condition) eval(ctx *Context) (interface{}, error) {
+	if c.op == nil {
+		return c.e.evalValue(ctx)
+	}
 
+	left, err := c.left.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	right, err := c.right.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	return c.op(left, right), nil
+}
+
+// isTrue checks whether the result of an if-argument evaluates to true.
+func isTrue(evaled interface{}) bool {
 	res_bool, is_bool := evaled.(bool)
 	if !is_bool {
 		// {% if x %}
 		// Anything evals to TRUE which is DIFFER from the type's default value!
 		res_bool = reflect.Zero(reflect.TypeOf(evaled)).Interface() != evaled
 	}
+	return res_bool
+}
+
+func tagIf(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
+	renderedStrings := make([]string, 0, len(execCtx.template.nodes)-execCtx.node_pos)
 
-	if res_bool {
+	*args = strings.TrimSpace(*args)
+	if len(*args) == 0 {
+		return nil, errors.New("If-argument is empty.")
+	}
+
+	evaled, err := evalCondArg(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTrue(evaled) {
 		node, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "else", "endif")
 		if err != nil {
 			return nil, err
@@ -331,264 +415,214 @@ type forContext struct {
 	Last     bool
 }
 
-func tagFor(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
-	var renderedStrings []string
+// forArgs are the parsed arguments of a for-tag:
+//     {% for <varname> in <slice/array/string/map> %} or {% for <int> %}
+type forArgs struct {
+	raw     string
+	has_in  bool
+	varname string
+	e       *expr
+}
+
+func parseForArgs(args string) (*forArgs, error) {
+	fa := &forArgs{raw: args}
 
 	// TODO: Replace strings.Contains by a more intelligent function (see comment above as well)
-	if strings.Contains(*args, "in") {
+	if strings.Contains(args, "in") {
 		// <varname> in <slice/array/string/map>
-		// TODO: Update context with "forloop"-struct every loop round
-		args := strings.SplitN(*args, "in", 2)
-		if len(args) != 2 {
+		_args := strings.SplitN(args, "in", 2)
+		if len(_args) != 2 {
 			return nil, errors.New("When using 'in' in for-loop, it must use the following syntax: <varname> in <array/slice/string/map>")
 		}
-		varname := strings.TrimSpace(args[0])
-		e, err := newExpr(&args[1])
-		if err != nil {
-			return nil, err
-		}
-		value, err := e.evalValue(ctx)
-		if err != nil {
-			return nil, err
-		}
-		rv := reflect.ValueOf(value)
-		switch rv.Kind() {
-		case reflect.Slice, reflect.Array, reflect.String, reflect.Map:
-			// Iterate through slice/array
+		fa.has_in = true
+		fa.varname = strings.TrimSpace(_args[0])
+		args = _args[1]
+	}
 
-			if rv.Len() > 0 {
-				// Prepare renderedStrings
-				renderedStrings = make([]string, 0, (len(execCtx.template.nodes)-execCtx.node_pos)*rv.Len())
+	// Otherwise try to evaluate the argument, and run in X times if it evaluates to an integer
+	e, err := newExpr(&args)
+	if err != nil {
+		return nil, err
+	}
+	fa.e = e
 
-				// If map, get all keys
-				var map_items []reflect.Value
-				if rv.Kind() == reflect.Map {
-					map_items = rv.MapKeys()
-				}
+	return fa, nil
+}
 
-				// Create for-context
-				forCtx := &forContext{
-					Max:      rv.Len() - 1,
-					Max1:     rv.Len(),
-					Counter1: 1,
-					First:    true,
-				}
+// loop calls body once for every item (populating the for-context before each
+// call) or empty if there's nothing to iterate over. Every iteration is
+// recorded in state (if not nil, see Limits).
+func (fa *forArgs) loop(state *renderState, ctx *Context, body func() error, empty func() error) error {
+	value, err := fa.e.evalValue(ctx)
+	if err != nil {
+		return err
+	}
 
-				// Check if this is a nested loop (3rd grade)
-				// If so, add to forloops.
-				forloops, has_forloops := (*ctx)["forloops"]
-				if has_forloops {
-					forloops = append(forloops.([]*forContext), forCtx)
-					(*ctx)["forloops"] = forloops // Pointer might have been changed, this is why we set it again 
-				} else {
-					// Check if this is a nested loop (2nd grade)
-					// If so, populate forloops.
-					_forloop, has_forloop := (*ctx)["forloop"]
-					if has_forloop {
-						// Create forloops and add prev and current context to it
-						has_forloops = true
-						forloops = []*forContext{_forloop.(*forContext), forCtx}
-						(*ctx)["forloops"] = forloops
-					}
-				}
+	var rv reflect.Value
+	var length int
+	if fa.has_in {
+		rv = reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array, reflect.String, reflect.Map:
+			length = rv.Len()
+		default:
+			return errors.New("For-loop 'in'-operator can onl be used for slices/arrays/strings/maps.")
+		}
+	} else {
+		// If value is an integer, iterate X times.
+		rng, is_int := value.(int)
+		if !is_int {
+			return errors.New(fmt.Sprintf("For-loop error: Cannot iterate over '%v'.", fa.raw))
+		}
+		length = rng
+	}
 
-				// Do the loops
-				starter_pos := execCtx.node_pos
-				for i := 0; i < rv.Len(); i++ {
-					// Handle each type separately
-					var item interface{}
-					switch rv.Kind() {
-					case reflect.Slice, reflect.Array:
-						item = rv.Index(i).Interface()
-						(*ctx)[varname] = item
-					case reflect.Map:
-						// Create special Context struct for a map
-						(*ctx)[varname] = struct {
-							Key   interface{}
-							Value interface{}
-						}{
-							Key:   map_items[i].Interface(),
-							Value: rv.MapIndex(map_items[i]).Interface(),
-						}
-					case reflect.String:
-						item = rv.Interface().(string)[i : i+1]
-						(*ctx)[varname] = item
-					}
-					execCtx.node_pos = starter_pos
-
-					// Populate and update for-context
-					if i == 1 {
-						forCtx.First = false
-					}
-					if i == rv.Len()-1 {
-						// Last item reached
-						forCtx.Last = true
-					}
-
-					(*ctx)["forloop"] = forCtx
-					(*ctx)["forcounter"] = i
-					(*ctx)["forcounter1"] = i + 1
-
-					// Execute for-body
-					tn, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "else", "endfor")
-					if err != nil {
-						return nil, err
-					}
-					if tn.tagname == "else" {
-						// Skip else since it's not relevant
-						execCtx.ignoreUntilAnyTagNode("endfor")
-					}
-					renderedStrings = append(renderedStrings, (*str_items)...)
-
-					// Increase counters
-					forCtx.Counter++
-					forCtx.Counter1++
-				}
+	if length <= 0 {
+		// Zero executions, directly execute else or go to endfor
+		return empty()
+	}
 
-				// Remove for-context
-				delete(*ctx, varname)
-				delete(*ctx, "forloop")
-				delete(*ctx, "forcounter")
-				delete(*ctx, "forcounter1")
+	// If map, get all keys
+	var map_items []reflect.Value
+	if fa.has_in && rv.Kind() == reflect.Map {
+		map_items = rv.MapKeys()
+	}
 
-				// Check for nested, if so, remove myself from forloops
-				if has_forloops {
-					forloops = (forloops.([]*forContext))[:len(forloops.([]*forContext))-1]
-					(*ctx)["forloops"] = forloops
-				}
+	// Create for-context
+	forCtx := &forContext{
+		Max:      length - 1,
+		Max1:     length,
+		Counter1: 1,
+		First:    true,
+	}
 
-				// Check whether forloops can be removed
-				if has_forloops && len(forloops.([]*forContext)) == 0 {
-					delete(*ctx, "forloops")
-				}
-			} else {
-				// Zero executions, directly execute else or go to endfor
-				tn, err := execCtx.ignoreUntilAnyTagNode("else", "endfor")
-				if err != nil {
-					return nil, err
-				}
-				if tn.tagname == "else" {
-					// Execute empty block
-					_, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "endfor")
-					if err != nil {
-						return nil, err
-					}
-					renderedStrings = append(renderedStrings, (*str_items)...)
+	// Check if this is a nested loop (3rd grade)
+	// If so, add to forloops.
+	forloops, has_forloops := (*ctx)["forloops"]
+	if has_forloops {
+		forloops = append(forloops.([]*forContext), forCtx)
+		(*ctx)["forloops"] = forloops // Pointer might have been changed, this is why we set it again 
+	} else {
+		// Check if this is a nested loop (2nd grade)
+		// If so, populate forloops.
+		_forloop, has_forloop := (*ctx)["forloop"]
+		if has_forloop {
+			// Create forloops and add prev and current context to it
+			has_forloops = true
+			forloops = []*forContext{_forloop.(*forContext), forCtx}
+			(*ctx)["forloops"] = forloops
+		}
+	}
+
+	// Do the loops
+	for i := 0; i < length; i++ {
+		if fa.has_in {
+			// Handle each type separately
+			switch rv.Kind() {
+			case reflect.Slice, reflect.Array:
+				(*ctx)[fa.varname] = rv.Index(i).Interface()
+			case reflect.Map:
+				// Create special Context struct for a map
+				(*ctx)[fa.varname] = struct {
+					Key   interface{}
+					Value interface{}
+				}{
+					Key:   map_items[i].Interface(),
+					Value: rv.MapIndex(map_items[i]).Interface(),
 				}
+			case reflect.String:
+				(*ctx)[fa.varname] = rv.Interface().(string)[i : i+1]
 			}
-		default:
-			return nil, errors.New("For-loop 'in'-operator can onl be used for slices/arrays/strings/maps.")
 		}
-	} else {
-		// try to evaluate the argument, and run in X times if it evaluates to an integer
-		e, err := newExpr(args)
-		if err != nil {
-			return nil, err
+
+		// Populate and update for-context
+		if i == 1 {
+			forCtx.First = false
 		}
-		value, err := e.evalValue(ctx)
-		if err != nil {
-			return nil, err
+		if i == length-1 {
+			// Last item reached
+			forCtx.Last = true
 		}
 
-		// If value is an integer, iterate X times.
-		if rng, is_int := value.(int); is_int {
-			if rng > 0 {
-				// Prepare renderedStrings
-				renderedStrings = make([]string, 0, (len(execCtx.template.nodes)-execCtx.node_pos)*rng)
-
-				// Create for-context
-				forCtx := &forContext{
-					Max:      rng - 1,
-					Max1:     rng,
-					Counter1: 1,
-					First:    true,
-				}
+		(*ctx)["forloop"] = forCtx // overwrite current forloop-context
+		(*ctx)["forcounter"] = i
+		(*ctx)["forcounter1"] = i + 1
 
-				// Check if this is a nested loop (3rd grade)
-				// If so, add to forloops.
-				forloops, has_forloops := (*ctx)["forloops"]
-				if has_forloops {
-					forloops = append(forloops.([]*forContext), forCtx)
-					(*ctx)["forloops"] = forloops // Pointer might have been changed, this is why we set it again 
-				} else {
-					// Check if this is a nested loop (2nd grade)
-					// If so, populate forloops.
-					_forloop, has_forloop := (*ctx)["forloop"]
-					if has_forloop {
-						// Create forloops and add prev and current context to it
-						has_forloops = true
-						forloops = []*forContext{_forloop.(*forContext), forCtx}
-						(*ctx)["forloops"] = forloops
-					}
-				}
+		// Execute for-body
+		if err := state.iterate(); err != nil {
+			return err
+		}
+		if err := body(); err != nil {
+			return err
+		}
 
-				// Do the loops
-				starter_pos := execCtx.node_pos
-				for i := 0; i < rng; i++ {
-					execCtx.node_pos = starter_pos
-
-					// Populate and update for-context
-					if i == 1 {
-						forCtx.First = false
-					}
-					if i == rng-1 {
-						// Last item reached
-						forCtx.Last = true
-					}
-
-					(*ctx)["forloop"] = forCtx // overwrite current forloop-context
-					(*ctx)["forcounter"] = i
-					(*ctx)["forcounter1"] = i + 1
-
-					// Execute for-body
-					tn, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "else", "endfor")
-					if err != nil {
-						return nil, err
-					}
-					if tn.tagname == "else" {
-						// Skip else since it's not relevant
-						execCtx.ignoreUntilAnyTagNode("endfor")
-					}
-					renderedStrings = append(renderedStrings, (*str_items)...)
-
-					// Increase counters
-					forCtx.Counter++
-					forCtx.Counter1++
-				}
+		// Increase counters
+		forCtx.Counter++
+		forCtx.Counter1++
+	}
 
-				// Remove for-context
-				delete(*ctx, "forloop")
-				delete(*ctx, "forcounter")
-				delete(*ctx, "forcounter1")
+	// Remove for-context
+	if fa.has_in {
+		delete(*ctx, fa.varname)
+	}
+	delete(*ctx, "forloop")
+	delete(*ctx, "forcounter")
+	delete(*ctx, "forcounter1")
+
+	// Check for nested, if so, remove myself from forloops
+	if has_forloops {
+		forloops = (forloops.([]*forContext))[:len(forloops.([]*forContext))-1]
+		(*ctx)["forloops"] = forloops
+	}
 
-				// Check for nested, if so, remove myself from forloops
-				if has_forloops {
-					forloops = (forloops.([]*forContext))[:len(forloops.([]*forContext))-1]
-					(*ctx)["forloops"] = forloops
-				}
+	// Check whether forloops can be removed
+	if has_forloops && len(forloops.([]*forContext)) == 0 {
+		delete(*ctx, "forloops")
+	}
 
-				// Check whether forloops can be removed
-				if has_forloops && len(forloops.([]*forContext)) == 0 {
-					delete(*ctx, "forloops")
-				}
-			} else {
-				// Zero executions, directly execute else or go to endfor
-				tn, err := execCtx.ignoreUntilAnyTagNode("else", "endfor")
-				if err != nil {
-					return nil, err
-				}
-				if tn.tagname == "else" {
-					// Execute empty block
-					_, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "endfor")
-					if err != nil {
-						return nil, err
-					}
-					renderedStrings = append(renderedStrings, (*str_items)...)
-				}
+	return nil
+}
+
+func tagFor(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
+	var renderedStrings []string
+
+	fa, err := parseForArgs(*args)
+	if err != nil {
+		return nil, err
+	}
+
+	starter_pos := execCtx.node_pos
+	err = fa.loop(execCtx.state, ctx, func() error {
+		execCtx.node_pos = starter_pos
+
+		// Execute for-body
+		tn, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "else", "endfor")
+		if err != nil {
+			return err
+		}
+		if tn.tagname == "else" {
+			// Skip else since it's not relevant
+			execCtx.ignoreUntilAnyTagNode("endfor")
+		}
+		renderedStrings = append(renderedStrings, (*str_items)...)
+		return nil
+	}, func() error {
+		tn, err := execCtx.ignoreUntilAnyTagNode("else", "endfor")
+		if err != nil {
+			return err
+		}
+		if tn.tagname == "else" {
+			// Execute empty block
+			_, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "endfor")
+			if err != nil {
+				return err
 			}
-		} else {
-			return nil, errors.New(fmt.Sprintf("For-loop error: Cannot iterate over '%v'.", *args))
+			renderedStrings = append(renderedStrings, (*str_items)...)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	outputString := strings.Join(renderedStrings, "")
@@ -609,41 +643,162 @@ func tagForIgnore(args *string, execCtx *executionContext) error {
 	return nil
 }
 
-func tagBlock(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
-	renderedStrings := make([]string, 0, len(execCtx.template.nodes)-execCtx.node_pos)
+// blockInfo describes the position of a block within its template.
+type blockInfo struct {
+	name       string
+	start      int  // node position of the block-tag
+	end        int  // node position of the endblock-tag
+	uses_super bool // whether the block's content references {{ block.super }}
+}
+
+// blockDef is a block definition of one template of an inheritance chain.
+type blockDef struct {
+	template *Template
+	info     *blockInfo
+}
+
+// renderedBlock is the already rendered content of a parent block, accessible
+// via {{ block.super }}. It's not a string to prevent it from being escaped again.
+type renderedBlock string
+
+// blockChain returns all definitions of a block, from the most derived template
+// to the template which is currently executed.
+// The current template might not define the block at all (see ExecuteBlock).
+func (execCtx *executionContext) blockChain(name string) []*blockDef {
+	self := &blockDef{
+		template: execCtx.template,
+		info:     execCtx.template.blocks[name],
+	}
 
-	// TODO: Prevent nested block-tags
+	var chain []*blockDef
+	if _chain, has_chain := execCtx.internal_context[fmt.Sprintf("block_%s", name)]; has_chain {
+		chain = _chain.([]*blockDef)
+	}
+	if self.info == nil {
+		return chain
+	}
+	if len(chain) == 0 {
+		return []*blockDef{self}
+	}
 
-	// Check whether we replace this block by a internal Context or 
-	// if we render the default content
-	child_block, has_childblock := execCtx.internal_context[fmt.Sprintf("block_%s", *args)]
-	if has_childblock {
-		// Use the prerendered child's data as output
-		str, is_string := child_block.(*string)
-		if !is_string {
-			panic("Internal error; internal block string is NOT a string. Please report this issue.")
+	for _, def := range chain {
+		if def.template == self.template && def.info == self.info {
+			// We're executing a block which is part of the chain (nested block)
+			return chain
 		}
-		// Now we have to ignore the default block
-		_, err := execCtx.ignoreUntilAnyTagNode("endblock")
+	}
+	return append(chain[:len(chain):len(chain)], self)
+}
+
+// blockOverrides are the block definitions registered by extends-tags (like
+// the block_<name> entries of the internal context); they are used to walk
+// an inheritance chain without executing it (see generator and checker).
+type blockOverrides map[string][]*blockDef
+
+// register registers every block of tpl as an override (see extendBase).
+func (o blockOverrides) register(tpl *Template) {
+	for name, bi := range tpl.blocks {
+		chain := o[name]
+		o[name] = append(chain[:len(chain):len(chain)], &blockDef{
+			template: tpl,
+			info:     bi,
+		})
+	}
+}
+
+// chain returns all definitions of a block like executionContext.blockChain.
+func (o blockOverrides) chain(tpl *Template, name string) []*blockDef {
+	self := &blockDef{
+		template: tpl,
+		info:     tpl.blocks[name],
+	}
+
+	chain := o[name]
+	if len(chain) == 0 {
+		return []*blockDef{self}
+	}
+	for _, def := range chain {
+		if def.template == self.template && def.info == self.info {
+			return chain
+		}
+	}
+	return append(chain[:len(chain):len(chain)], self)
+}
+
+// renderBlock renders chain[idx] and provides the rendered content of
+// chain[idx+1] as {{ block.super }}.
+func (execCtx *executionContext) renderBlock(chain []*blockDef, idx int, ctx *Context) (*string, error) {
+	def := chain[idx]
+
+	var super renderedBlock
+	if def.info.uses_super && idx+1 < len(chain) {
+		super_str, err := execCtx.renderBlock(chain, idx+1, ctx)
 		if err != nil {
 			return nil, err
 		}
-
-		// Return the prerendered data
-		return str, nil
+		super = renderedBlock(*super_str)
 	}
 
-	// Execute default nodes
-	_, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "endblock")
+	// Provide {{ block.super }} and restore the outer block afterwards
+	outer_block, has_outer_block := (*ctx)["block"]
+	(*ctx)["block"] = Context{"super": super}
+	defer func() {
+		if has_outer_block {
+			(*ctx)["block"] = outer_block
+		} else {
+			delete(*ctx, "block")
+		}
+	}()
+
+	// Share the internal context, so nested blocks are overridden as well
+	blockExecCtx := newExecutionContext(def.template, &execCtx.internal_context)
+	blockExecCtx.interpret = execCtx.interpret
+	blockExecCtx.state = execCtx.state
+	if body, is_compiled := def.template.compiledBlock(def.info.start); is_compiled && !execCtx.interpret {
+		var out strings.Builder
+		if err := runCompiled(body, blockExecCtx, ctx, &out); err != nil && err != errStopExecution {
+			return nil, err
+		}
+		outputString := out.String()
+		return &outputString, nil
+	}
+	blockExecCtx.node_pos = def.info.start
+	_, str_items, err := blockExecCtx.executeUntilAnyTagNode(ctx, "endblock")
 	if err != nil {
 		return nil, err
 	}
-	renderedStrings = append(renderedStrings, (*str_items)...)
 
-	outputString := strings.Join(renderedStrings, "")
+	outputString := strings.Join(*str_items, "")
 	return &outputString, nil
 }
 
+func tagBlock(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
+	if _, has_block := execCtx.template.blocks[*args]; !has_block {
+		return nil, errors.New(fmt.Sprintf("Block '%s' is not indexed. Please report this issue.", *args))
+	}
+
+	// Render the most derived definition of this block (which might be
+	// this one if it's not overridden by a child template)
+	out, err := execCtx.renderBlock(execCtx.blockChain(*args), 0, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the default content of this block
+	execCtx.node_pos = execCtx.template.blocks[*args].end
+
+	return out, nil
+}
+
+func tagBlockIgnore(args *string, execCtx *executionContext) error {
+	bi, has_block := execCtx.template.blocks[*args]
+	if !has_block {
+		return errors.New(fmt.Sprintf("Block '%s' is not indexed. Please report this issue.", *args))
+	}
+	execCtx.node_pos = bi.end
+	return nil
+}
+
 func tagTrim(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
 	renderedStrings := make([]string, 0, len(execCtx.template.nodes)-execCtx.node_pos)
 
@@ -677,27 +832,42 @@ func tagRemove(args *string, execCtx *executionContext, ctx *Context) (*string,
 	renderedStrings = append(renderedStrings, (*str_items)...)
 	outputString := strings.Join(renderedStrings, "")
 
-	// Parse args {% remove "abc","def","ghj" %}
-	patterns := *splitArgs(args, ",")
-	if len(patterns) == 0 {
+	patterns, err := parseRemovePatterns(*args)
+	if err != nil {
+		return nil, err
+	}
+	return removePatterns(outputString, patterns, ctx)
+}
+
+// parseRemovePatterns parses the args of a remove-tag: {% remove "abc","def","ghj" %}
+func parseRemovePatterns(args string) ([]*expr, error) {
+	raw_patterns := *splitArgs(&args, ",")
+	if len(raw_patterns) == 0 {
 		// default patterns (spaces, tabs, new lines)
-		patterns = []string{"\" \"", "\"\t\"", "\"\n\"", "\"\r\""}
+		raw_patterns = []string{"\" \"", "\"\t\"", "\"\n\"", "\"\r\""}
 	}
 
-	// Do remove all the patterns
-	for _, pattern := range patterns {
+	patterns := make([]*expr, 0, len(raw_patterns))
+	for _, pattern := range raw_patterns {
 		e, err := newExpr(&pattern)
 		if err != nil {
 			return nil, err
 		}
+		patterns = append(patterns, e)
+	}
+	return patterns, nil
+}
+
+// removePatterns removes all (evaluated) patterns from str.
+func removePatterns(str string, patterns []*expr, ctx *Context) (*string, error) {
+	for _, e := range patterns {
 		evaledPattern, err := e.evalString(ctx)
 		if err != nil {
 			return nil, err
 		}
-		outputString = strings.Replace(outputString, *evaledPattern, "", -1)
+		str = strings.Replace(str, *evaledPattern, "", -1)
 	}
-
-	return &outputString, nil
+	return &str, nil
 }
 
 func tagRemoveIgnore(args *string, execCtx *executionContext) error {
@@ -708,63 +878,195 @@ func tagRemoveIgnore(args *string, execCtx *executionContext) error {
 	return nil
 }
 
-func createBaseTplForExtendInclude(args string, tpl *Template, ctx *Context) (*Template, error) {
+// loadingRef is an entry of the chain of templates which are currently loaded
+// or executed (the extending/including templates).
+type loadingRef struct {
+	name    string
+	include bool // whether this template references the next one via a non-static include
+}
+
+func parseExtendsArgs(args string) (*expr, bool, error) {
 	// Skip an optional static flag at the beginning
-	if strings.HasPrefix(args, "static ") {
+	static := strings.HasPrefix(args, "static ")
+	if static {
 		args = args[len("static "):]
 	}
 
-	// Example: {% extends/include "base.html" abc=<expr> ghi=<expr> ... %}
+	// Example: {% extends "base.html" %}
 	_args := strings.Split(args, " ")
 	if len(_args) <= 0 {
-		return nil, errors.New("Please provide at least a filename to extend from.")
+		return nil, false, errors.New("Please provide at least a filename to extend from.")
 	}
 	e, err := newExpr(&_args[0])
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
+
+	return e, static, nil
+}
+
+// locateTemplate evaluates the template name expression and looks the template
+// content up using the locator of tpl.
+func locateTemplate(e *expr, tpl *Template, ctx *Context) (*string, *string, error) {
 	name, err := e.evalString(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	//raw_context := _args[1:] // TODO
 	if strings.TrimSpace(*name) == "" {
-		return nil, errors.New("Please provide a propper template filename (empty or an expression evaluating to an empty string is not allowed).")
+		return nil, nil, errors.New("Please provide a propper template filename (empty or an expression evaluating to an empty string is not allowed).")
 	}
 
-	// Create new template
 	if tpl.locator == nil {
-		panic(fmt.Sprintf("Please provide a template locator to lookup template '%v'.", *name))
+		return nil, nil, errors.New(fmt.Sprintf("Please provide a template locator to lookup template '%v'.", *name))
+	}
+	if err := tpl.sandbox.checkTemplate(*name); err != nil {
+		return nil, nil, err
+	}
+
+	content, err := tpl.locator(name)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	base_tpl_content, err := tpl.locator(name)
+	return content, name, nil
+}
+
+// checkCycle returns an error if loading the template name from the last template
+// of chain results in a cyclic dependency. If the cycle contains a non-static
+// include, it's a recursive include (which can terminate during execution) and
+// true is returned instead.
+func checkCycle(name string, chain []loadingRef) (bool, error) {
+	for idx, ref := range chain {
+		if ref.name != name {
+			continue
+		}
+
+		names := make([]string, 0, len(chain)-idx+1)
+		recursive := false
+		for _, cref := range chain[idx:] {
+			names = append(names, cref.name)
+			recursive = recursive || cref.include
+		}
+		if recursive {
+			return true, nil
+		}
+
+		names = append(names, name)
+		return false, errors.New(fmt.Sprintf("Cyclic template dependency detected: %s", strings.Join(names, " -> ")))
+	}
+	return false, nil
+}
+
+// parseDependency parses a template which is referenced by an extends/include-tag.
+func parseDependency(name *string, content *string, tpl *Template, chain []loadingRef) (*Template, error) {
+	dep_tpl, err := newTemplate(*name, content, tpl.locator)
+	if err != nil {
+		return nil, err
+	}
+	dep_tpl.loading = chain
+	dep_tpl.sandbox = tpl.sandbox
+
+	err = dep_tpl.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return dep_tpl, nil
+}
+
+// resolveDependency parses a template referenced by tpl during the preparation
+// phase and records it in the dependency graph of tpl. It returns nil (but no
+// error) for recursive includes.
+func resolveDependency(name *string, content *string, tpl *Template, include bool) (*Template, error) {
+	chain := tpl.loadingChain(include)
+	recursive, err := checkCycle(*name, chain)
 	if err != nil {
 		return nil, err
 	}
+	if recursive {
+		tpl.addDependency(*name, nil)
+		return nil, nil
+	}
 
-	// TODO: Do the pre-rendering (FromString) in the parent's FromString(), just do the execution here.
-	base_tpl, err := FromString(*name, base_tpl_content, tpl.locator)
+	dep_tpl, err := parseDependency(name, content, tpl, chain)
 	if err != nil {
 		return nil, err
 	}
+	tpl.addDependency(*name, dep_tpl)
 
-	return base_tpl, nil
+	return dep_tpl, nil
+}
+
+// loadDependency parses a template referenced by the current template during execution.
+func (execCtx *executionContext) loadDependency(name *string, content *string, include bool) (*Template, *executionContext, error) {
+	if err := execCtx.checkDepth(include); err != nil {
+		return nil, nil, err
+	}
+	chain := execCtx.loadingChain(include)
+
+	// Recursive includes are allowed during execution (limited by MaxTemplateDepth)
+	if _, err := checkCycle(*name, chain); err != nil {
+		return nil, nil, err
+	}
+
+	dep_tpl, err := parseDependency(name, content, execCtx.template, chain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dep_tpl, execCtx.dependencyContext(dep_tpl, include), nil
+}
+
+// checkDepth returns an error if extending/including another template exceeds
+// the maximum template depth.
+func (execCtx *executionContext) checkDepth(include bool) error {
+	if max := execCtx.state.maxDepth(); len(execCtx.loadingChain(include)) > max {
+		return &LimitError{Limit: "MaxDepth", Max: max}
+	}
+	return nil
+}
+
+// dependencyContext creates the execution context for an extended/included template.
+func (execCtx *executionContext) dependencyContext(dep_tpl *Template, include bool) *executionContext {
+	var depExecCtx *executionContext
+	if include {
+		depExecCtx = newExecutionContext(dep_tpl, nil)
+	} else {
+		// Share our internal context with the base template
+		depExecCtx = newExecutionContext(dep_tpl, &execCtx.internal_context)
+	}
+	depExecCtx.loading = execCtx.loadingChain(include)
+	depExecCtx.interpret = execCtx.interpret
+	depExecCtx.state = execCtx.state
+	return depExecCtx
 }
 
 func tagExtendsPrepare(tn *tagNode, tpl *Template) error {
-	// Only prepare, if args starts with "static "
-	if !strings.HasPrefix(tn.tagargs, "static ") {
+	e, static, err := parseExtendsArgs(tn.tagargs)
+	if err != nil {
+		return err
+	}
+
+	// Only prepare, if args starts with "static " or the name is known
+	// in advance (the latter only to check the dependency)
+	if !static && !e.isConstant() {
 		return nil
 	}
 
 	// In preparation-phase we have no Context, so create an empty one.
-	base_tpl, err := createBaseTplForExtendInclude(tn.tagargs, tpl, &Context{})
+	content, name, err := locateTemplate(e, tpl, &Context{})
+	if err != nil {
+		return err
+	}
+	base_tpl, err := resolveDependency(name, content, tpl, false)
 	if err != nil {
 		return err
 	}
 
 	// Save base_tpl
-	tpl.cache[fmt.Sprintf("extends_%s", tn.tagargs)] = base_tpl
+	if static {
+		tpl.cache[fmt.Sprintf("extends_%s", tn.tagargs)] = base_tpl
+	}
 
 	return nil
 }
@@ -773,53 +1075,190 @@ func tagExtends(args *string, execCtx *executionContext, ctx *Context) (*string,
 	// Extends executes the base template and passes the blocks via Context 
 
 	// Example: {% extends "base.html" abc=<expr> ghi=<expr> ... %}
+	base_tpl, baseExecCtx, err := execCtx.extendBase(args, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything after the extends-tag is only relevant through its blocks
+	execCtx.node_pos = len(execCtx.template.nodes)
+
+	return base_tpl.execute(ctx, baseExecCtx)
+}
+
+// extendBase loads the base template of an extends-tag and registers every
+// block of the current template (including nested ones) as an override; the
+// base template renders them in place of its own blocks.
+func (execCtx *executionContext) extendBase(args *string, ctx *Context) (*Template, *executionContext, error) {
 	var base_tpl *Template
+	var baseExecCtx *executionContext
 	_base_tpl, has_precached := execCtx.template.cache[fmt.Sprintf("extends_%s", *args)]
 	if has_precached {
+		if err := execCtx.checkDepth(false); err != nil {
+			return nil, nil, err
+		}
 		base_tpl = _base_tpl.(*Template)
+		baseExecCtx = execCtx.dependencyContext(base_tpl, false)
 	} else {
 		// Get dynamic
-		_base_tpl, err := createBaseTplForExtendInclude(*args, execCtx.template, ctx)
+		e, _, err := parseExtendsArgs(*args)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		content, name, err := locateTemplate(e, execCtx.template, ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		base_tpl, baseExecCtx, err = execCtx.loadDependency(name, content, false)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Since the most derived template is executed first, every chain is ordered
+	// from the most derived to the least derived definition.
+	for name, bi := range execCtx.template.blocks {
+		key := fmt.Sprintf("block_%s", name)
+		var chain []*blockDef
+		if _chain, has_chain := execCtx.internal_context[key]; has_chain {
+			chain = _chain.([]*blockDef)
+		}
+		execCtx.internal_context[key] = append(chain, &blockDef{
+			template: execCtx.template,
+			info:     bi,
+		})
+	}
+
+	return base_tpl, baseExecCtx, nil
+}
+
+// includeArgs contains the parsed arguments of an include-tag. Syntax:
+//     {% include [static] <name> [ignore missing] [with] [key=<expr> ...] [only] %}
+type includeArgs struct {
+	static         bool
+	name           *expr
+	ignore_missing bool // no error is raised if the locator can't find the template
+	only           bool // the included template only sees the given variables
+	with_keys      []string
+	with_exprs     []*expr
+}
+
+func parseIncludeArgs(args string) (*includeArgs, error) {
+	ia := &includeArgs{}
+
+	if strings.HasPrefix(args, "static ") {
+		ia.static = true
+		args = args[len("static "):]
+	}
+
+	parts := make([]string, 0, 5)
+	for _, part := range *splitArgs(&args, " ") {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("Please provide at least a filename to include.")
+	}
+
+	e, err := newExpr(&parts[0])
+	if err != nil {
+		return nil, err
+	}
+	ia.name = e
+	parts = parts[1:]
+
+	if len(parts) >= 2 && parts[0] == "ignore" && parts[1] == "missing" {
+		ia.ignore_missing = true
+		parts = parts[2:]
+	}
+
+	if len(parts) > 0 && parts[len(parts)-1] == "only" {
+		ia.only = true
+		parts = parts[:len(parts)-1]
+	}
+
+	if len(parts) > 0 && parts[0] == "with" {
+		parts = parts[1:]
+		if len(parts) == 0 {
+			return nil, errors.New("Include's 'with' requires at least one key=<expr> argument.")
 		}
-		base_tpl = _base_tpl
 	}
 
-	// Execute every 'block' and store it's result as "block_%s" in the internal Context
-	for {
-		node, err := execCtx.ignoreUntilAnyTagNode("block")
+	for _, part := range parts {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || !exprIdentChecker.MatchString(kv[0]) || strings.Contains(kv[0], ".") {
+			return nil, errors.New(fmt.Sprintf("Include argument '%s' must be of the form key=<expr>.", part))
+		}
+		e, err := newExpr(&kv[1])
 		if err != nil {
-			// No block left
-			break
+			return nil, err
+		}
+		ia.with_keys = append(ia.with_keys, kv[0])
+		ia.with_exprs = append(ia.with_exprs, e)
+	}
+
+	return ia, nil
+}
+
+// includeContext builds the Context the included template is executed with. The
+// caller's Context is never modified.
+func (ia *includeArgs) includeContext(ctx *Context) (*Context, error) {
+	include_ctx := make(Context, len(ia.with_keys))
+	if !ia.only {
+		for k, v := range *ctx {
+			include_ctx[k] = v
 		}
-		blockname := node.tagargs
-		node, str_items, err := execCtx.executeUntilAnyTagNode(ctx, "endblock")
+	} else {
+		// Internal keys aren't variables
+		for _, k := range []string{GoContextKey, sandboxKey, renderStateKey, LocaleKey, TranslatorKey} {
+			if v, has_key := (*ctx)[k]; has_key {
+				include_ctx[k] = v
+			}
+		}
+	}
+
+	for idx, key := range ia.with_keys {
+		value, err := ia.with_exprs[idx].evalPointer(ctx)
 		if err != nil {
 			return nil, err
 		}
-		rendered_string := strings.Join(*str_items, "")
-		execCtx.internal_context[fmt.Sprintf("block_%s", blockname)] = &rendered_string
+		include_ctx[key] = value
 	}
 
-	// Share our internal context with the base template
-	return base_tpl.execute(ctx, newExecutionContext(base_tpl, &execCtx.internal_context))
+	return &include_ctx, nil
 }
 
 func tagIncludePrepare(tn *tagNode, tpl *Template) error {
-	// Only prepare, if args starts with "static "
-	if !strings.HasPrefix(tn.tagargs, "static ") {
+	ia, err := parseIncludeArgs(tn.tagargs)
+	if err != nil {
+		return err
+	}
+	tpl.cache[fmt.Sprintf("include_args_%s", tn.tagargs)] = ia
+
+	// Only prepare, if args starts with "static " or the name is known
+	// in advance (the latter only to check the dependency)
+	if !ia.static && !ia.name.isConstant() {
 		return nil
 	}
 
 	// In preparation-phase we have no Context, so create an empty one.
-	base_tpl, err := createBaseTplForExtendInclude(tn.tagargs, tpl, &Context{})
+	content, name, err := locateTemplate(ia.name, tpl, &Context{})
+	if err != nil {
+		if ia.ignore_missing {
+			return nil
+		}
+		return err
+	}
+	base_tpl, err := resolveDependency(name, content, tpl, !ia.static)
 	if err != nil {
 		return err
 	}
 
-	// Save base_tpl
-	tpl.cache[fmt.Sprintf("include_%s", tn.tagargs)] = base_tpl
+	// Save base_tpl (static includes can't be recursive, so it's never nil here)
+	if ia.static {
+		tpl.cache[fmt.Sprintf("include_%s", tn.tagargs)] = base_tpl
+	}
 
 	return nil
 }
@@ -827,18 +1266,52 @@ func tagIncludePrepare(tn *tagNode, tpl *Template) error {
 func tagInclude(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
 	// Includes a template and executes it 
 
+	var ia *includeArgs
+	_ia, has_args := execCtx.template.cache[fmt.Sprintf("include_args_%s", *args)]
+	if has_args {
+		ia = _ia.(*includeArgs)
+	} else {
+		// Tag was not prepared (e. g. a custom Prepare-function is in place)
+		_ia, err := parseIncludeArgs(*args)
+		if err != nil {
+			return nil, err
+		}
+		ia = _ia
+	}
+
 	var base_tpl *Template
+	var baseExecCtx *executionContext
 	_base_tpl, has_precached := execCtx.template.cache[fmt.Sprintf("include_%s", *args)]
 	if has_precached {
+		if err := execCtx.checkDepth(true); err != nil {
+			return nil, err
+		}
 		base_tpl = _base_tpl.(*Template)
+		baseExecCtx = execCtx.dependencyContext(base_tpl, true)
+	} else if ia.static {
+		// Static template was missing during preparation and is ignored
+		empty := ""
+		return &empty, nil
 	} else {
 		// Get dynamic
-		_base_tpl, err := createBaseTplForExtendInclude(*args, execCtx.template, ctx)
+		content, name, err := locateTemplate(ia.name, execCtx.template, ctx)
+		if err != nil {
+			if ia.ignore_missing {
+				empty := ""
+				return &empty, nil
+			}
+			return nil, err
+		}
+		base_tpl, baseExecCtx, err = execCtx.loadDependency(name, content, true)
 		if err != nil {
 			return nil, err
 		}
-		base_tpl = _base_tpl
 	}
 
-	return base_tpl.Execute(ctx)
+	include_ctx, err := ia.includeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return base_tpl.execute(include_ctx, baseExecCtx)
 }
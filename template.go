@@ -1,6 +1,7 @@
 package pongo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -56,6 +57,9 @@ type executionContext struct {
 	template         *Template
 	node_pos         int
 	internal_context Context
+	loading          []loadingRef // templates which extend/include this one
+	interpret        bool         // whether to use the interpreter instead of the compiled program
+	state            *renderState // shared by all execution contexts of an execution (nil if unlimited)
 }
 
 type templateLocator func(*string) (*string, error)
@@ -85,6 +89,29 @@ type Template struct {
 	// Static content (doesn't change with execution)
 	cache map[string]interface{}
 
+	// All blocks of this template (including nested ones), indexed by name
+	blocks map[string]*blockInfo
+
+	// Dependency graph (templates referenced by extends/include tags)
+	loading       []loadingRef         // templates which are loading this one (during parsing)
+	dependencies  []string             // names of the referenced templates
+	dep_templates map[string]*Template // parsed referenced templates (nil for recursive includes)
+
+	// Compiled template (see program)
+	program *program
+
+	// Policy for untrusted templates (see Sandbox)
+	sandbox *Sandbox
+
+	// Expected shape of the Context (see Bind)
+	shape *contextShape
+
+	// Limits of every execution (see SetLimits)
+	limits Limits
+
+	// Locale of every execution (see SetLocale)
+	locale string
+
 	// Debugging
 	debug bool
 }
@@ -252,7 +279,9 @@ func (cn *contentNode) getLine() int        { return cn.line }
 func (cn *contentNode) getContent() *string { return &cn.content }
 
 func (cn *contentNode) execute(execCtx *executionContext, ctx *Context) (*string, error) {
-
+	if err := execCtx.state.write(len(cn.content)); err != nil {
+		return nil, err
+	}
 	return &cn.content, nil
 }
 
@@ -271,6 +300,11 @@ func addFilterNode(tpl *Template) error {
 	if err != nil {
 		return err
 	}
+	for _, filter := range e.filters {
+		if err := tpl.sandbox.checkFilter(filter.name); err != nil {
+			return err
+		}
+	}
 
 	// Add 'safe' filter to those filter calls to make them
 	// safe
@@ -298,7 +332,13 @@ func (fn *filterNode) execute(execCtx *executionContext, ctx *Context) (*string,
 		return "", err, 0
 	}*/
 	//return out, nil, 1
-	return out, err
+	if err != nil {
+		return nil, err
+	}
+	if err := execCtx.state.write(len(*out)); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 func addTagNode(tpl *Template) error {
@@ -327,6 +367,12 @@ func addTagNode(tpl *Template) error {
 	if !has_tag {
 		return errors.New(fmt.Sprintf("Tag '%s' does not exist", tagname))
 	}
+	if tag != nil {
+		// Placeholders (like endif) are always allowed
+		if err := tpl.sandbox.checkTag(tagname); err != nil {
+			return err
+		}
+	}
 
 	tn.tagname = tagname
 	tn.tagargs = strings.TrimSpace(tagargs)
@@ -382,6 +428,10 @@ func Must(t *Template, err error) *Template {
 // one will be created to search for files in the same directory the template
 // file is located. file_path can either be an absolute filepath or a relative one.
 func FromFile(file_path string, locator templateLocator) (*Template, error) {
+	return fromFile(file_path, locator, nil)
+}
+
+func fromFile(file_path string, locator templateLocator, sandbox *Sandbox) (*Template, error) {
 	var err error
 
 	// What is file_path?
@@ -421,25 +471,23 @@ func FromFile(file_path string, locator templateLocator) (*Template, error) {
 	name := filepath.Base(file_path)
 
 	strbuf := string(buf)
-	tpl, err := newTemplate(name, &strbuf, locator)
-	if err != nil {
-		return nil, err
-	}
-
-	err = tpl.parse()
-	if err != nil {
-		return nil, err
-	}
-
-	return tpl, nil
+	return parseTemplate(name, &strbuf, locator, sandbox)
 }
 
 // Creates a new template instance from string.
 func FromString(name string, tplstr *string, locator templateLocator) (*Template, error) {
+	return parseTemplate(name, tplstr, locator, nil)
+}
+
+func parseTemplate(name string, tplstr *string, locator templateLocator, sandbox *Sandbox) (*Template, error) {
 	tpl, err := newTemplate(name, tplstr, locator)
 	if err != nil {
 		return nil, err
 	}
+	if sandbox != nil {
+		tpl.sandbox = sandbox
+		tpl.limits = sandbox.Limits
+	}
 
 	err = tpl.parse()
 	if err != nil {
@@ -465,6 +513,9 @@ func newTemplate(name string, tplstr *string, locator templateLocator) (*Templat
 		autosafe: true,
 		locator:  locator,
 		cache:    make(map[string]interface{}),
+		blocks:   make(map[string]*blockInfo),
+
+		dep_templates: make(map[string]*Template),
 	}
 
 	return tpl, nil
@@ -487,11 +538,123 @@ func (tpl *Template) parse() error {
 		return errors.New(fmt.Sprintf("[Parsing error: %s] [Line %d, Column %d] %s", tpl.name, tpl.line, tpl.col, tpl.parseErr))
 	}
 
+	if err := tpl.indexBlocks(); err != nil {
+		return err
+	}
+
+	tpl.compile()
+
 	tpl.parsed = true
 
 	return nil
 }
 
+// indexBlocks looks up all (possibly nested) blocks and remembers their start and
+// end position, so they can be overridden by a child template and rendered
+// out of order.
+func (tpl *Template) indexBlocks() error {
+	open_blocks := make([]*blockInfo, 0, 5)
+
+	for pos, node := range tpl.nodes {
+		if fn, is_filter := node.(*filterNode); is_filter {
+			if len(open_blocks) > 0 && fn.e.usesVar("block") {
+				open_blocks[len(open_blocks)-1].uses_super = true
+			}
+			continue
+		}
+		tn, is_tag := node.(*tagNode)
+		if !is_tag {
+			continue
+		}
+
+		switch tn.tagname {
+		case "block":
+			name := tn.tagargs
+			if !exprIdentChecker.MatchString(name) || strings.Contains(name, ".") {
+				return tpl.nodeError(tn, fmt.Sprintf("Block name '%s' must only contain A-Za-z0-9_", name))
+			}
+			if _, has_block := tpl.blocks[name]; has_block {
+				return tpl.nodeError(tn, fmt.Sprintf("Block '%s' is defined more than once", name))
+			}
+			bi := &blockInfo{
+				name:  name,
+				start: pos,
+			}
+			tpl.blocks[name] = bi
+			open_blocks = append(open_blocks, bi)
+		case "endblock":
+			if len(open_blocks) == 0 {
+				return tpl.nodeError(tn, "Found an endblock without a corresponding block")
+			}
+			bi := open_blocks[len(open_blocks)-1]
+			if tn.tagargs != "" && tn.tagargs != bi.name {
+				return tpl.nodeError(tn, fmt.Sprintf("Endblock '%s' does not match block '%s'", tn.tagargs, bi.name))
+			}
+			bi.end = pos
+			open_blocks = open_blocks[:len(open_blocks)-1]
+		default:
+			if len(open_blocks) > 0 && mentionsVar(tn.tagargs, "block") {
+				open_blocks[len(open_blocks)-1].uses_super = true
+			}
+		}
+	}
+
+	if len(open_blocks) > 0 {
+		bi := open_blocks[len(open_blocks)-1]
+		return tpl.nodeError(tpl.nodes[bi.start], fmt.Sprintf("Block '%s' is not closed (missing endblock)", bi.name))
+	}
+
+	return nil
+}
+
+func (tpl *Template) nodeError(n node, msg string) error {
+	return errors.New(fmt.Sprintf("[Parsing error: %s] [Line %d, Column %d] %s", tpl.name, n.getLine(), n.getCol(), msg))
+}
+
+// Returns the names of all templates this template depends on (directly or
+// indirectly) through extends/include tags, e. g. to watch them for changes.
+// Templates whose name is only known during execution (like
+// {% include tpl_name %}) are not part of the list.
+func (tpl *Template) Dependencies() []string {
+	deps := make([]string, 0, len(tpl.dependencies))
+	seen := make(map[string]bool)
+
+	var walk func(*Template)
+	walk = func(t *Template) {
+		for _, name := range t.dependencies {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			deps = append(deps, name)
+
+			if dep_tpl := t.dep_templates[name]; dep_tpl != nil {
+				walk(dep_tpl)
+			}
+		}
+	}
+	walk(tpl)
+
+	return deps
+}
+
+func (tpl *Template) addDependency(name string, dep_tpl *Template) {
+	if _, has_dep := tpl.dep_templates[name]; !has_dep {
+		tpl.dependencies = append(tpl.dependencies, name)
+	}
+	if dep_tpl != nil || tpl.dep_templates[name] == nil {
+		tpl.dep_templates[name] = dep_tpl
+	}
+}
+
+// loadingChain returns the chain of templates which are loading this template
+// including the template itself.
+func (tpl *Template) loadingChain(include bool) []loadingRef {
+	chain := make([]loadingRef, len(tpl.loading), len(tpl.loading)+1)
+	copy(chain, tpl.loading)
+	return append(chain, loadingRef{name: tpl.name, include: include})
+}
+
 // Executes the template with the given context and writes to http.ResponseWriter
 // on success. Context can be nil. Nothing is written on error; instead the error
 // is being returned.
@@ -506,24 +669,132 @@ func (tpl *Template) ExecuteRW(w http.ResponseWriter, ctx *Context) error {
 
 // Executes the template with the given context (can be nil).
 func (tpl *Template) Execute(ctx *Context) (out *string, err error) {
-	defer func() {
-		rerr := recover()
-		if rerr != nil {
-			// Panic recovered
-			out = nil
-			err = errors.New(fmt.Sprintf("Pongo panicked with this error (please report this issue, see console output! You can see the stack trace when activating debugging: tpl.SetDebug(true)): %s", rerr))
-
-			if tpl.debug {
-				fmt.Println("*************************************************************************")
-				fmt.Println("Due to panicking of pongo, I'm printing the error message and stack here.")
-				fmt.Printf("Panic message: %s\n", rerr)
-				fmt.Println("*************************************************************************")
-				debug.PrintStack()
-				fmt.Println("*************************************************************************")
+	defer tpl.recoverPanic(&out, &err)
+	return tpl.execute(ctx, nil)
+}
+
+// Executes the template with data as root context: identifiers resolve against
+// the exported methods and fields of a struct (or a pointer to it) or the keys
+// of a map with string keys, following the same rules as accessing them within
+// the template (e. g. {{ Title }} is like {{ data.Title }}). data can be a
+// Context as well (or nil).
+func (tpl *Template) ExecuteValue(data interface{}) (out *string, err error) {
+	return tpl.Execute(valueContext(data))
+}
+
+// Executes the template like ExecuteValue, but stops as soon as go_ctx is
+// cancelled (checked before every node and loop iteration); the error of
+// go_ctx is returned then. go_ctx is passed to methods taking a
+// context.Context (see GoContextKey) unless data provides another one.
+func (tpl *Template) ExecuteContext(go_ctx context.Context, data interface{}) (out *string, err error) {
+	defer tpl.recoverPanic(&out, &err)
+
+	// The caller's Context is never modified
+	ctx := Context{GoContextKey: go_ctx}
+	if data_ctx := valueContext(data); data_ctx != nil {
+		for k, v := range *data_ctx {
+			ctx[k] = v
+		}
+	}
+
+	execCtx := newExecutionContext(tpl, nil)
+	execCtx.state = tpl.newRenderState(go_ctx)
+	return tpl.execute(&ctx, execCtx)
+}
+
+// valueContext returns the Context to execute a template with data as root
+// context (see ExecuteValue).
+func valueContext(data interface{}) *Context {
+	switch v := data.(type) {
+	case nil:
+		return nil
+	case *Context:
+		return v
+	case Context:
+		return &v
+	case map[string]interface{}:
+		ctx := Context(v)
+		return &ctx
+	}
+	return &Context{rootValueKey: data}
+}
+
+// Executes only the block with the given name (e. g. to render a part of a page
+// for a partial update) using the given context (can be nil). The block is looked
+// up along the extends-chain of the template, so it's rendered the same way as
+// within the whole page (including overrides and {{ block.super }}); the content
+// surrounding the block is not rendered.
+func (tpl *Template) ExecuteBlock(name string, ctx *Context) (out *string, err error) {
+	defer tpl.recoverPanic(&out, &err)
+
+	if ctx == nil {
+		ctx = &Context{}
+	}
+	defer tpl.enterSandbox(ctx)()
+	defer tpl.enterLocale(ctx)()
+
+	// Walk up the extends-chain and register all block overrides on our way
+	execCtx := newExecutionContext(tpl, nil)
+	execCtx.state = tpl.newRenderState(nil)
+	for {
+		extends_node, err := execCtx.template.extendsNode()
+		if err != nil {
+			return nil, fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", execCtx.template.name, extends_node.getLine(), extends_node.getCol(), *extends_node.getContent(), err)
+		}
+		if extends_node == nil {
+			break
+		}
+		_, baseExecCtx, err := execCtx.extendBase(&extends_node.tagargs, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", execCtx.template.name, extends_node.getLine(), extends_node.getCol(), *extends_node.getContent(), err)
+		}
+		execCtx = baseExecCtx
+	}
+
+	chain := execCtx.blockChain(name)
+	if len(chain) == 0 {
+		return nil, errors.New(fmt.Sprintf("[Error: %s] Block '%s' not found.", tpl.name, name))
+	}
+	defer execCtx.enterRenderState(ctx)()
+
+	return execCtx.renderBlock(chain, 0, ctx)
+}
+
+// extendsNode returns the extends-tag of the template or nil if the
+// template doesn't extend another one. The extends-tag must be the first
+// node (apart from whitespace), otherwise it's returned with an error.
+func (tpl *Template) extendsNode() (*tagNode, error) {
+	first := true
+	for _, node := range tpl.nodes {
+		if tn, is_tag := node.(*tagNode); is_tag && tn.tagname == "extends" {
+			if !first {
+				return tn, errors.New("Extends must be the first tag of the template.")
 			}
+			return tn, nil
 		}
-	}()
-	return tpl.execute(ctx, nil)
+		if cn, is_content := node.(*contentNode); !is_content || strings.TrimSpace(cn.content) != "" {
+			first = false
+		}
+	}
+	return nil, nil
+}
+
+func (tpl *Template) recoverPanic(out **string, err *error) {
+	rerr := recover()
+	if rerr != nil {
+		// Panic recovered
+		*out = nil
+		*err = errors.New(fmt.Sprintf("Pongo panicked with this error (please report this issue, see console output! You can see the stack trace when activating debugging: tpl.SetDebug(true)): %s", rerr))
+
+		if tpl.debug {
+			fmt.Println("*************************************************************************")
+			fmt.Println("Due to panicking of pongo, I'm printing the error message and stack here.")
+			fmt.Printf("Panic message: %s\n", rerr)
+			fmt.Println("*************************************************************************")
+			debug.PrintStack()
+			fmt.Println("*************************************************************************")
+		}
+	}
 }
 
 // pongo will print out a stacktrace whenever it panics if set to true.
@@ -544,19 +815,35 @@ func newExecutionContext(tpl *Template, internalContext *Context) *executionCont
 	}
 }
 
+// loadingChain returns the chain of templates which are executing this template
+// including the template itself.
+func (execCtx *executionContext) loadingChain(include bool) []loadingRef {
+	chain := make([]loadingRef, len(execCtx.loading), len(execCtx.loading)+1)
+	copy(chain, execCtx.loading)
+	return append(chain, loadingRef{name: execCtx.template.name, include: include})
+}
+
 func (tpl *Template) execute(ctx *Context, execCtx *executionContext) (*string, error) {
 	if execCtx == nil {
 		execCtx = newExecutionContext(tpl, nil)
+		execCtx.state = tpl.newRenderState(nil)
 	}
 
 	if ctx == nil {
 		ctx = &Context{}
 	}
+	defer tpl.enterSandbox(ctx)()
+	defer tpl.enterLocale(ctx)()
+	defer execCtx.enterRenderState(ctx)()
 
 	return execCtx.execute(ctx)
 }
 
 func (execCtx *executionContext) execute(ctx *Context) (*string, error) {
+	if execCtx.template.program != nil && !execCtx.interpret {
+		return execCtx.executeCompiled(ctx)
+	}
+
 	renderedStrings := make([]string, 0, len(execCtx.template.nodes))
 
 	// TODO: We could replace this code by executeUntilAnyTagNode(ctx), but
@@ -565,9 +852,9 @@ func (execCtx *executionContext) execute(ctx *Context) (*string, error) {
 	execCtx.node_pos = 0
 	for execCtx.node_pos < len(execCtx.template.nodes) {
 		node := execCtx.template.nodes[execCtx.node_pos]
-		str, err := node.execute(execCtx, ctx)
+		str, err := execCtx.executeNode(node, ctx)
 		if err != nil {
-			return nil, errors.New(fmt.Sprintf("[Error: %s] [Line %d Col %d (%s)] %s", execCtx.template.name, node.getLine(), node.getCol(), *node.getContent(), err))
+			return nil, fmt.Errorf("[Error: %s] [Line %d Col %d (%s)] %w", execCtx.template.name, node.getLine(), node.getCol(), *node.getContent(), err)
 		}
 		renderedStrings = append(renderedStrings, *str)
 
@@ -579,6 +866,14 @@ func (execCtx *executionContext) execute(ctx *Context) (*string, error) {
 	return &outputString, nil
 }
 
+// executeNode executes n unless the execution was cancelled.
+func (execCtx *executionContext) executeNode(n node, ctx *Context) (*string, error) {
+	if err := execCtx.state.check(); err != nil {
+		return nil, err
+	}
+	return n.execute(execCtx, ctx)
+}
+
 func (execCtx *executionContext) executeUntilAnyTagNode(ctx *Context, nodenames ...string) (*tagNode, *[]string, error) {
 	renderedStrings := make([]string, 0, len(execCtx.template.nodes)-execCtx.node_pos)
 
@@ -597,9 +892,9 @@ func (execCtx *executionContext) executeUntilAnyTagNode(ctx *Context, nodenames
 				}
 			}
 		}
-		str, err := node.execute(execCtx, ctx)
+		str, err := execCtx.executeNode(node, ctx)
 		if err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("[Error in block-execution: %s] [Line %d Col %d (%s)] %s", execCtx.template.name, node.getLine(), node.getCol(), *node.getContent(), err))
+			return nil, nil, fmt.Errorf("[Error in block-execution: %s] [Line %d Col %d (%s)] %w", execCtx.template.name, node.getLine(), node.getCol(), *node.getContent(), err)
 		}
 		renderedStrings = append(renderedStrings, *str)
 		execCtx.node_pos++
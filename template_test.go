@@ -1,9 +1,16 @@
 package pongo
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"go/format"
+	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -33,6 +40,55 @@ func (p *Person) SayHelloTo(name1, name2 string) string {
 	return fmt.Sprintf("Hello to %s and %s from Flo!", name1, name2)
 }
 
+type Message string
+
+type Color string
+
+func (m Message) Upper() Message {
+	return Message(strings.ToUpper(string(m)))
+}
+
+func (p *Person) Greeting(greeting string) Message {
+	return Message(fmt.Sprintf("%s, %s", greeting, p.Name))
+}
+
+func (p *Person) AgeIn(years int) int {
+	return p.Age + years
+}
+
+func (p *Person) Share(ratio float64) float64 {
+	return p.Accounts["default"] * ratio
+}
+
+func (p *Person) Friend(name string) (*Person, error) {
+	for _, friend := range p.Friends {
+		if friend.Name == name {
+			return friend, nil
+		}
+	}
+	return nil, errFriendNotFound
+}
+
+func (p *Person) Balance(ctx context.Context, account string) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if user, has_user := ctx.Value("user").(string); has_user && user != p.Name {
+		return 0, errors.New("access denied")
+	}
+	return p.Accounts[account], nil
+}
+
+func (p *Person) Split() (string, string) {
+	return p.Name[:1], p.Name[1:]
+}
+
+func (p *Person) Join(sep string, names ...string) string {
+	return strings.Join(append([]string{p.Name}, names...), sep)
+}
+
+var errFriendNotFound = errors.New("friend not found")
+
 var (
 	person = Person{
 		Name: "Florian",
@@ -148,6 +204,82 @@ var standard_tests = []test{
 	{"{{ person.SayHelloTo:\"Cowboy, Mike\",\"Cowboy, Thorsten\" }}", "", Context{"person": person}, ""},                                                      // call w/ args (w/o pointer)
 	{"{{ person.SayHelloTo:5,\"Cowboy, Thorsten\" }}", "", Context{"person": person}, ""},                                                                     // call w/ args (w/o pointer) (wrong arg type)
 
+	// Method calls with parentheses
+	{"{{ person.Greeting(\"Hi\") }}", "Hi, Florian", Context{"person": &person}, ""},
+	{"{{ person.Greeting(\"Hi\").Upper() }}", "HI, FLORIAN", Context{"person": &person}, ""},
+	{"{{ person.Greeting(greeting).Upper }}", "HEY, FLORIAN", Context{"person": &person, "greeting": "Hey"}, ""},
+	{"{{ person.Greeting(greeting|capitalize) }}", "Hey, Florian", Context{"person": &person, "greeting": "hey"}, ""},
+	{"{{ person.Greeting(\"a|b, c.d\") }}", "a|b, c.d, Florian", Context{"person": &person}, ""},
+	{"{{ person.Friends.0.Greeting(\"Hey\").Upper().1 }}", "E", Context{"person": &person}, ""},
+	{"{{ person.SayHelloTo(\"Mike\", person.Friends.0.Name) }}", "Hello to Mike and Georg from Flo!", Context{"person": &person}, ""},
+	{"{{ person.SayHelloTo(\"Mike\") }}", "", Context{"person": &person}, "Method 'SayHelloTo' requires 2 argument(s), 1 given"},
+	{"{{ person.SayHelloTo:\"Mike\" }}", "", Context{"person": &person}, "Method 'person.SayHelloTo' requires 2 argument(s), 1 given"},
+	{"{{ person.Greeting.0 }}", "", Context{"person": &person}, "Method 'Greeting' requires 1 argument(s), 0 given"},
+	{"{{ person.Join(\", \", \"Georg\", \"Mike\") }}", "Florian, Georg, Mike", Context{"person": &person}, ""}, // variadic
+	{"{{ person.Join(\"-\") }}", "Florian", Context{"person": &person}, ""},
+	{"{{ person.Join() }}", "", Context{"person": &person}, "requires at least 1 argument(s), 0 given"},
+	{"{{ person.Unknown(\"x\") }}", "", Context{"person": &person}, ""},
+	{"{{ greet(\"Flo\") }}", "Hello Flo!", Context{"greet": func(name string) string { return "Hello " + name + "!" }}, ""},
+	{"{{ greet(\"Flo\") }}", "", Context{"greet": "no function"}, "is not a function"},
+	{"{{ person.Greeting(\"Hi\" }}", "", Context{"person": &person}, "unclosed parenthesis"},
+	{"{{ person.Greeting(\"Hi\",) }}", "", Context{"person": &person}, "Empty argument"},
+
+	// Methods returning (value, error) and taking a context.Context
+	{"{{ person.Friend(\"Mike\").Age }}", "25", Context{"person": &person}, ""},
+	{"{{ person.Friend(\"Mike\") }}", "{Mike 25 [] map[] 0}", Context{"person": &person}, ""},
+	{"{{ person.Friend(\"Nobody\").Age }}", "", Context{"person": &person}, "[Line 1 Col 32 (person.Friend(\"Nobody\").Age)] Method 'Friend' returned an error: friend not found"},
+	{"{% if true %}{{ person.Friend(\"Nobody\") }}{% endif %}", "", Context{"person": &person}, "Method 'Friend' returned an error: friend not found"},
+	{"{{ person.Balance(\"default\") }}", "1234.56", Context{"person": &person}, ""},
+	{"{{ person.Balance:\"default\" }}", "1234.56", Context{"person": &person}, ""},
+	{"{{ person.Balance(\"default\") }}", "1234.56", Context{"person": &person, GoContextKey: context.WithValue(context.Background(), "user", "Florian")}, ""},
+	{"{{ person.Balance(\"default\") }}", "", Context{"person": &person, GoContextKey: context.WithValue(context.Background(), "user", "Mike")}, "access denied"},
+	{"{{ person.Split }}", "", Context{"person": &person}, "returns more than one value"},
+
+	// Method argument conversion
+	{"{{ person.AgeIn(10) }}", "50", Context{"person": &person}, ""},
+	{"{{ person.AgeIn(\"10\") }}", "50", Context{"person": &person}, ""},
+	{"{{ person.AgeIn(10.0) }}", "50", Context{"person": &person}, ""},
+	{"{{ person.AgeIn(years) }}", "50", Context{"person": &person, "years": int64(10)}, ""},
+	{"{{ person.AgeIn(10.5) }}", "", Context{"person": &person}, "Argument 1 of method 'AgeIn': 10.5 (float64) can't be used as int"},
+	{"{{ person.AgeIn(\"ten\") }}", "", Context{"person": &person}, "Argument 1 of method 'AgeIn': ten (string) can't be used as int"},
+	{"{{ person.AgeIn(true) }}", "", Context{"person": &person}, "can't be used as int"},
+	{"{{ person.Share(1) }}", "1234.56", Context{"person": &person}, ""},
+	{"{{ person.Share(\"0.5\") }}", "617.28", Context{"person": &person}, ""},
+	{"{{ person.Greeting(5) }}", "5, Florian", Context{"person": &person}, ""},
+	{"{{ person.Greeting(0.5) }}", "0.5, Florian", Context{"person": &person}, ""},
+	{"{{ person.Greeting(person) }}", "", Context{"person": &person}, "can't be used as string"},
+
+	// Subscripts
+	{"{{ m[key] }}", "Pongo", Context{"m": map[string]string{"name": "Pongo"}, "key": "name"}, ""},
+	{"{{ m[\"name\"] }}", "Pongo", Context{"m": map[string]string{"name": "Pongo"}}, ""},
+	{"{{ m[missing] }}", "", Context{"m": map[string]string{"name": "Pongo"}}, ""},
+	{"{{ items[i + 1] }}", "c", Context{"items": []string{"a", "b", "c"}, "i": 1}, ""},
+	{"{{ items[-1] }}", "c", Context{"items": []string{"a", "b", "c"}}, ""},
+	{"{{ items[-4] }}", "", Context{"items": []string{"a", "b", "c"}}, ""},
+	{"{{ items[3] }}", "", Context{"items": []string{"a", "b", "c"}}, ""},
+	{"{{ items.-1 }}", "", Context{"items": []string{"a", "b", "c"}}, "must only contain"},
+	{"{{ m[42] }}", "answer", Context{"m": map[int]string{42: "answer"}}, ""},
+	{"{{ m.42 }}", "answer", Context{"m": map[int]string{42: "answer"}}, ""},
+	{"{{ ids[\"7\"] }}", "seven", Context{"ids": map[int]string{7: "seven"}}, ""},
+	{"{{ flags[true] }}", "on", Context{"flags": map[bool]string{true: "on", false: "off"}}, ""},
+	{"{{ colors[\"red\"] }}", "#f00", Context{"colors": map[Color]string{"red": "#f00"}}, ""},
+	{"{{ grid[1][0] }}", "3", Context{"grid": [][]int{{1, 2}, {3, 4}}}, ""},
+	{"{{ person.Friends[n - 1].Name }}", "Philipp", Context{"person": &person, "n": 3}, ""},
+	{"{{ person.Friends[-1].Greeting(\"Hi\") }}", "Hi, Philipp", Context{"person": &person}, ""},
+	{"{{ person[\"Name\"] }}", "Florian", Context{"person": &person}, ""},
+	{"{{ name[0] }}", "P", Context{"name": "Pongo"}, ""},
+	{"{{ name[-1] }}", "o", Context{"name": "Pongo"}, ""},
+	{"{{ items[(i + 1) * 2] }}", "e", Context{"items": []string{"a", "b", "c", "d", "e"}, "i": 1}, ""},
+	{"{{ items[7 % 3] }}", "b", Context{"items": []string{"a", "b", "c"}}, ""},
+	{"{{ items[-i] }}", "c", Context{"items": []string{"a", "b", "c"}, "i": 1}, ""},
+	{"{{ items[1 / zero] }}", "", Context{"items": []string{"a", "b", "c"}, "zero": 0}, "Division by zero"},
+	{"{{ items[name + 1] }}", "", Context{"items": []string{"a", "b", "c"}, "name": "x"}, "Operator '+' can't be applied"},
+	{"{{ m[\"na\" + \"me\"] }}", "Pongo", Context{"m": map[string]string{"name": "Pongo"}}, ""},
+	{"{{ m[key|lower] }}", "Pongo", Context{"m": map[string]string{"name": "Pongo"}, "key": "NAME"}, ""},
+	{"{{ items[1 }}", "", Context{"items": []string{"a", "b"}}, "unclosed bracket"},
+	{"{{ items[] }}", "", Context{"items": []string{"a", "b"}}, "Empty subscript"},
+	{"{{ items[1]x }}", "", Context{"items": []string{"a", "b"}}, "must only contain"},
+
 	// Time samples (no need for a date-filter, because you can simply call time's Format method from Pongo)
 	{"{{ mydate.Format:\"02.01.2006 15:04:05\" }}", "18.08.2012 10:49:12", Context{"mydate": time.Date(2012, time.August, 18, 10, 49, 12, 0, time.Now().Location())}, ""},
 }
@@ -216,14 +348,19 @@ var filter_tests = []test{
 	{"{{ \"<strong><em>Hi Florian!</em></strong>\"|striptags:\"strong,em\" }}", "Hi Florian!", nil, ""},
 	{"{{ \"<strong><em>Hi Florian!</em></strong><img /></img>\"|striptags }}", "Hi Florian!", nil, ""}, // remove all tags
 	{"{{ 5|striptags:\"x\" }}", "", nil, "not of type string"},
-	{"{{ \"\"|striptags:\"x\",123 }}", "", nil, "Please provide a comma-seperated string with tags (or no string to remove all tags)."},
-
-	// Custom 'add' filter (see the TestSuites(*testing.T) function)
-	{"{{ 5|add:7 }}", "12", nil, ""},
-	{"{{ 5|add:7,Seven }}", "19", Context{"Seven": 7}, ""},
-	{"{{ 5|add:7,10 }}", "22", nil, ""},
-	{"{{ 5|add:7,10,25 }}", "47", nil, ""},
-	{"{{ 5|add:10,\"test\" }}", "", nil, "No int: test"},
+	{"{{ \"\"|striptags:\"x\",123 }}", "", nil, "Filter 'striptags' takes 0 to 1 argument(s), 2 given."},
+	{"{{ \"\"|striptags:tags }}", "", Context{"tags": 5}, "Taglist must be a string"},
+	{"{{ html|striptags }}", "Hello World &amp; you", Context{"html": "<p class=\"intro\" title=\"a > b\">Hello <!-- <b>x</b> -->World</p><script>var s = \"<b>\";</script><style>p {}</style> & you"}, ""},
+	{"{{ html|striptags:\"b, EM\"|unsafe }}", "<p>Hi <i>there</i>!</p>", Context{"html": "<p><B class=\"x\">Hi</b> <em id=\"y\"><i>there</i></em>!</p>"}, ""},
+	{"{{ html|striptags:\"script\"|unsafe }}", "<p>Hi</p><!-- c -->", Context{"html": "<p>Hi</p><script src=\"x.js\">alert(\"</p>\")</script><!-- c -->"}, ""},
+	{"{{ html|striptags:\"a+,(b\"|unsafe }}", "<a>x</a>", Context{"html": "<a>x</a>"}, ""},
+
+	// Custom 'sum' filter (see the TestFromString(*testing.T) function)
+	{"{{ 5|sum:7 }}", "12", nil, ""},
+	{"{{ 5|sum:7,Seven }}", "19", Context{"Seven": 7}, ""},
+	{"{{ 5|sum:7,10 }}", "22", nil, ""},
+	{"{{ 5|sum:7,10,25 }}", "47", nil, ""},
+	{"{{ 5|sum:10,\"test\" }}", "", nil, "No int: test"},
 
 	// Floatformat filter
 	{"{{ 34.23234|floatformat }}", "34.2", nil, ""},
@@ -239,6 +376,142 @@ var filter_tests = []test{
 	{"{{ 34.00000|floatformat:\"-3\" }}", "34", nil, ""},
 	{"{{ 34.26000|floatformat:\"-3\" }}", "34.260", nil, ""},
 	{"{{ value|floatformat }}", "NaN", Context{"value" : math.NaN()}, ""},
+	{"{{ 34|floatformat:2 }} {{ \"34.26\"|floatformat }} {{ \"34\"|floatformat }}", "34.00 34.3 34", nil, ""},
+	{"{{ \"abc\"|floatformat }}", "", nil, "Illegal type for floatformat"},
+
+	// String filters
+	{"{{ \"my FIRST post\"|title }}", "My First Post", nil, ""},
+	{"{{ \"they're bill's 1st friends\"|title }}", "They're Bill's 1st Friends", nil, ""},
+	{"{{ \"Joel is a slug\"|truncatechars:7 }}", "Joel i…", nil, ""},
+	{"{{ \"Joel\"|truncatechars:\"7\" }}", "Joel", nil, ""},
+	{"{{ \"Joel\"|truncatechars:\"x\" }}", "", nil, "Filter 'truncatechars' requires an int as argument 1, not string ('x')."},
+	{"{{ \"Joel\"|truncatechars:n }}", "", Context{"n": "x"}, "truncatechars requires an integer argument"},
+	{"{{ \"Joel  is a slug\"|truncatewords:2 }}", "Joel is …", nil, ""},
+	{"{{ \"Joel is\"|truncatewords:2 }}", "Joel is", nil, ""},
+	{"{{ \"<p>Joel <b>is</b> a slug</p>\"|truncatechars_html:7|unsafe }}", "<p>Joel <b>i…</b></p>", nil, ""},
+	{"{{ \"<p>J&amp;el<br>is</p>\"|truncatechars_html:4|unsafe }}", "<p>J&amp;e…</p>", nil, ""},
+	{"{{ \"<p>Joel</p>\"|truncatechars_html:4|unsafe }}", "<p>Joel</p>", nil, ""},
+	{"{{ \" Joel is  a slug \"|wordcount }}", "4", nil, ""},
+	{"{{ text|wordwrap:5 }}", "Joel\nis a\nslug\nfoo\nbarbazqux", Context{"text": "Joel is a slug\nfoo barbazqux"}, ""},
+	{"{{ text|wordwrap:0 }}", "", Context{"text": "Joel"}, "positive width"},
+	{"[{{ \"Joel\"|center:9 }}][{{ \"Jo\"|center:5 }}][{{ \"Joel\"|center:2 }}]", "[   Joel  ][  Jo ][Joel]", nil, ""},
+	{"[{{ \"Joel\"|ljust:6 }}][{{ 42|rjust:\"6\" }}]", "[Joel  ][    42]", nil, ""},
+	{"{{ \"String with spaces\"|cut:\" \" }}", "Stringwithspaces", nil, ""},
+	{"{{ 1001|cut:0 }}", "11", nil, ""},
+	{"{{ \" Jöel is a  slug!_-\"|slugify }}", "joel-is-a-slug", nil, ""},
+	{"{{ \"Ça -- marche\"|slugify }}", "ca-marche", nil, ""},
+	{"{{ text|linebreaks }}", "<p>Joel<br>is &lt;a&gt;</p>\n\n<p>slug</p>", Context{"text": "Joel\r\nis <a>\n\n\nslug"}, ""},
+	{"{{ text|unsafe|linebreaks }}", "<p><b>Joel</b></p>", Context{"text": "<b>Joel</b>"}, ""},
+	{"{{ text|linebreaksbr }}", "Joel<br>is &amp; slug", Context{"text": "Joel\nis & slug"}, ""},
+	{"{{ text|linenumbers }}", "01. a\n02. b\n03. c\n04. d\n05. e\n06. f\n07. g\n08. h\n09. i\n10. &lt;j&gt;", Context{"text": "a\nb\nc\nd\ne\nf\ng\nh\ni\n<j>"}, ""},
+	{"{{ text|addslashes }}", "I\\'m \\\"using\\\" \\\\", Context{"text": "I'm \"using\" \\"}, ""},
+	{"{{ \"Joël\"|make_list|join:\",\" }}", "J,o,ë,l", nil, ""},
+	{"{{ 123|make_list|length }}", "3", nil, ""},
+	{"{{ 10|stringformat:\"04d\" }}", "0010", nil, ""},
+	{"{{ 3.14159|stringformat:\".2f\" }}", "3.14", nil, ""},
+	{"{{ 255|stringformat:\"x\" }}", "ff", nil, ""},
+	{"{{ 3.7|stringformat:\"i\" }}", "3", nil, ""},
+	{"{{ n|stringformat:\"d\" }} {{ n|stringformat:\"x\" }} {{ \"9007199254740993\"|stringformat:\"d\" }}", "9007199254740993 20000000000001 9007199254740993", Context{"n": int64(9007199254740993)}, ""},
+	{"[{{ \"Joel\"|stringformat:\"-6s\" }}]", "[Joel  ]", nil, ""},
+	{"{{ 5|stringformat:\"%\" }}", "", nil, "Invalid format specifier"},
+	{"{{ \"Joel\"|stringformat:\"d\" }}", "", nil, "is not a number"},
+	{"{{ \"800-COLLECT\"|phone2numeric }}", "800-2655328", nil, ""},
+
+	// List filters
+	{"{{ names|first }}-{{ names|last }}", "Florian-Timm", Context{"names": []string{"Florian", "Georg", "Timm"}}, ""},
+	{"{{ \"Flo\"|first }}{{ \"Flo\"|last }}[{{ names|first }}]", "Fo[]", Context{"names": []int{}}, ""},
+	{"{{ 5|first }}", "", nil, "Cannot apply first to variable of type int"},
+	{"{{ names|slice:\"1:3\"|join:\",\" }}", "2,3", Context{"names": []int{1, 2, 3, 4}}, ""},
+	{"{{ names|slice:\":-1\"|join:\",\" }}|{{ names|slice:\"::2\"|join:\",\" }}|{{ names|slice:2|join:\",\" }}", "1,2,3|1,3|1,2", Context{"names": [4]int{1, 2, 3, 4}}, ""},
+	{"{{ names|slice:\"-2:10\"|join:\",\" }}|{{ names|slice:\"3:1:-1\"|join:\",\" }}", "3,4|4,3", Context{"names": []int{1, 2, 3, 4}}, ""},
+	{"{{ \"Florian\"|slice:\"1:4\" }}", "lor", nil, ""},
+	{"{{ names|slice:\"::0\" }}", "", Context{"names": []int{1}}, "Slice step cannot be zero"},
+	{"{{ names|slice:\"a:b\" }}", "", Context{"names": []int{1}}, "Invalid slice 'a:b'"},
+	{"{{ names|reverse|join:\",\" }} {{ \"Flö\"|reverse }}", "3,2,1 ölF", Context{"names": []int{1, 2, 3}}, ""},
+	{"{{ names|sort|join:\",\" }}", "Florian,Georg,Timm", Context{"names": []string{"Timm", "Florian", "Georg"}}, ""},
+	{"{{ numbers|sort|join:\",\" }}", "1,2.5,3", Context{"numbers": []interface{}{3, 2.5, 1}}, ""},
+	{"{{ numbers|sort }}", "", Context{"numbers": []interface{}{3, "a"}}, "Cannot compare"},
+	{"{{ people|dictsort:\"Age\"|map:\"Name\"|join:\",\" }}", "Florian,Georg,Timm", Context{"people": []*Person{{Name: "Georg", Age: 32}, {Name: "Florian", Age: 25}, {Name: "Timm", Age: 32}}}, ""},
+	{"{{ people|dictsortreversed:\"Age\"|map:\"Name\"|join:\",\" }}", "Georg,Timm,Florian", Context{"people": []*Person{{Name: "Georg", Age: 32}, {Name: "Florian", Age: 25}, {Name: "Timm", Age: 32}}}, ""},
+	{"{{ rows|dictsort:\"n\"|map:\"name\"|join:\",\" }}", "b,c,a", Context{"rows": []map[string]interface{}{{"name": "b", "n": 2}, {"name": "a", "n": 10}, {"name": "c", "n": 2.5}}}, ""},
+	{"{{ rows|dictsort:\"x\" }}", "", Context{"rows": []map[string]interface{}{{"name": "b", "n": 2}, {"name": "a", "n": 10}, {"name": "c", "n": 2.5}}}, "has no field or key 'x'"},
+	{"{{ people|map:\"Friends.0.Name\"|join:\",\" }}", "Georg", Context{"people": []Person{{Friends: []*Person{{Name: "Georg"}}}}}, ""},
+	{"{{ names|unique|join:\",\" }}", "b,a,c", Context{"names": []string{"b", "a", "b", "c", "a"}}, ""},
+	{"{{ lists|unique|length }}", "2", Context{"lists": [][]int{{1}, {2}, {1}}}, ""},
+	{"[{{ names|random }}]", "[]", Context{"names": []string{}}, ""},
+	{"{{ names|length_is:3 }} {{ names|length_is:\"2\" }}", "true false", Context{"names": []string{"a", "b", "c"}}, ""},
+	{"{{ person|attribute:field }}", "Florian", Context{"person": &Person{Name: "Florian"}, "field": "Name"}, ""},
+	{"{{ person|attribute:\"Nickname\" }}", "", Context{"person": &Person{Name: "Florian"}}, "has no field or key 'Nickname'"},
+
+	// Number filters
+	{"{{ 5|add:7 }} {{ 5|add:\"3\" }} {{ \"5\"|add:n }} {{ 1.5|add:2 }}", "12 8 -3 3.5", Context{"n": -8}, ""},
+	{"{{ \"Flo\"|add:\"rian\" }} {{ \"5\"|add:\"b\" }}", "Florian 5b", nil, ""},
+	{"{{ a|add:b|join:\",\" }}", "1,2,3", Context{"a": []int{1, 2}, "b": []int{3}}, ""},
+	{"{{ a|add:b|join:\",\" }}", "1,x", Context{"a": []int{1}, "b": []string{"x"}}, ""},
+	{"{{ 5|add:b }}", "", Context{"b": []int{3}}, "Cannot add 5 (int) and [3] ([]int)"},
+	{"{{ n|add:1 }} {{ m|add:d }}", "9.223372036854776e+18 -9.223372036854776e+18", Context{"n": int64(math.MaxInt64), "m": int64(math.MinInt64), "d": -1}, ""},
+	{"{{ 4500|intcomma }} {{ 45000000|intcomma }} {{ n|intcomma }} {{ \"100\"|intcomma }}", "4,500 45,000,000 -1,234,567.25 100", Context{"n": -1234567.25}, ""},
+	{"{{ \"abc\"|intcomma }}", "", nil, "is not a number"},
+	{"{{ 999999|intword }} {{ 1000000|intword }} {{ 1200000|intword }} {{ 1200000000|intword }} {{ n|intword }}", "999999 1.0 million 1.2 million 1.2 billion -3.5 trillion", Context{"n": -3500000000000}, ""},
+	{"{{ nan|intword }} {{ inf|intword }} {{ big|intword }}", "NaN +Inf 20.0 googol", Context{"nan": math.NaN(), "inf": math.Inf(1), "big": 2e101}, ""},
+	{"{{ 0|filesizeformat }}, {{ 1|filesizeformat }}, {{ 1023|filesizeformat }}, {{ 1024|filesizeformat }}, {{ 123456789|filesizeformat }}", "0 bytes, 1 byte, 1023 bytes, 1.0 KB, 117.7 MB", nil, ""},
+	{"{{ n|filesizeformat }} {{ 1125899906842624|filesizeformat }}", "-2.0 KB 1.0 PB", Context{"n": -2048}, ""},
+	{"{{ 21|divisibleby:3 }} {{ 20|divisibleby:\"3\" }}", "true false", nil, ""},
+	{"{{ 21|divisibleby:0 }}", "", nil, "divisibleby requires a non-zero number"},
+	{"{{ 3.5|divisibleby:3 }} {{ 7.5|divisibleby:2.5 }} {{ 9|divisibleby:1.5 }} {{ 9|divisibleby:\"2.5\" }}", "false true true false", nil, ""},
+	{"vote{{ 1|pluralize }}, vote{{ 2|pluralize }}, vote{{ 0|pluralize }}", "vote, votes, votes", nil, ""},
+	{"class{{ 2|pluralize:\"es\" }} cand{{ 1|pluralize:\"y,ies\" }} cand{{ items|pluralize:\"y,ies\" }}", "classes candy candies", Context{"items": []int{1, 2}}, ""},
+	{"{{ 2|pluralize:\"a,b,c\" }}", "", nil, "Invalid suffixes"},
+	{"{{ 123456789|get_digit:2 }} {{ 123|get_digit:5 }} {{ 123|get_digit:0 }}", "8 0 123", nil, ""},
+	{"{{ 1.5|get_digit:1 }}", "", nil, "is not an integer"},
+
+	// Date filters
+	{"{{ d|date:\"D d M Y H:i\" }}, {{ d|date }}", "Tue 05 Mar 2024 14:30, March 5, 2024", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, ""},
+	{"{{ d|date:\"jS \\o\\f F, l\" }}", "5th of March, Tuesday", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, ""},
+	{"{{ d|date:\"g:i A, P, h a, y-n-j z W t L\" }}", "2:30 PM, 2:30 p.m., 02 p.m., 24-3-5 65 10 31 True", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, ""},
+	{"{{ d|date:\"c U O T Z\" }}", "2024-03-05T14:30:00+00:00 1709649000 +0000 UTC 0", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, ""},
+	{"{{ d|date:\"P\" }}|{{ d2|date:\"P, N jS, b\" }}", "midnight|noon, Sept. 22nd, sep", Context{"d": time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), "d2": time.Date(2023, 9, 22, 12, 0, 0, 0, time.UTC)}, ""},
+	{"{{ d|date:\"Y-m-d\" }} {{ d|time }} {{ d|time:\"H:i:s.u\" }}", "2024-03-05 9 a.m. 09:00:05.000250", Context{"d": func() *time.Time { t := time.Date(2024, 3, 5, 9, 0, 5, 250000, time.UTC); return &t }()}, ""},
+	{"{{ ts|timezone:\"UTC\"|date:\"Y-m-d H:i\" }}", "1970-01-02 00:00", Context{"ts": 86400}, ""},
+	{"{{ d|timezone:\"Europe/Berlin\"|date:\"H:i T e\" }}", "15:30 CET Europe/Berlin", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, ""},
+	{"{{ d|timezone:\"Mars/Base\" }}", "", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, "Unknown time zone 'Mars/Base'"},
+	{"{{ \"today\"|date }}", "", nil, "date requires a time (time.Time, *time.Time or a Unix timestamp), not string ('today')"},
+	{"{{ d|date:5 }}", "", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)}, "Filter 'date' requires a string as argument 1, not int ('5')."},
+	{"{{ d|date:f }}", "", Context{"d": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), "f": 5}, "date's format must be a string"},
+	{"{{ d|date }}", "", Context{"d": (*time.Time)(nil)}, "date requires a time (time.Time, *time.Time or a Unix timestamp), not *time.Time ('<nil>')."},
+	{"[{{ d.Year }}]", "[]", Context{"d": (*time.Time)(nil)}, ""},
+	{"{{ \"today\"|time_format:\"2006\" }}", "", nil, "time_format requires a time"},
+	{"{{ d|time_format }}", "", Context{"d": time.Now()}, "Filter 'time_format' takes 1 argument(s), 0 given."},
+	{"{{ d|time_format:f }}", "", Context{"d": time.Now(), "f": nil}, "time_format requires you pass a format."},
+
+	// URL and encoding filters
+	{"{{ q|urlencode }}|{{ \"https://example.com/?a=b\"|urlencode:\"\" }}", "a%20b%26c/d%3F%C3%A9|https%3A%2F%2Fexample.com%2F%3Fa%3Db", Context{"q": "a b&c/d?é"}, ""},
+	{"{{ u|iriencode }}", "/%C3%BCber?q=%C3%A4&amp;p=1", Context{"u": "/über?q=ä&p=1"}, ""},
+	{"{{ text|urlize }}", "Visit <a href=\"http://www.example.com\" rel=\"nofollow\">www.example.com</a>. or (<a href=\"https://go.dev/wiki/Go_(game)\" rel=\"nofollow\">https://go.dev/wiki/Go_(game)</a>), mail <a href=\"mailto:flo@example.org\">flo@example.org</a>! &lt;b&gt;", Context{"text": "Visit www.example.com. or (https://go.dev/wiki/Go_(game)), mail flo@example.org! <b>"}, ""},
+	{"{{ text|urlize }}", "<a href=\"http://x.com/?a=1&amp;b=%3C2\" rel=\"nofollow\">http://x.com/?a=1&amp;b=&lt;2</a>&gt; 1.5 a:b", Context{"text": "http://x.com/?a=1&b=<2> 1.5 a:b"}, ""},
+	{"{{ text|unsafe|urlize }}", "<i>see</i>\n<a href=\"http://example.com\" rel=\"nofollow\">example.com</a>", Context{"text": "<i>see</i>\nexample.com"}, ""},
+	{"{{ text|urlizetrunc:15 }}", "<a href=\"https://example.com/page\" rel=\"nofollow\">https://exampl…</a> <a href=\"http://go.org\" rel=\"nofollow\">go.org</a>", Context{"text": "https://example.com/page go.org"}, ""},
+	{"{{ text|urlizetrunc:0 }}", "", Context{"text": "go.org"}, "urlizetrunc requires a positive length"},
+	{"{{ \"pongo\"|base64 }}|{{ \"cG9uZ28=\"|base64:\"decode\" }}|{{ \"cG9uZ28\"|base64:\"decode\" }}|{{ b|base64 }}", "cG9uZ28=|pongo|pongo|//4=", Context{"b": []byte{0xff, 0xfe}}, ""},
+	{"{{ \"%%%\"|base64:\"decode\" }}", "", nil, "'%%%' is not base64 encoded"},
+	{"{{ \"pongo\"|base64:\"rot13\" }}", "", nil, "base64's argument must be \"encode\" or \"decode\""},
+	{"{{ data|json }}", "{\"n\":[1,2],\"name\":\"\\u003c/script\\u003e\\u003cb\\u003e\\u0026\"}", Context{"data": map[string]interface{}{"name": "</script><b>&", "n": []int{1, 2}}}, ""},
+	{"{{ data|json:2 }}", "[\n  1,\n  2\n]", Context{"data": []int{1, 2}}, ""},
+	{"{{ data|json_script:\"user-data\" }}|{{ 5|json_script }}", "<script id=\"user-data\" type=\"application/json\">{\"name\":\"\\u003c/script\\u003e\"}</script>|<script type=\"application/json\">5</script>", Context{"data": map[string]string{"name": "</script>"}}, ""},
+	{"{{ f|json }}", "", Context{"f": func() {}}, "json can't encode func()"},
+
+	// HTML filters
+	{"{{ html|sanitize_html }}", "<p>Hi <a href=\"http://example.com\" title=\"a &gt; b\">link</a> <b>bold</b></p>", Context{"html": "<p onclick=\"x()\">Hi <a href=\"http://example.com\" title=\"a > b\" target=_blank>link</a> <b>bold</b><script>alert(1)</script><style>p {}</style><!-- comment -->"}, ""},
+	{"{{ html|sanitize_html }}", "<a>x</a><a>y</a><img alt=\"\">", Context{"html": "<a href=\"javascript:alert(1)\">x</a><a href=\"java&#115;cript:alert(1)\">y</a><img src=\"vbscript:x\" alt=\"\">"}, ""},
+	{"{{ html|sanitize_html:\"p,a[href]\" }}", "<p>1 &lt; 2 <a href=\"/a\">b</a></p>", Context{"html": "<p class=\"x\">1 < 2 <a href=\"/a\" title=\"t\"><em>b</em></a>"}, ""},
+	{"{{ html|sanitize_html }}|{{ \"<b>\"|sanitize_html }}", "<em>a</em>|<b></b>", Context{"html": "<em>a</div>"}, ""},
+	{"{{ text|markdown }}", "<h1>Title</h1>\n<p>Some <em>emphasized</em> and <strong>strong</strong> text with <code>a &lt; b</code> and a <a href=\"http://example.com\" title=\"Example\">link</a>.</p>\n", Context{"text": "# Title\n\nSome *emphasized* and **strong** text with `a < b` and a [link](http://example.com \"Example\")."}, ""},
+	{"{{ text|markdown }}", "<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>three</li>\n</ul>\n</li>\n</ul>\n<ol start=\"3\">\n<li>four</li>\n</ol>\n<pre><code class=\"language-go\">x := &lt;-ch\n</code></pre>\n<blockquote>\n<p>quote</p>\n</blockquote>\n<hr />\n", Context{"text": "- one\n- two\n  * three\n\n3. four\n\n```go\nx := <-ch\n```\n\n> quote\n\n***"}, ""},
+	{"{{ text|markdown }}", "<p>See <a href=\"/docs\">the docs</a> and <img src=\"/logo.png\" alt=\"the logo\" /></p>\n", Context{"text": "See [the docs][docs] and ![the *logo*](/logo.png)\n\n[docs]: /docs"}, ""},
+	{"{{ text|markdown }}", "<p>&lt;script&gt;alert(1)&lt;/script&gt; <a href=\"\">x</a></p>\n", Context{"text": "<script>alert(1)</script> [x](javascript:alert(1))"}, ""},
+	{"{{ text|unsafe|markdown }}", "<div>\n<b>raw</b>\n</div>\n<p><em>text</em></p>\n", Context{"text": "<div>\n<b>raw</b>\n</div>\n\n*text*"}, ""},
+	{"{{ text|markdown:\"sanitize\" }}", "<p><b>bold</b> <a>x</a></p>\n", Context{"text": "<b onclick=\"x()\">bold</b> <a href=\"javascript:alert(1)\">x</a><script>alert(1)</script>"}, ""},
+	{"{{ text|markdown:\"raw\" }}", "", Context{"text": "x"}, "markdown's argument must be \"sanitize\""},
 }
 
 var tags_tests = []test{
@@ -317,6 +590,8 @@ var tags_tests = []test{
 	{"{% for word in words %}{{ word|capitalize }}{% if !forloop.Last %} {%endif %}{% endfor %}", "Hi Florian", Context{"words": []string{"hi", "florian"}}, ""}, // slices in for-loops
 	{"{% for word in words %}{{ word.Key }} means {{ word.Value }}{% endfor %}", "salut means hello", Context{"words": map[string]string{"salut": "hello"}}, ""}, // maps in for-loops
 	{"{% for friend in person.Friends %}{{ friend.Name }}{% endfor %}", "Florian", Context{"person": Person{Friends: []*Person{&Person{Name: "Florian"}}}}, ""},  // slices with structs in for-loops
+	{"{% for n in grid[0] %}{{ n }}{% endfor %}", "12", Context{"grid": [][]int{{1, 2}, {3, 4}}}, ""},                                                             // subscripts in for-loops
+	{"{% for n in grid[i + 1] %}{{ n }}{% endfor %}", "34", Context{"grid": [][]int{{1, 2}, {3, 4}}, "i": 0}, ""},
 
 	// Nested forloops and use of forloop/forloops
 	{"{% for 3 %}{{ forloop.Counter1 }}{%for 6%}{{ forloop.Counter1 }}{% endfor %}{% endfor %}", "112345621234563123456", nil, ""},                                                                                                                                                                                                                                                                                                                                  // addressing their respective for-loop-context
@@ -377,6 +652,29 @@ var tags_tests = []test{
 	{"{% extends \"base\" %}  This doesn't show up", "Hello Josh!", nil, ""},
 	{"{% extends \"base2\" %}  This doesn't show up {% block name %}Florian{% endblock %}", "", nil, "Could not find the template"},
 
+	// Multi-level inheritance and block.super
+	{"{% extends \"layout_child\" %}", "<<i>Base</i>+Child|[child-inner]>", nil, ""},
+	{"{% extends \"layout_child\" %}{% block title %}{{ block.super }}+GC{% endblock %}", "<<i>Base</i>+Child+GC|[child-inner]>", nil, ""},
+	{"{% extends \"layout_child\" %}{% block inner %}{{ block.super }}/gc{% endblock %}", "<<i>Base</i>+Child|[child-inner/gc]>", nil, ""},
+	{"{% extends \"layout_child\" %}{% block content %}({{ block.super }}){% endblock %}", "<<i>Base</i>+Child|([child-inner])>", nil, ""},
+	{"{% extends \"layout_child\" %}{% block content %}{% block extra %}e{% endblock %}{% endblock %}{% block extra %}gc{% endblock %}", "", nil, "defined more than once"},
+	{"{% extends \"layout_child\" %}{% block content %}{% block extra %}{{ name }}{% endblock %}{% endblock %}", "<<i>Base</i>+Child|flo>", Context{"name": "flo"}, ""},
+	{"{% extends \"layout\" %}{% block content %}{% block inner %}x{% endblock %}{% endblock %}", "<<i>Base</i>|x>", nil, ""},
+	{"{% extends \"layout\" %}{% block inner %}{{ block.super|unsafe }}{% endblock %}", "<<i>Base</i>|[base-inner]>", nil, ""},
+	{"{% block a %}[{{ block.super }}]{% endblock %}", "[]", nil, ""},
+	{"{% extends \"layout_fail\" %}{% block a %}block.super{# block.super #}{% endblock %}", "block.super", nil, ""}, // the parent block isn't rendered
+	{"{% extends \"layout_fail\" %}{% block a %}{% if x == \"block.super\" %}{% endif %}ok{% endblock %}", "ok", nil, ""},
+	{"{% extends \"layout_fail\" %}{% block a %}{{ x|default:block.super }}{% endblock %}", "", nil, "divisibleby"},
+	{"{% block a %}{{ block }}{% endblock %}{{ block }}", "map[super:]", Context{"block": ""}, ""}, // context is restored after the block
+	{"{% block a %}x{% endblock a %}", "x", nil, ""},
+	{"{% for 3 %}{% block a %}{{ forcounter }}{% endblock %}{% endfor %}", "012", nil, ""},
+	{"{% if false %}{% block a %}{% block b %}x{% endblock %}{% endblock %}{% endif %}y", "y", nil, ""}, // nested blocks are ignored
+	{"{% block a %}{% block a %}{% endblock %}{% endblock %}", "", nil, "Block 'a' is defined more than once"},
+	{"{% block a %}x", "", nil, "Block 'a' is not closed"},
+	{"x{% endblock %}", "", nil, "[Line 1, Column 14] Found an endblock without a corresponding block"},
+	{"{% block a %}x{% endblock b %}", "", nil, "Endblock 'b' does not match block 'a'"},
+	{"{% block a.b %}x{% endblock %}", "", nil, "must only contain"},
+
 	// Static extend (template will be pre-cached at startup and not dynamically rendered)
 	// This improves speed significantly
 	{"{% extends static \"base\" %}  This doesn't show up {% block name %}Florian{% endblock %}", "Hello Florian!", nil, ""},
@@ -397,6 +695,37 @@ var tags_tests = []test{
 	{"{% include static \"foobar\" %} This and that", "", nil, "Could not find the template"},
 	{"{% include static \"greetings_with_errors\" %} This and that", "", nil, "[Parsing error: greetings_with_errors] [Line 1, Column 27] Filter 'notexistent' not found"},
 
+	// Include with arguments
+	{"{% include \"row\" with item=\"Apple pie\" count=3 %}", "Apple pie-3-flo", Context{"name": "flo"}, ""},
+	{"{% include \"row\" with item=fruit count=fruits|length %}", "Banana-2-flo", Context{"name": "flo", "fruit": "Banana", "fruits": []string{"Banana", "Apple"}}, ""},
+	{"{% include \"row\" item=\"Apple\" %}", "Apple--flo", Context{"name": "flo"}, ""}, // without 'with'
+	{"{% include \"row\" with item=\"Apple\" only %}", "Apple--", Context{"name": "flo", "count": 5}, ""},
+	{"{% include \"hello\" with v=s %}|{% include \"hello\" with v=s.Friends.0 only %}", "Hello Flo!|Hi, Florian|Hello Flo!|Hi, Georg", Context{"s": &person}, ""}, // pointers are passed on (pointer-receiver methods)
+	{"{% include \"row\" with item=items[i + 1] count=items[-1]|length %}", "Banana-3-flo", Context{"name": "flo", "items": []string{"Apple", "Banana", "Fig"}, "i": 0}, ""},
+	{"{% include \"row\" only %}", "--", Context{"name": "flo", "item": "Apple"}, ""},
+	{"{% include \"row\" with item=\"Apple\" %}{{ item }}", "Apple--Banana", Context{"item": "Banana"}, ""}, // caller's context stays untouched
+	{"{% include static \"row\" with item=\"Apple\" count=1 only %}", "Apple-1-", Context{"name": "flo"}, ""},
+	{"{% include \"row\" with %}", "", nil, "requires at least one key=<expr> argument"},
+	{"{% include \"row\" with item %}", "", nil, "must be of the form key=<expr>"},
+	{"{% include \"row\" with item.Name=1 %}", "", nil, "must be of the form key=<expr>"},
+
+	// Include with ignore missing
+	{"{% include \"foobar\" ignore missing %}This and that", "This and that", nil, ""},
+	{"{% include tpl_name ignore missing with item=1 %}This and that", "This and that", Context{"tpl_name": "foobar"}, ""},
+	{"{% include static \"foobar\" ignore missing %}This and that", "This and that", nil, ""},
+	{"{% include \"row\" ignore missing with item=1 only %}", "1--", nil, ""},
+	{"{% include \"greetings_with_errors\" ignore missing %} This and that", "", nil, "Filter 'notexistent' not found"}, // only missing templates are ignored
+
+	// Dependency graph (cycles, missing templates, recursive includes)
+	{"{% extends \"cycle_a\" %}", "", nil, "Cyclic template dependency detected: cycle_a -> cycle_b -> cycle_a"},
+	{"{% extends static \"cycle_a\" %}", "", nil, "Cyclic template dependency detected: cycle_a -> cycle_b -> cycle_a"},
+	{"{% extends tpl_name %}", "", Context{"tpl_name": "cycle_a"}, "Cyclic template dependency detected: cycle_a -> cycle_b -> cycle_a"},
+	{"{% extends tpl_name %}", "", Context{"tpl_name": "cycle_dynamic", "next": "cycle_dynamic"}, "Cyclic template dependency detected: cycle_dynamic -> cycle_dynamic"},
+	{"{% include static \"cycle_static\" %}", "", nil, "Cyclic template dependency detected: cycle_static -> cycle_static"},
+	{"{% if false %}{% include \"foobar\" %}{% endif %}", "", nil, "Could not find the template"}, // missing templates are detected while parsing
+	{"{% include \"tree\" with node=person %}", "Florian(Georg)(Mike)(Philipp)", Context{"person": &person}, ""},                     // recursive includes are allowed
+	{"{% include \"endless\" %}", "", nil, "Maximum template depth of 100 reached"},
+
 	// Custom tag.. 
 	// TODO
 }
@@ -421,6 +750,18 @@ var file_tests = []test{
 var base1 = "Hello {% block name %}Josh{% endblock %}!"
 var greetings1 = "Hello {{ name|capitalize }}!"
 var greetings_with_errors = "Hello {{ name|notexistent }}!"
+var cycle_a = "{% extends \"cycle_b\" %}"
+var cycle_b = "{% extends \"cycle_a\" %}"
+var cycle_dynamic = "{% extends next %}"
+var cycle_static = "{% include static \"cycle_static\" %}"
+var tree = "{{ node.Name }}{% for child in node.Friends %}({% include \"tree\" with node=child %}){% endfor %}"
+var endless = "x{% include \"endless\" %}"
+var row1 = "{{ item }}-{{ count }}-{{ name }}"
+var price1 = "{{ amount|currency }}"
+var hello1 = "{{ v.SayHello }}|{{ v.Greeting(\"Hi\") }}"
+var layout1 = "<{% block title %}<i>Base</i>{% endblock %}|{% block content %}[{% block inner %}base-inner{% endblock %}]{% endblock %}>"
+var layout_fail = "{% block a %}{{ 1|divisibleby:0 }}{% endblock %}"
+var layout_child1 = "{% extends \"layout\" %}This doesn't show up{% block title %}{{ block.super }}+Child{% endblock %}{% block inner %}child-inner{% endblock %}"
 
 func getTemplateCallback(name *string) (*string, error) {
 	switch *name {
@@ -430,6 +771,30 @@ func getTemplateCallback(name *string) (*string, error) {
 		return &greetings1, nil
 	case "greetings_with_errors":
 		return &greetings_with_errors, nil
+	case "row":
+		return &row1, nil
+	case "price":
+		return &price1, nil
+	case "hello":
+		return &hello1, nil
+	case "cycle_a":
+		return &cycle_a, nil
+	case "cycle_b":
+		return &cycle_b, nil
+	case "cycle_dynamic":
+		return &cycle_dynamic, nil
+	case "cycle_static":
+		return &cycle_static, nil
+	case "tree":
+		return &tree, nil
+	case "endless":
+		return &endless, nil
+	case "layout":
+		return &layout1, nil
+	case "layout_child":
+		return &layout_child1, nil
+	case "layout_fail":
+		return &layout_fail, nil
 	default:
 		return nil, errors.New("Could not find the template")
 	}
@@ -455,7 +820,7 @@ func execTpl(t *test) (*string, error) {
 
 func TestFromString(t *testing.T) {
 	// Provide custom filter
-	Filters["add"] = func(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+	Filters["sum"] = func(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
 		i, is_int := value.(int)
 		if !is_int {
 			return nil, errors.New(fmt.Sprintf("No int: %v", value))
@@ -511,6 +876,91 @@ func TestFromString(t *testing.T) {
 	}
 }
 
+func TestRandomFilter(t *testing.T) {
+	names := []string{"Florian", "Georg", "Timm", "Jan", "Max"}
+	in := "{% for i in names %}{{ names|random }},{% endfor %}"
+	tpl, err := FromString("gotest", &in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func() string {
+		SetRandomSeed(42)
+		out, err := tpl.Execute(&Context{"names": names})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *out
+	}
+
+	first := run()
+	if second := run(); first != second {
+		t.Errorf("Same seed returned different results: '%s' and '%s'", first, second)
+	}
+	for _, name := range strings.Split(strings.TrimSuffix(first, ","), ",") {
+		if !containsString(names, name) {
+			t.Errorf("random returned '%s', which isn't part of the list", name)
+		}
+	}
+}
+
+func TestCompiledMatchesInterpreter(t *testing.T) {
+	copyContext := func(ctx Context) *Context {
+		c := make(Context, len(ctx))
+		for k, v := range ctx {
+			c[k] = v
+		}
+		return &c
+	}
+
+	for name, testsuite := range string_tests {
+		for _, test := range testsuite {
+			if test.err == "FUTURE" {
+				continue
+			}
+			tpl, err := FromString("gotest", &test.tpl, getTemplateCallback)
+			if err != nil {
+				continue
+			}
+
+			interpreted, interpreted_err := tpl.executeInterpreted(copyContext(test.ctx))
+			compiled, compiled_err := tpl.Execute(copyContext(test.ctx))
+
+			if fmt.Sprint(interpreted_err) != fmt.Sprint(compiled_err) {
+				t.Errorf("[Suite: %s] Test '%s': interpreter failed with '%v', compiled template with '%v'", name, test.tpl, interpreted_err, compiled_err)
+				continue
+			}
+			if interpreted != nil && compiled != nil && *interpreted != *compiled {
+				t.Errorf("[Suite: %s] Test '%s': interpreter rendered '%s', compiled template '%s'", name, test.tpl, *interpreted, *compiled)
+			}
+		}
+	}
+
+	// Custom tags with a body (but without an Ignore function)
+	Tags["shout"] = &TagHandler{
+		Execute: func(args *string, execCtx *executionContext, ctx *Context) (*string, error) {
+			_, items, err := execCtx.executeUntilAnyTagNode(ctx, "endshout")
+			if err != nil {
+				return nil, err
+			}
+			out := strings.ToUpper(strings.Join(*items, ""))
+			return &out, nil
+		},
+	}
+	Tags["endshout"] = nil
+	defer delete(Tags, "shout")
+	defer delete(Tags, "endshout")
+
+	tpl_str := "a{% shout %}b{{ x }}{% endshout %}c{% if x %}{% shout %}!{% endshout %}{% endif %}"
+	tpl := Must(FromString("gotest", &tpl_str, nil))
+	if tpl.program != nil {
+		t.Errorf("The template '%s' must not be compiled", tpl_str)
+	}
+	out, err := tpl.Execute(&Context{"x": "y"})
+	if err != nil || *out != "aBYc!" {
+		t.Errorf("Test '%s': got %v, %v; should='aBYc!'", tpl_str, out, err)
+	}
+}
+
 func TestFromFile(t *testing.T) {
 	for _, test := range file_tests {
 		name := test.tpl
@@ -565,10 +1015,1396 @@ func TestFromFile(t *testing.T) {
 	}
 }
 
+func TestDependencies(t *testing.T) {
+	in := "{% extends \"layout_child\" %}{% block content %}{% include \"row\" %}{% include tpl_name %}{% include \"tree\" %}{% include \"foobar\" ignore missing %}{% include static \"row\" %}{% endblock %}"
+	tpl, err := FromString("gotest", &in, getTemplateCallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := tpl.Dependencies()
+	expected := []string{"layout_child", "layout", "row", "tree"}
+	if fmt.Sprintf("%v", deps) != fmt.Sprintf("%v", expected) {
+		t.Errorf("Dependencies() returned %v, expected %v", deps, expected)
+	}
+
+	in = "No dependencies"
+	tpl, err = FromString("gotest", &in, getTemplateCallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps := tpl.Dependencies(); len(deps) != 0 {
+		t.Errorf("Dependencies() returned %v, expected none", deps)
+	}
+}
+
+func TestExecuteBlock(t *testing.T) {
+	tests := []struct {
+		tpl    string
+		block  string
+		output string
+		ctx    Context
+		err    string
+	}{
+		{"<div>{% block results %}{{ count }} results{% endblock %}</div>", "results", "3 results", Context{"count": 3}, ""},
+		{"{% extends \"layout_child\" %}", "title", "<i>Base</i>+Child", nil, ""},
+		{"{% extends \"layout_child\" %}", "content", "[child-inner]", nil, ""},
+		{"{% extends \"layout_child\" %}{% block inner %}{{ block.super }}/{{ name }}{% endblock %}", "content", "[child-inner/flo]", Context{"name": "flo"}, ""},
+		{"{% extends \"layout_child\" %}{% block inner %}{{ block.super }}/gc{% endblock %}", "inner", "child-inner/gc", nil, ""},
+		{"{% extends tpl_name %}{% block extra %}extra{% endblock %}", "extra", "extra", Context{"tpl_name": "layout"}, ""}, // only defined in the child
+		{"{% extends \"layout\" %}", "foobar", "", nil, "Block 'foobar' not found"},
+		{"{% extends tpl_name %}", "title", "", nil, "Please provide a propper template filename"},
+		{"\n  {% extends \"layout\" %}{% block title %}t{% endblock %}", "title", "t", nil, ""},
+		{"x{% extends \"layout\" %}{% block title %}t{% endblock %}", "title", "", nil, "(extends \"layout\")] Extends must be the first tag"},
+		{"{% if true %}{% extends \"layout\" %}{% endif %}", "title", "", nil, "Extends must be the first tag"},
+	}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, getTemplateCallback)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		var out *string
+		if test.ctx != nil {
+			out, err = tpl.ExecuteBlock(test.block, &test.ctx)
+		} else {
+			out, err = tpl.ExecuteBlock(test.block, nil)
+		}
+		if err != nil {
+			if test.err == "" || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Test '%s' (block '%s') FAILED: %v", test.tpl, test.block, err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("Test '%s' (block '%s') SUCCEEDED, but '%s' was EXPECTED in error msg; got output: '%s'", test.tpl, test.block, test.err, *out)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' (block '%s') FAILED; got='%s' should='%s'", test.tpl, test.block, *out, test.output)
+		}
+	}
+}
+
+func TestMethodErrorPropagation(t *testing.T) {
+	in := "{% for friend in names %}{{ person.Friend(friend).Name }}{% endfor %}"
+	tpl, err := FromString("gotest", &in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpl.Execute(&Context{"person": &person, "names": []string{"Mike", "Nobody"}})
+	if !errors.Is(err, errFriendNotFound) {
+		t.Errorf("Expected errFriendNotFound to be propagated, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	in = "{{ person.Balance(\"default\") }}"
+	tpl, err = FromString("gotest", &in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpl.Execute(&Context{"person": &person, GoContextKey: ctx})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled to be propagated, got: %v", err)
+	}
+}
+
+type Address struct {
+	City    string `json:"city"`
+	ZipCode string `pongo:"zip"`
+}
+
+type Account struct {
+	*Address
+	FirstName string `json:"first_name"`
+	LastName  string `pongo:"surname" json:"last_name"`
+	Password  string `pongo:"-"`
+	Email     string `json:"-"`
+	City      string `json:"home_city"`
+}
+
+func TestFieldLookup(t *testing.T) {
+	account := &Account{
+		Address:   &Address{City: "Berlin", ZipCode: "10115"},
+		FirstName: "Florian",
+		LastName:  "Schlachter",
+		Password:  "secret",
+		Email:     "flo@example.com",
+		City:      "Hamburg",
+	}
+
+	tests := []struct {
+		opts   FieldLookupOptions
+		tpl    string
+		output string
+	}{
+		{FieldLookupOptions{}, "{{ a.FirstName }}", "Florian"},
+		{FieldLookupOptions{}, "{{ a.first_name }}", ""},
+		{FieldLookupOptions{}, "{{ a.surname }}|{{ a.LastName }}", "Schlachter|Schlachter"},
+		{FieldLookupOptions{}, "{{ a.Password }}", ""},
+		{FieldLookupOptions{}, "{{ a.zip }}|{{ a.ZipCode }}|{{ a.Address.City }}", "10115|10115|Berlin"},
+		{FieldLookupOptions{}, "{{ a.City }}", "Hamburg"}, // embedded fields have a lower priority
+		{FieldLookupOptions{}, "{{ b.zip }}|{{ b.City }}", "|Munich"}, // nil embedded struct
+		{FieldLookupOptions{JSONTags: true}, "{{ a.first_name }}|{{ a.last_name }}|{{ a.Address.city }}", "Florian|Schlachter|Berlin"},
+		{FieldLookupOptions{JSONTags: true}, "{{ a.home_city }}|{{ a.Email }}", "Hamburg|flo@example.com"},
+		{FieldLookupOptions{IgnoreCase: true}, "{{ a.first_name }}|{{ a.firstname }}|{{ a.FIRSTNAME }}|{{ a.last_name }}", "Florian|Florian|Florian|Schlachter"},
+		{FieldLookupOptions{IgnoreCase: true}, "{{ a.zip_code }}|{{ a.Sur_Name }}|{{ a.password }}", "10115|Schlachter|"},
+		{FieldLookupOptions{IgnoreCase: true}, "{{ a.city }}", "Hamburg"},
+	}
+
+	defer func(opts FieldLookupOptions) {
+		FieldLookup = opts
+	}(FieldLookup)
+
+	for _, test := range tests {
+		FieldLookup = test.opts
+		tpl, err := FromString("gotest", &test.tpl, nil)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		out, err := tpl.Execute(&Context{"a": account, "b": Account{City: "Munich"}})
+		if err != nil {
+			t.Errorf("Test '%s' (%+v) FAILED: %v", test.tpl, test.opts, err)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' (%+v) FAILED; got='%s' should='%s'", test.tpl, test.opts, *out, test.output)
+		}
+	}
+}
+
+func TestFilterSpecs(t *testing.T) {
+	for name := range Filters {
+		if name == "sum" {
+			continue
+		}
+		if spec, has_spec := FilterSpecs[name]; !has_spec || spec.Doc == "" {
+			t.Errorf("Filter '%s' has no spec or documentation.", name)
+		}
+	}
+
+	err := RegisterFilter("repeat", func(value interface{}, args []interface{}, ctx *FilterChainContext) (interface{}, error) {
+		sep := ""
+		if len(args) > 1 {
+			sep = args[1].(string)
+		}
+		n, _ := filterIntArg("repeat", args[:1])
+		return strings.Repeat(stringValue(value)+sep, n), nil
+	}, &FilterSpec{MinArgs: 1, MaxArgs: 2, ArgTypes: []ArgType{IntArg, StringArg}, Doc: "Repeats a string."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Filters, "repeat")
+	defer delete(FilterSpecs, "repeat")
+
+	tests := []struct {
+		tpl    string
+		output string
+		err    string
+	}{
+		{"{{ \"a\"|repeat:3 }}|{{ \"a\"|repeat:\"2\",\"-\" }}|{{ \"a\"|repeat:n }}", "aaa|a-a-|aa", ""},
+		{"{{ \"a\"|repeat }}", "", "Filter 'repeat' takes 1 to 2 argument(s), 0 given."},
+		{"{{ \"a\"|repeat:1,\"-\",3 }}", "", "Filter 'repeat' takes 1 to 2 argument(s), 3 given."},
+		{"{{ \"a\"|repeat:1.5 }}", "", "Filter 'repeat' requires an int as argument 1, not float64 ('1.5')."},
+		{"{{ \"a\"|repeat:2,true }}", "", "Filter 'repeat' requires a string as argument 2, not bool ('true')."},
+		{"{{ 4|divisibleby:\"two\" }}", "", "Filter 'divisibleby' requires a number as argument 1, not string ('two')."},
+	}
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, nil)
+		if err != nil {
+			if test.err == "" || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("Test '%s' FAILED: expected the error '%s'", test.tpl, test.err)
+			continue
+		}
+		out, err := tpl.Execute(&Context{"n": 2})
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' FAILED; got='%s' should='%s'", test.tpl, *out, test.output)
+		}
+	}
+
+	usages := map[string]string{
+		"lower":    "lower",
+		"join":     "join:<string>",
+		"date":     "date[:<string>]",
+		"repeat":   "repeat:<int>[,<string>]",
+		"add":      "add:<value>",
+		"currency": "currency[:<string>]",
+	}
+	for name, usage := range usages {
+		if got := FilterSpecs[name].Usage(name); got != usage {
+			t.Errorf("Usage of '%s' is '%s', not '%s'", name, got, usage)
+		}
+	}
+	if got := (&FilterSpec{MinArgs: 1, MaxArgs: -1, ArgTypes: []ArgType{StringArg, StringArg}}).Usage("concat"); got != "concat:<string>[,<string>...]" {
+		t.Errorf("Usage of a variadic filter is '%s'", got)
+	}
+
+	if err := RegisterFilter("bad", nil, &FilterSpec{MinArgs: 2, MaxArgs: 1}); err == nil {
+		t.Errorf("RegisterFilter accepted MinArgs > MaxArgs")
+	}
+	if err := RegisterFilter("a|b", nil, nil); err == nil {
+		t.Errorf("RegisterFilter accepted an invalid name")
+	}
+}
+
+type checkContext struct {
+	User   *Person
+	Users  []*Person
+	Scores map[string]int
+	Title  string
+	Extra  interface{}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		tpl    string
+		errors []string
+	}{
+		{"{{ User.Name }} {{ Title|upper }} {{ Extra.Anything.Goes }} {{ user.age }}", nil},
+		{"{{ User.SayHello }} {{ User.Balance(\"default\") }} {{ User.Friend(\"Mike\").Age }} {{ User.Join(\",\") }}", nil},
+		{"{{ Users[0].Friends[-1].Name }} {{ Users.0.Accounts.default }} {{ Title.0 }} {{ User.Accounts[Title] }}", nil},
+		{"{{ Usr.Name }}", []string{"Variable 'Usr' is not part of the Context."}},
+		{"{{ User.Nmae }}", []string{"Field or method 'Nmae' not found in type pongo.Person."}},
+		{"{{ User.Friend(\"Mike\").Agee }}", []string{"Field or method 'Agee' not found in type pongo.Person."}},
+		{"{{ User.SayHelloTo(\"Mike\") }}", []string{"Method 'SayHelloTo' requires 2 argument(s), 1 given."}},
+		{"{{ User.Greet(\"Hi\") }}", []string{"Method 'Greet' not found in type *pongo.Person."}},
+		{"{{ User.Split }}", []string{"Method 'Split' returns more than one value"}},
+		{"{{ Users[Title] }}{{ User.Age.Value }}", []string{"Specifier 'Value' can't be applied to type int."}},
+		{"{{ User.Friends[i] }}", []string{"Variable 'i' is not part of the Context."}},
+		{"{{ Title|join:\",\" }}{{ Title|default:Missing }}", []string{"Variable 'Missing' is not part of the Context."}},
+		{"{% for u in Users %}{{ u.Name }}{{ forloop.Counter1 }}{% else %}{{ Title }}{% endfor %}", nil},
+		{"{% for s in Scores %}{{ s.Key|upper }}{{ s.Value }}{% endfor %}{% for c in Title %}{{ c }}{% endfor %}{% for 3 %}{% endfor %}", nil},
+		{"{% for u in Users %}{{ u.Nme }}{% endfor %}{{ u.Name }}", []string{
+			"Field or method 'Nme' not found in type pongo.Person.",
+			"Variable 'u' is not part of the Context.",
+		}},
+		{"{% for u in User %}{% endfor %}{% for Title %}{% endfor %}", []string{
+			"For-loop 'in'-operator can't be used for pongo.Person",
+			"Cannot iterate over 'Title' (string).",
+		}},
+		{"{% if User.Age > 18 && Nobody %}{% endif %}{% if %}{% endif %}{% for u in Users %}", []string{
+			"Variable 'Nobody' is not part of the Context.",
+			"If-argument is empty.",
+			"No end-node (possible nodes: [else endfor]) found.",
+		}},
+		{"{% remove \"a\",Pattern %}{% endremove %}", []string{"Variable 'Pattern' is not part of the Context."}},
+		{"{% include \"row\" with item=Title count=1 name=User.Name only %}", nil},
+		{"{% include \"row\" with item=Title count=User.Nmae only %}", []string{
+			"Field or method 'Nmae' not found in type pongo.Person.",
+			"[Check error: row] [Line 1, Column 32] Variable 'name' is not part of the Context.",
+		}},
+		{"{% include \"tree\" with node=User %}", nil},
+		{"{% include tpl_name %}", []string{"Variable 'tpl_name' is not part of the Context."}},
+		{"{% extends \"layout\" %}{% block inner %}{{ User.Name }}{% endblock %}", nil},
+		{"{% extends \"layout\" %}{% block title %}{{ block.super }}{{ User.Foo }}{% endblock %}", []string{"Field or method 'Foo' not found in type pongo.Person."}},
+		{"{% extends \"base\" %}{% block name %}{% for u in Users %}{% block item %}{{ u.Age }}{% endblock %}{% endfor %}{% endblock %}", nil},
+		{"Hello\n  {{ Usr }}", []string{"[Check error: gotest] [Line 2, Column 10] Variable 'Usr' is not part of the Context."}},
+	}
+
+	defer func(opts FieldLookupOptions) {
+		FieldLookup = opts
+	}(FieldLookup)
+	FieldLookup = FieldLookupOptions{IgnoreCase: true}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, getTemplateCallback)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if err := tpl.Bind(checkContext{}); err != nil {
+			t.Fatal(err)
+		}
+		err = tpl.Check()
+		if len(test.errors) == 0 {
+			if err != nil {
+				t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			}
+			continue
+		}
+
+		var check_errs CheckErrors
+		if !errors.As(err, &check_errs) {
+			t.Errorf("Test '%s' FAILED: expected CheckErrors, got: %v", test.tpl, err)
+			continue
+		}
+		if len(check_errs) != len(test.errors) {
+			t.Errorf("Test '%s' FAILED: expected %d errors, got:\n%v", test.tpl, len(test.errors), err)
+			continue
+		}
+		for idx, expected := range test.errors {
+			if !strings.Contains(check_errs[idx].Error(), expected) {
+				t.Errorf("Test '%s' FAILED: expected error '%s', got: %v", test.tpl, expected, check_errs[idx])
+			}
+		}
+	}
+
+	// Shape given by sample values
+	tpl_str := "{{ name|capitalize }}{{ items.0 }}{{ items.first }}{{ anything.goes }}"
+	tpl := Must(FromString("gotest", &tpl_str, nil))
+	if err := tpl.Check(); err == nil || !strings.Contains(err.Error(), "Check requires the shape of the Context") {
+		t.Errorf("Expected an error without shape, got: %v", err)
+	}
+	if err := tpl.Bind(Context{"name": "flo", "items": []int{1, 2}, "anything": nil}); err != nil {
+		t.Fatal(err)
+	}
+	err := tpl.Check()
+	if err == nil || err.Error() != "[Check error: gotest] [Line 1, Column 50] Index 'first' of []int is neither an integer nor part of the Context." {
+		t.Errorf("Unexpected check result: %v", err)
+	}
+	if err := tpl.Bind(42); err == nil {
+		t.Errorf("Expected an error binding an int")
+	}
+}
+
+type viewModel struct {
+	Title  string
+	User   *Person
+	Items  []string
+	Extra  Context
+	secret string
+}
+
+func (vm *viewModel) Greeting(name string) string {
+	return fmt.Sprintf("Hello %s, welcome to %s!", name, vm.Title)
+}
+
+func (vm viewModel) ItemCount() int {
+	return len(vm.Items)
+}
+
+func TestExecuteValue(t *testing.T) {
+	vm := &viewModel{
+		Title:  "pongo",
+		User:   &person,
+		Items:  []string{"a", "b"},
+		Extra:  Context{"key": "value"},
+		secret: "secret",
+	}
+
+	tests := []struct {
+		tpl    string
+		data   interface{}
+		output string
+	}{
+		{"{{ Title|upper }} {{ User.Name }} {{ Items.1 }} {{ Extra.key }}", vm, "PONGO Florian b value"},
+		{"{{ Greeting(User.Name) }}|{{ ItemCount }}|{{ Greeting:Title }}", vm, "Hello Florian, welcome to pongo!|2|Hello pongo, welcome to pongo!"},
+		{"{% for item in Items %}{{ item }}{{ Title }}{% endfor %}{% if ItemCount > 1 %}!{% endif %}", vm, "apongobpongo!"},
+		{"{% for Title in Items %}{{ Title }}{% endfor %}{{ Title }}", vm, "abpongo"}, // the Context shadows the root value
+		{"{{ secret }}{{ Missing }}", vm, ""},
+		{"{{ ItemCount }}|{{ Greeting }}", *vm, "2|"}, // pointer methods require a pointer
+		{"{% include \"row\" with item=Title count=ItemCount %}|{% include \"row\" with item=Title only %}", vm, "pongo-2-|pongo--"},
+		{"{{ name }} {{ age }}", map[string]interface{}{"name": "flo", "age": 29}, "flo 29"},
+		{"{{ name }} {{ age }}", Context{"name": "flo", "age": 29}, "flo 29"},
+		{"{{ red }}/{{ blue }}", map[Color]int{"red": 1, "blue": 2}, "1/2"},
+		{"{{ name }}", nil, ""},
+		{"{{ name }}", 42, ""},
+	}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, getTemplateCallback)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		out, err := tpl.ExecuteValue(test.data)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' FAILED; got='%s' should='%s'", test.tpl, *out, test.output)
+		}
+	}
+
+	// Arguments taken from the Context are resolved for every execution
+	tpl_str := "{{ 5|add:n }}|{{ Greeting(Title) }}"
+	tpl := Must(FromString("gotest", &tpl_str, nil))
+	for _, n := range []int{1, 100, 7} {
+		out, err := tpl.Execute(&Context{"n": n, rootValueKey: vm})
+		if expected := fmt.Sprintf("%d|Hello pongo, welcome to pongo!", 5+n); err != nil || *out != expected {
+			t.Errorf("Execution with n=%d: got %v, %v; should='%s'", n, out, err, expected)
+		}
+	}
+
+	tpl_str = "{{ Greeting(\"a\", \"b\") }}"
+	_, err := Must(FromString("gotest", &tpl_str, nil)).ExecuteValue(vm)
+	if err == nil || !strings.Contains(err.Error(), "Method 'Greeting' requires 1 argument(s), 2 given.") {
+		t.Errorf("Expected an argument error, got: %v", err)
+	}
+
+	// Methods of the root value are checked as well
+	tpl_str = "{{ ItemCount }}{{ Greeting(Title) }}{{ Greeting }}{{ ItemCount.Foo }}"
+	tpl = Must(FromString("gotest", &tpl_str, nil))
+	if err := tpl.Bind(vm); err != nil {
+		t.Fatal(err)
+	}
+	err = tpl.Check()
+	var check_errs CheckErrors
+	if !errors.As(err, &check_errs) || len(check_errs) != 2 ||
+		check_errs[0].Msg != "Method 'Greeting' requires 1 argument(s), 0 given." ||
+		check_errs[1].Msg != "Specifier 'Foo' can't be applied to type int." {
+		t.Errorf("Unexpected check result: %v", err)
+	}
+}
+
+func TestExecuteContext(t *testing.T) {
+	// Cancelled before the execution
+	tpl_str := "{{ name }}"
+	tpl := Must(FromString("gotest", &tpl_str, nil))
+	go_ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tpl.ExecuteContext(go_ctx, &Context{"name": "flo"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+
+	// Cancelled during a loop
+	tpl_str = "{% for i in items %}{{ tick() }}{% endfor %}"
+	tpl = Must(FromString("gotest", &tpl_str, nil))
+	go_ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	ticks := 0
+	ctx := Context{
+		"items": make([]int, 1000000),
+		"tick": func() string {
+			ticks++
+			if ticks == 10 {
+				cancel()
+			}
+			return ""
+		},
+	}
+	if _, err := tpl.ExecuteContext(go_ctx, &ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if ticks != 10 {
+		t.Errorf("Expected the execution to stop after 10 iterations, got %d", ticks)
+	}
+	if _, has_key := ctx[GoContextKey]; has_key {
+		t.Errorf("The caller's Context must not be modified")
+	}
+
+	// The context is passed to methods
+	tpl_str = "{{ person.Balance(\"default\") }}"
+	tpl = Must(FromString("gotest", &tpl_str, nil))
+	out, err := tpl.ExecuteContext(context.WithValue(context.Background(), "user", "Florian"), Context{"person": &person})
+	if err != nil || *out != "1234.56" {
+		t.Errorf("Unexpected result: %v, %v", out, err)
+	}
+	_, err = tpl.ExecuteContext(context.WithValue(context.Background(), "user", "Mike"), Context{"person": &person})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Expected an access denied error, got: %v", err)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	date := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		tpl    string
+		locale string // set by SetLocale
+		ctx    Context
+		output string
+	}{
+		{"{{ 1234.5|floatformat:2 }} {{ 1234567.25|intcomma }} {{ 1234.5|currency }} {{ 1234.5|currency:\"eur\" }} {{ 1234.5|currency:\"JPY\" }}", "", nil, "1234.50 1,234,567.25 $1,234.50 €1,234.50 ¥1,234"},
+		{"{{ 1234.5|floatformat:2 }} {{ 1234567.25|intcomma }} {{ 1234.5|currency }} {{ amount|currency:\"USD\" }}", "de", Context{"amount": -0.5}, "1234,50 1.234.567,25 1.234,50\u00a0€ -0,50\u00a0$"},
+		{"{{ 1234.5|floatformat:2 }} {{ 1234567.25|intcomma }} {{ 1234.5|currency }} {{ 2048|filesizeformat }}", "fr", nil, "1234,50 1\u202f234\u202f567,25 1\u202f234,50\u00a0€ 2,0 KB"},
+		{"{{ date|time_format:\"Monday, 2. January 2006 (Mon, Jan)\" }}", "", Context{"date": date}, "Tuesday, 5. March 2024 (Tue, Mar)"},
+		{"{{ date|time_format:\"Monday, 2. January 2006 (Mon, Jan) 15:04\" }}", "de", Context{"date": date}, "Dienstag, 5. März 2024 (Di., März) 14:30"},
+		{"{{ date|time_format:\"Monday 2 January 2006\" }}", "", Context{"date": date, LocaleKey: "fr_FR"}, "mardi 5 mars 2024"},
+		{"{{ date|date }}, {{ date|time }}", "de", Context{"date": date}, "5. März 2024, 14:30"},
+		{"{{ date|date:\"l j F Y\" }}, {{ date|date:\"N\" }}", "fr", Context{"date": date}, "mardi 5 mars 2024, mars"},
+		{"{{ 1.5|floatformat }}", "de", Context{LocaleKey: "fr"}, "1,5"},                // the Context overrides the template's locale
+		{"{{ 1234|intcomma }}", "", Context{LocaleKey: "xx"}, "1,234"},                 // unknown locales are ignored
+		{"{{ 1234|intcomma }}", "", Context{LocaleKey: Locales["de"]}, "1.234"},        // locales can be passed directly
+		{"{% include \"price\" with amount=5 only %}", "de", nil, "5,00\u00a0€"}, // included templates use the same locale
+	}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, getTemplateCallback)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if test.locale != "" {
+			if err := tpl.SetLocale(test.locale); err != nil {
+				t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+				continue
+			}
+		}
+		ctx := test.ctx
+		if ctx == nil {
+			ctx = Context{}
+		}
+		out, err := tpl.Execute(&ctx)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' FAILED; got='%s' should='%s'", test.tpl, *out, test.output)
+		}
+		if _, has_locale := ctx[LocaleKey]; has_locale != (test.ctx != nil && test.ctx[LocaleKey] != nil) {
+			t.Errorf("Test '%s' FAILED; the locale wasn't removed from the Context", test.tpl)
+		}
+	}
+
+	in := "{{ 1.5 }}"
+	tpl := Must(FromString("gotest", &in, nil))
+	if err := tpl.SetLocale("de_AT"); err != nil {
+		t.Errorf("SetLocale(\"de_AT\") should fall back to \"de\": %v", err)
+	}
+	if err := tpl.SetLocale("xx"); err == nil || !strings.Contains(err.Error(), "Locale 'xx' not found") {
+		t.Errorf("SetLocale(\"xx\") should fail, got: %v", err)
+	}
+}
+
+const test_po = `# German translations
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Hello"
+msgstr "Hallo"
+
+msgid "Hello %(name)s"
+msgstr "Hallo %(name)s"
+
+msgid "100%% sure, %(name)s"
+msgstr "%(name)s, 100%% sicher"
+
+msgid "One apple"
+msgid_plural "%(n)s apples"
+msgstr[0] "Ein Apfel"
+msgstr[1] "%(n)s Äpfel"
+
+msgctxt "month"
+msgid "May"
+msgstr "Mai"
+
+#, fuzzy
+msgid "Bye"
+msgstr "Tschüss"
+
+msgid ""
+"Multiple "
+"lines"
+msgstr "Mehrere\n"
+"Zeilen"
+`
+
+// writeMO encodes messages (msgid => translation) as little-endian .mo file.
+func writeMO(messages map[string]string) []byte {
+	ids := make([]string, 0, len(messages))
+	for id := range messages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	const header = 28
+	var strs bytes.Buffer
+	table := make([]uint32, 0, 4*len(ids))
+	offset := header + 16*len(ids)
+	for _, list := range []func(string) string{
+		func(id string) string { return id },
+		func(id string) string { return messages[id] },
+	} {
+		for _, id := range ids {
+			s := list(id)
+			table = append(table, uint32(len(s)), uint32(offset+strs.Len()))
+			strs.WriteString(s + "\x00")
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, v := range []uint32{0x950412de, 0, uint32(len(ids)), header, uint32(header + 8*len(ids)), 0, 0} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	binary.Write(&buf, binary.LittleEndian, table)
+	buf.Write(strs.Bytes())
+	return buf.Bytes()
+}
+
+func TestRelativeDates(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	catalog, err := ParsePO([]byte("msgid \"\"\nmsgstr \"Plural-Forms: nplurals=2; plural=(n != 1);\\n\"\n\n" +
+		"msgid \"%d day\"\nmsgid_plural \"%d days\"\nmsgstr[0] \"%d Tag\"\nmsgstr[1] \"%d Tage\"\n\n" +
+		"msgid \"%d hour\"\nmsgid_plural \"%d hours\"\nmsgstr[0] \"%d Stunde\"\nmsgstr[1] \"%d Stunden\"\n\n" +
+		"msgid \"%s ago\"\nmsgstr \"vor %s\"\n\nmsgid \"yesterday\"\nmsgstr \"gestern\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	day := 24 * time.Hour
+	tests := []struct {
+		tpl    string
+		ctx    Context
+		output string
+	}{
+		{"{{ d|timesince }}", Context{"d": ago(4*day + 6*time.Hour + 5*time.Minute)}, "4 days, 6 hours"},
+		{"{{ d|timesince }}", Context{"d": ago(400 * day)}, "1 year, 1 month"},
+		{"{{ d|timesince }}", Context{"d": ago(365*day + 21*day)}, "1 year"},
+		{"{{ d|timesince }}", Context{"d": ago(61 * time.Minute)}, "1 hour, 1 minute"},
+		{"{{ d|timesince }}|{{ d2|timesince }}", Context{"d": ago(30 * time.Second), "d2": ago(-day)}, "0 minutes|0 minutes"},
+		{"{{ d|timesince:d2 }}", Context{"d": ago(10 * day), "d2": ago(3 * day)}, "1 week"},
+		{"{{ d|timesince }}", Context{"d": now.Add(-2*time.Hour).Unix()}, "2 hours"},
+		{"{{ d|timeuntil }}", Context{"d": ago(-9 * day)}, "1 week, 2 days"},
+		{"{{ d|timeuntil:d2 }}", Context{"d": ago(-day), "d2": ago(-day)}, "0 minutes"},
+		{"{{ d|naturalday }}|{{ d2|naturalday }}|{{ d3|naturalday }}", Context{"d": ago(14 * time.Hour), "d2": ago(15 * time.Hour), "d3": ago(-10 * time.Hour)}, "today|yesterday|tomorrow"},
+		{"{{ d|naturalday }}|{{ d|naturalday:\"j.n.\" }}", Context{"d": ago(3 * day)}, "March 2, 2024|2.3."},
+		{"{{ d|naturalday }}", Context{"d": now.In(time.FixedZone("UTC+10", 10*60*60))}, "today"},
+		{"{{ d|naturaltime }}|{{ d2|naturaltime }}|{{ d3|naturaltime }}", Context{"d": now, "d2": ago(time.Second), "d3": ago(30 * time.Second)}, "now|a second ago|30 seconds ago"},
+		{"{{ d|naturaltime }}|{{ d2|naturaltime }}|{{ d3|naturaltime }}", Context{"d": ago(3 * time.Minute), "d2": ago(time.Hour), "d3": ago(-5 * time.Hour)}, "3 minutes ago|an hour ago|5 hours from now"},
+		{"{{ d|naturaltime }}|{{ d2|naturaltime }}", Context{"d": ago(2*day + 3*time.Hour), "d2": ago(-time.Minute)}, "2 days, 3 hours ago|a minute from now"},
+		{"{{ d|naturaltime }}|{{ d2|naturalday }}|{{ d3|timesince }}", Context{"d": ago(day + 3*time.Hour), "d2": ago(day), "d3": ago(2 * day), TranslatorKey: catalog}, "vor 1 Tag, 3 Stunden|gestern|2 Tage"},
+	}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, nil)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		out, err := tpl.Execute(&test.ctx)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' FAILED; got='%s' should='%s'", test.tpl, *out, test.output)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	po, err := ParsePO([]byte(test_po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo, err := ParseMO(writeMO(map[string]string{
+		"":              "Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n",
+		"Hello":         "Cześć",
+		"file\x00files": "plik\x00pliki\x00plików",
+		"month\x04May":  "maj",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		catalog *Catalog
+		got     string
+		should  string
+	}{
+		{po, po.Gettext("Hello"), "Hallo"},
+		{po, po.Gettext("Unknown"), "Unknown"},
+		{po, po.Gettext("Bye"), "Bye"}, // fuzzy entries are skipped
+		{po, po.Gettext("Multiple lines"), "Mehrere\nZeilen"},
+		{po, po.Gettext("May"), "May"},
+		{po, po.Gettext("month\x04May"), "month\x04May"}, // entries with a context are skipped
+		{po, po.NGettext("One apple", "%(n)s apples", 1), "Ein Apfel"},
+		{po, po.NGettext("One apple", "%(n)s apples", 0), "%(n)s Äpfel"},
+		{po, po.NGettext("One pear", "%(n)s pears", 1), "One pear"},
+		{po, po.NGettext("One pear", "%(n)s pears", 2), "%(n)s pears"},
+		{mo, mo.Gettext("Hello"), "Cześć"},
+		{mo, mo.Gettext("May"), "May"},
+		{mo, mo.Gettext("month\x04May"), "month\x04May"},
+		{mo, mo.NGettext("file", "files", 1), "plik"},
+		{mo, mo.NGettext("file", "files", 3), "pliki"},
+		{mo, mo.NGettext("file", "files", 5), "plików"},
+		{mo, mo.NGettext("file", "files", 22), "pliki"},
+		{mo, mo.NGettext("file", "files", 112), "plików"},
+	}
+	for idx, test := range tests {
+		if test.got != test.should {
+			t.Errorf("Test %d FAILED; got='%s' should='%s'", idx, test.got, test.should)
+		}
+	}
+
+	rules := []struct {
+		expression string
+		n          []int
+		forms      []int
+	}{
+		{"n != 1", []int{0, 1, 2}, []int{1, 0, 1}},
+		{"n>1", []int{0, 1, 2}, []int{0, 0, 1}},
+		{"0", []int{0, 1, 2}, []int{0, 0, 0}},
+		{"(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", []int{1, 2, 5, 11, 21, 22, 25, 111}, []int{0, 1, 2, 2, 0, 1, 2, 2}},
+		{"n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5", []int{0, 1, 2, 5, 50, 101}, []int{0, 1, 2, 3, 4, 5}},
+		{"!(n == 1) * 2 - 1 + 1", []int{1, 2}, []int{0, 2}},
+	}
+	for _, rule := range rules {
+		fn, err := ParsePluralRule(rule.expression)
+		if err != nil {
+			t.Errorf("Rule '%s' FAILED: %v", rule.expression, err)
+			continue
+		}
+		for idx, n := range rule.n {
+			if form := fn(n); form != rule.forms[idx] {
+				t.Errorf("Rule '%s' FAILED for n=%d; got=%d should=%d", rule.expression, n, form, rule.forms[idx])
+			}
+		}
+	}
+
+	errs := []struct {
+		got    error
+		should string
+	}{
+		{errorOf(ParsePluralRule("n ==")), "Invalid plural expression"},
+		{errorOf(ParsePluralRule("n ? 1")), "Invalid plural expression"},
+		{errorOf(ParsePluralRule("(n")), "Invalid plural expression"},
+		{errorOf(ParsePluralRule("x")), "Invalid plural expression"},
+		{errorOf(ParsePO([]byte("msgid \"a\"\nmsgstr \"b"))), "[PO error] [Line 2]"},
+		{errorOf(ParsePO([]byte("msgstr \"b\""))), "[PO error] [Line 1]"},
+		{errorOf(ParseMO([]byte("not a mo file, not at all"))), "[MO error] Invalid magic number."},
+	}
+	for idx, test := range errs {
+		if test.got == nil || !strings.Contains(test.got.Error(), test.should) {
+			t.Errorf("Error test %d FAILED; got='%v' should contain='%s'", idx, test.got, test.should)
+		}
+	}
+}
+
+func errorOf(_ interface{}, err error) error {
+	return err
+}
+
+func TestTranslation(t *testing.T) {
+	catalog, err := ParsePO([]byte(test_po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Translators["de"] = catalog
+	defer delete(Translators, "de")
+
+	tests := []struct {
+		tpl    string
+		ctx    Context
+		output string
+	}{
+		{"{% trans \"Hello\" %}", nil, "Hello"},
+		{"{% trans \"Hello\" %}", Context{LocaleKey: "de"}, "Hallo"},
+		{"{% trans \"Hello\" noop %}", Context{LocaleKey: "de"}, "Hello"},
+		{"{% trans greeting %}", Context{LocaleKey: "de_CH", "greeting": "Hello"}, "Hallo"},
+		{"{% trans \"Hello\" %}", Context{LocaleKey: "de", TranslatorKey: untranslated{}}, "Hello"},
+		{"{{ _(\"Hello\") }}, {{ _(\"Hello\")|upper }}", Context{LocaleKey: "de"}, "Hallo, HALLO"},
+		{"{% if _(\"Hello\") == \"Hallo\" %}yes{% endif %}", Context{LocaleKey: "de"}, "yes"},
+		{"{% blocktrans %}Hello {{ name }}{% endblocktrans %}", Context{LocaleKey: "de", "name": "Flo"}, "Hallo Flo"},
+		{"{% blocktrans %}Hello {{ name }}{% endblocktrans %}", Context{"name": "Flo"}, "Hello Flo"},
+		{"{% blocktrans with name=user.Name|upper %}Hello {{ name }}{% endblocktrans %}", Context{LocaleKey: "de", "user": map[string]string{"Name": "flo"}}, "Hallo FLO"},
+		{"{% blocktrans %}100% sure, {{ name }}{% endblocktrans %}", Context{LocaleKey: "de", "name": "Flo"}, "Flo, 100% sicher"},
+		{"{% blocktrans count n=apples|length %}One apple{% plural %}{{ n }} apples{% endblocktrans %}", Context{LocaleKey: "de", "apples": []int{1}}, "Ein Apfel"},
+		{"{% blocktrans count n=apples|length %}One apple{% plural %}{{ n }} apples{% endblocktrans %}", Context{LocaleKey: "de", "apples": []int{1, 2, 3}}, "3 Äpfel"},
+		{"{% blocktrans count n=count %}One apple{% plural %}{{ n }} apples{% endblocktrans %}", Context{"count": "7"}, "7 apples"},
+		{"{% blocktrans trimmed with name=\"Flo\" %}\n  Hello\n  {{ name }}\n{% endblocktrans %}", Context{LocaleKey: "de"}, "Hallo Flo"},
+		{"{% for i in items %}{% blocktrans count n=i %}One apple{% plural %}{{ n }} apples{% endblocktrans %};{% endfor %}", Context{LocaleKey: "de", "items": []int{1, 2}}, "Ein Apfel;2 Äpfel;"},
+		{"{% if false %}{% blocktrans %}{% if x %}{% endif %}{% endblocktrans %}{% endif %}ok", nil, "ok"},
+	}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, nil)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		ctx := test.ctx
+		if ctx == nil {
+			ctx = Context{}
+		}
+		out, err := tpl.Execute(&ctx)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		if *out != test.output {
+			t.Errorf("Test '%s' FAILED; got='%s' should='%s'", test.tpl, *out, test.output)
+		}
+	}
+
+	// Translations and variables are escaped, messages of the template are not
+	in := "{% trans \"<b>Hello</b>\" %} {% trans html %} {% blocktrans %}<i>{{ html }}</i>{% endblocktrans %}"
+	tpl := Must(FromString("gotest", &in, nil))
+	out, err := tpl.Execute(&Context{"html": "<b>"})
+	if err != nil || *out != "<b>Hello</b> &lt;b&gt; <i>&lt;b&gt;</i>" {
+		t.Errorf("Autosafe translation FAILED; got='%v' (%v)", *out, err)
+	}
+
+	// Catalogs can only use the variables of the original message
+	evil, err := ParsePO([]byte("msgid \"Hello %(name)s\"\nmsgstr \"%(user.Delete)s %(name)s\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := []struct {
+		tpl    string
+		ctx    Context
+		should string
+	}{
+		{"{% trans %}", nil, "Please provide a message to translate"},
+		{"{% blocktrans %}Hello {{ name }}{% endblocktrans %}", Context{TranslatorKey: evil, "name": "Flo"}, "unknown variable 'user.Delete'"},
+		{"{% trans \"a\" \"b\" %}", nil, "Unknown trans argument"},
+		{"{% blocktrans name=x %}{% endblocktrans %}", nil, "must be of the form key=<expr>"},
+		{"{% blocktrans %}{{ name|upper }}{% endblocktrans %}", nil, "Blocktrans only allows simple variables"},
+		{"{% blocktrans %}{% if x %}{% endif %}{% endblocktrans %}", nil, "Tag 'if' is not allowed within blocktrans"},
+		{"{% blocktrans %}Hello", nil, "No end-node"},
+		{"{% blocktrans count n=x %}One{% endblocktrans %}", Context{"x": 1}, "requires a {% plural %} tag"},
+		{"{% blocktrans %}One{% plural %}Many{% endblocktrans %}", nil, "requires blocktrans with 'count'"},
+		{"{% blocktrans count n=x %}One{% plural %}Many{% endblocktrans %}", Context{"x": "many"}, "count must be a number"},
+	}
+	for _, test := range errs {
+		tpl, err := FromString("gotest", &test.tpl, nil)
+		if err == nil {
+			ctx := test.ctx
+			if ctx == nil {
+				ctx = Context{}
+			}
+			_, err = tpl.Execute(&ctx)
+		}
+		if err == nil || !strings.Contains(err.Error(), test.should) {
+			t.Errorf("Test '%s' FAILED; got error '%v' should contain '%s'", test.tpl, err, test.should)
+		}
+	}
+
+	// The sandbox allows the translation function
+	in = "{{ _(\"Hello\") }}"
+	tpl = Must((&Sandbox{NoMethodCalls: true}).FromString("gotest", &in, nil))
+	if out, err := tpl.Execute(&Context{LocaleKey: "de"}); err != nil || *out != "Hallo" {
+		t.Errorf("Translation in the sandbox FAILED; got='%v' (%v)", out, err)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	in := `{% trans "Hello" %}{% trans name %}
+{{ _("Bye")|upper }}{% if x == _("Yes") %}{% endif %}{{ my_("No") }}
+{% blocktrans with a=b %}Hi {{ a }}, 100% {% endblocktrans %}
+{% blocktrans count n=x trimmed %}
+  One
+{% plural %}
+  {{ n }}   many
+{% endblocktrans %}`
+	tpl := Must(FromString("gotest", &in, nil))
+	messages, err := tpl.Messages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	should := []ExtractedMessage{
+		{ID: "Hello", Line: 1},
+		{ID: "Bye", Line: 2},
+		{ID: "Yes", Line: 2},
+		{ID: "Hi %(a)s, 100%% ", Line: 3},
+		{ID: "One", Plural: "%(n)s   many", Line: 4}, // only the lines are trimmed
+	}
+	if !reflect.DeepEqual(messages, should) {
+		t.Errorf("Messages FAILED; got=%+v should=%+v", messages, should)
+	}
+
+	in = "{% blocktrans %}{{ a.b }}{% endblocktrans %}"
+	tpl = Must(FromString("gotest", &in, nil))
+	if _, err := tpl.Messages(); err == nil || !strings.Contains(err.Error(), "Blocktrans only allows simple variables") {
+		t.Errorf("Messages should fail, got: %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		tpl    string
+		limits Limits
+		output string
+		limit  string // name of the exceeded limit
+	}{
+		{"{% for i in items %}{{ i }}{% endfor %}", Limits{MaxOutput: 3, MaxIterations: 3}, "123", ""},
+		{"{% for i in items %}{{ i }}{% endfor %}", Limits{MaxOutput: 2}, "", "MaxOutput"},
+		{"abc{% for i in items %}{% endfor %}", Limits{MaxOutput: 2}, "", "MaxOutput"},
+		{"{% for i in items %}{% endfor %}", Limits{MaxIterations: 2}, "", "MaxIterations"},
+		{"{% for 1000000000 %}{% endfor %}", Limits{MaxIterations: 1000}, "", "MaxIterations"},
+		{"{% for 3 %}{% for 3 %}{% endfor %}{% endfor %}", Limits{MaxIterations: 12}, "", ""}, // iterations of all loops are counted
+		{"{% for 3 %}{% for 3 %}{% endfor %}{% endfor %}", Limits{MaxIterations: 11}, "", "MaxIterations"},
+		{"{% for 1000000000 %}{% endfor %}", Limits{Timeout: 10 * time.Millisecond}, "", "Timeout"},
+		{"{{ \"x\"|rjust:5 }}|{{ \"x\"|center:4|length }}", Limits{MaxOutput: 10}, "    x|4", ""},
+		{"{{ \"x\"|rjust:5 }}|{{ \"x\"|center:5|length }}", Limits{MaxOutput: 10}, "", "MaxOutput"}, // padded widths count against the remaining output
+		{"{% if \"x\"|ljust:300000000 %}{% endif %}", Limits{MaxOutput: 100}, "", "MaxOutput"},
+		{"{% include \"row\" with item=1 count=2 %}", Limits{MaxDepth: 1}, "1-2-", ""},
+		{"{% include \"greetings\" %}", Limits{MaxDepth: 1}, "Hello !", ""},
+		{"{% include \"endless\" %}", Limits{MaxDepth: 5}, "", "MaxDepth"},
+		{"{% extends \"layout_child\" %}", Limits{MaxDepth: 1}, "", "MaxDepth"},
+		{"{% extends \"layout_child\" %}", Limits{MaxDepth: 2}, "<<i>Base</i>+Child|[child-inner]>", ""},
+	}
+
+	for _, test := range tests {
+		tpl, err := FromString("gotest", &test.tpl, getTemplateCallback)
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+			continue
+		}
+		tpl.SetLimits(test.limits)
+
+		for _, execute := range []func(*Context) (*string, error){tpl.Execute, tpl.executeInterpreted} {
+			out, err := execute(&Context{"items": []int{1, 2, 3}})
+			if test.limit == "" {
+				if err != nil {
+					t.Errorf("Test '%s' (%+v) FAILED: %v", test.tpl, test.limits, err)
+				} else if *out != test.output {
+					t.Errorf("Test '%s' (%+v) FAILED; got='%s' should='%s'", test.tpl, test.limits, *out, test.output)
+				}
+				continue
+			}
+
+			var limit_err *LimitError
+			if !errors.As(err, &limit_err) || limit_err.Limit != test.limit {
+				t.Errorf("Test '%s' (%+v) FAILED: expected %s to be exceeded, got: %v", test.tpl, test.limits, test.limit, err)
+			}
+		}
+	}
+
+	// The default depth is configurable
+	defer func(depth int) {
+		MaxTemplateDepth = depth
+	}(MaxTemplateDepth)
+	MaxTemplateDepth = 3
+	tpl_str := "{% include \"endless\" %}"
+	_, err := Must(FromString("gotest", &tpl_str, getTemplateCallback)).Execute(nil)
+	if err == nil || !strings.Contains(err.Error(), "Maximum template depth of 3 reached") {
+		t.Errorf("Expected the maximum depth to be reached, got: %v", err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	templates := map[string]string{
+		"mails/header":  "Hi {{ person.Name }}{{ person.SayHello }}",
+		"mails/footer":  "Bye {{ person.Name|upper }}",
+		"mails/include": "{% include \"secret\" %}",
+		"mails/greet":   "{{ greet(\"Flo\") }}",
+		"mails/pad":     "{{ name|ljust:300000000 }}",
+		"secret":        "secret",
+	}
+	locator := func(name *string) (*string, error) {
+		if tpl, has_tpl := templates[*name]; has_tpl {
+			return &tpl, nil
+		}
+		return nil, errors.New("Could not find the template")
+	}
+	greet := func(name string) string {
+		return "Hello " + name
+	}
+
+	tests := []struct {
+		sandbox *Sandbox
+		tpl     string
+		output  string
+		err     string
+	}{
+		// Tags and filters
+		{&Sandbox{Tags: []string{"if", "for"}}, "{% for 2 %}{% if true %}x{% else %}y{% endif %}{% endfor %}", "xx", ""},
+		{&Sandbox{Tags: []string{"if", "for"}}, "{% include \"secret\" %}", "", "Tag 'include' is not allowed in the sandbox."},
+		{&Sandbox{Filters: []string{"upper"}}, "{{ person.Name|upper }}<{{ name }}>", "FLORIAN<&lt;b&gt;>", ""},
+		{&Sandbox{Filters: []string{"upper"}}, "{{ person.Name|lower }}", "", "Filter 'lower' is not allowed in the sandbox."},
+		{&Sandbox{Filters: []string{"upper"}}, "{% if person.Name|lower %}{% endif %}", "", "Filter 'lower' is not allowed in the sandbox."},
+		{&Sandbox{Filters: []string{"upper"}}, "{{ name|unsafe }}", "", "Filter 'unsafe' is not allowed in the sandbox."},
+
+		// Method calls
+		{&Sandbox{NoMethodCalls: true}, "{{ person.Name }} {{ person.Friends.0.Name }}", "Florian Georg", ""},
+		{&Sandbox{NoMethodCalls: true}, "{{ person.SayHello }}", "", "Calling '*pongo.Person.SayHello' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ person.Friend(\"Mike\") }}", "", "Calling '*pongo.Person.Friend' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ person.SayHelloTo:\"a\",\"b\" }}", "", "Calling '*pongo.Person.SayHelloTo' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ greet(\"Flo\") }}", "", "Calling 'greet' is not allowed in the sandbox."},
+		{&Sandbox{Types: []string{"*pongo.Person"}}, "{{ person.SayHello }} {{ person.Friend(\"Mike\").Name }}", "Hello Flo! Mike", ""},
+		{&Sandbox{Types: []string{"*pongo.Person"}}, "{{ greet(\"Flo\") }}", "", "Calling 'greet' is not allowed in the sandbox."},
+		{&Sandbox{Methods: []string{"*pongo.Person.SayHello", "greet"}}, "{{ person.SayHello }} {{ greet(\"Flo\") }}", "Hello Flo! Hello Flo", ""},
+		{&Sandbox{Methods: []string{"*pongo.Person.SayHello", "greet"}}, "{{ person.Greeting(\"Hi\") }}", "", "Calling '*pongo.Person.Greeting' is not allowed in the sandbox."},
+
+		// Includes
+		{&Sandbox{NoIncludes: true}, "{% extends \"secret\" %}", "", "Tag 'extends' is not allowed in the sandbox."},
+		{&Sandbox{Roots: []string{"mails/"}}, "{% include \"mails/footer\" %}", "Bye FLORIAN", ""},
+		{&Sandbox{Roots: []string{"mails/"}}, "{% include \"mails/../secret\" %}", "", "Template 'mails/../secret' is outside of the sandbox's roots."},
+		{&Sandbox{Roots: []string{"mails/"}}, "{% include tpl_name %}", "", "Template 'secret' is outside of the sandbox's roots."},
+		{&Sandbox{Roots: []string{"mails/"}}, "{% include \"mails/include\" %}", "", "Template 'secret' is outside of the sandbox's roots."},
+		{&Sandbox{Roots: []string{"mails/"}, NoMethodCalls: true}, "{% include \"mails/header\" %}", "", "Calling '*pongo.Person.SayHello' is not allowed in the sandbox."},
+		{&Sandbox{Roots: []string{"mails/"}, NoMethodCalls: true}, "{% include tpl_name with person=person only %}", "", "Template 'secret' is outside of the sandbox's roots."},
+		{&Sandbox{NoMethodCalls: true}, "{% include \"mails/greet\" with greet=greet only %}", "", "Calling 'greet' is not allowed in the sandbox."},
+
+		// Limits
+		{&Sandbox{Limits: Limits{MaxIterations: 5}}, "{% for 10 %}{% endfor %}", "", "Limit MaxIterations of 5 exceeded."},
+		{&Sandbox{Limits: Limits{MaxOutput: 100}}, "{% if \"x\"|ljust:300000000 %}y{% endif %}{{ \"x\"|center:300000000|length }}", "", "Limit MaxOutput of 100 exceeded."},
+		{&Sandbox{Limits: Limits{MaxOutput: 100}}, "{% include \"mails/pad\" with name=name only %}", "", "Limit MaxOutput of 100 exceeded."},
+	}
+
+	for _, test := range tests {
+		ctx := Context{"person": &person, "name": "<b>", "greet": greet, "tpl_name": "secret"}
+		tpl, err := test.sandbox.FromString("gotest", &test.tpl, locator)
+		if err == nil {
+			var out *string
+			out, err = tpl.Execute(&ctx)
+			if err == nil && *out != test.output {
+				t.Errorf("Test '%s' (%+v) FAILED; got='%s' should='%s'", test.tpl, test.sandbox, *out, test.output)
+			}
+		}
+		if test.err == "" && err != nil {
+			t.Errorf("Test '%s' (%+v) FAILED: %v", test.tpl, test.sandbox, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("Test '%s' (%+v) FAILED: expected error '%s', got: %v", test.tpl, test.sandbox, test.err, err)
+		}
+		if _, has_key := ctx[renderStateKey]; has_key {
+			t.Errorf("Test '%s' (%+v) FAILED: the render state must be removed from the Context", test.tpl, test.sandbox)
+		}
+		if _, has_key := ctx[sandboxKey]; has_key {
+			t.Errorf("Test '%s' (%+v) FAILED: the sandbox must be removed from the Context", test.tpl, test.sandbox)
+		}
+	}
+
+	// Methods of the root value
+	tpl_str := "{{ Title }}{{ ItemCount }}"
+	tpl := Must((&Sandbox{NoMethodCalls: true}).FromString("gotest", &tpl_str, nil))
+	_, err := tpl.ExecuteValue(&viewModel{Title: "pongo"})
+	if err == nil || !strings.Contains(err.Error(), "Calling '*pongo.viewModel.ItemCount' is not allowed in the sandbox.") {
+		t.Errorf("Expected the method call to be denied, got: %v", err)
+	}
+
+	// Methods called implicitly by fmt and encoding/json
+	called := false
+	secret := sandboxSecret{&called}
+	ctx := Context{
+		"secret":  secret,
+		"secrets": []interface{}{1, secret},
+		"wrapped": struct{ S sandboxSecret }{secret},
+	}
+	implicit_tests := []struct {
+		sandbox *Sandbox
+		tpl     string
+		output  string
+		err     string
+	}{
+		{&Sandbox{NoMethodCalls: true}, "{{ secret }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secrets }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ wrapped }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secrets|join:\",\" }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|title }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|slugify }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|stringformat:\"s\" }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|center:10 }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|urlencode }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|truncatechars:3 }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{% if secret|cut:\"x\" %}{% endif %}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|lower }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secrets|last|upper }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ \"a\"|cut:secret }}", "", "Calling 'pongo.sandboxSecret.String' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ secret|json }}", "", "Calling 'pongo.sandboxSecret.MarshalJSON' is not allowed in the sandbox."},
+		{&Sandbox{NoMethodCalls: true}, "{{ wrapped|json_script }}", "", "Calling 'pongo.sandboxSecret.MarshalJSON' is not allowed in the sandbox."},
+		{&Sandbox{Types: []string{"pongo.sandboxSecret"}}, "{{ secret }} {{ secret|json }}", "secret \"secret\"", ""},
+	}
+	for _, test := range implicit_tests {
+		called = false
+		tpl, err := test.sandbox.FromString("gotest", &test.tpl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := tpl.Execute(&ctx)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) || called {
+				t.Errorf("Test '%s' FAILED: expected error '%s' (method called: %v), got: %v", test.tpl, test.err, called, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test '%s' FAILED: %v", test.tpl, err)
+		} else if *out != test.output {
+			t.Errorf("Test '%s' FAILED: got='%s' should='%s'", test.tpl, *out, test.output)
+		}
+	}
+}
+
+// sandboxSecret records whether its methods were called.
+type sandboxSecret struct {
+	called *bool
+}
+
+func (s sandboxSecret) String() string {
+	*s.called = true
+	return "secret"
+}
+
+func (s sandboxSecret) MarshalJSON() ([]byte, error) {
+	*s.called = true
+	return []byte(`"secret"`), nil
+}
+
 // TODO:
 // - Add Must() tests
 // - Add thread-safety tests.
 
+// genPage is the data of the templates of the code generator (see
+// TestGenerateGo).
+type genPage struct {
+	Name   string `pongo:"name"`
+	Html   string `pongo:"html"`
+	Words  []string `pongo:"words"`
+	N      int `pongo:"n"`
+	Flag   bool `pongo:"flag"`
+	Items  []int `pongo:"items"`
+	I      int `pongo:"i"`
+	M      map[string]string `pongo:"m"`
+	Zero   int `pongo:"zero"`
+	User   *Person `pongo:"user"`
+	Nobody *Person `pongo:"nobody"`
+	Extra  interface{} `pongo:"extra"`
+}
+
+func (p *genPage) Title(prefix string) string {
+	return prefix + ": " + p.Name
+}
+
+// Templates for the code generator (see TestGenerateGo)
+var gen_tests = []struct {
+	tpl    string
+	data   *genPage
+	limits Limits
+	cancel bool
+}{
+	{"Hello {{ name|capitalize }}!", &genPage{Name: "florian"}, Limits{}, false},
+	{"{{ html }}|{{ html|unsafe }}|{{ html|safe }}", &genPage{Html: "<b>bold</b>"}, Limits{}, false},
+	{"{% for w in words %}{% if forloop.First %}[{% else %},{% endif %}{{ w|upper }}{% if forloop.Last %}]{% endif %}{% endfor %}", &genPage{Words: []string{"a", "b", "c"}}, Limits{}, false},
+	{"{% for w in words %}{{ w }}{% else %}no words{% endfor %}", &genPage{}, Limits{}, false},
+	{"{% for 3 %}{% for 2 %}{{ forloops.0.Counter1 }}{{ forloops.1.Counter1 }}{{ forcounter }} {% endfor %}{% endfor %}", &genPage{}, Limits{}, false},
+	{"{% if n > 2 && flag %}big{% else %}small{% endif %}", &genPage{N: 3, Flag: true}, Limits{}, false},
+	{"{% if n > 2 && flag %}big{% else %}small{% endif %}", &genPage{N: 1, Flag: true}, Limits{}, false},
+	{"{% if !flag || name == \"flo\" %}yes{% endif %}{% if n >= 1.5 %}no{% endif %}", &genPage{Name: "flo", N: 1}, Limits{}, false},
+	{"{% trim %}   {{ name }}   {% endtrim %}|{% remove \"a\",\"n\" %}banana{% endremove %}", &genPage{Name: "flo"}, Limits{}, false},
+	{"{{ items[i + 1] }}|{{ items[5] }}|{{ items[-1] }}|{{ m.key }}|{{ m.missing }}|{{ words.0 }}", &genPage{Items: []int{1, 2}, M: map[string]string{"key": "value"}}, Limits{}, false},
+	{"{{ user.Name }}|{{ user.Greeting(\"Hi\").Upper }}|{{ user.AgeIn(2) }}|{{ user.Friends.0.Name }}|{{ nobody.Name }}|{{ Title(\"Page\") }}", &genPage{Name: "index", User: &person}, Limits{}, false},
+	{"{{ user.Friend(\"Georg\") }}", &genPage{User: &person}, Limits{}, false},
+	{"{{ user.Balance(\"default\") }}", &genPage{User: &person}, Limits{}, false},
+	{"{% for f in user.Friends %}{{ forcounter1 }}:{{ f.Name }}{% if !forloop.Last %},{% endif %}{% endfor %}|{{ user.Join(\"+\", \"a\", \"b\") }}", &genPage{User: &person}, Limits{}, false},
+	{"{% for kv in m %}{{ kv.Key }}={{ kv.Value }}{% endfor %}|{% for c in name %}[{{ c }}]{% endfor %}", &genPage{Name: "flo", M: map[string]string{"key": "value"}}, Limits{}, false},
+	{"{% include \"row\" with item=nobody count=user.Age name=user.Name %}|{{ nobody }}|{{ user.Friends.5.Name }}|{{ m[name] }}", &genPage{Name: "key", User: &person, M: map[string]string{"key": "value"}}, Limits{}, false},
+	{"{{ extra }}|{{ n|add:2 }}|{{ words|join:\", \" }}", &genPage{Extra: 4.5, N: 1, Words: []string{"a", "b"}}, Limits{}, false},
+	{"{% extends \"layout_child\" %}", &genPage{}, Limits{}, false},
+	{"{% extends \"layout\" %}{% block content %}({{ block.super }}){% endblock %}{% block inner %}{{ name }}{% endblock %}", &genPage{Name: "flo"}, Limits{}, false},
+	{"{% include \"row\" with item=words[0] count=words|length %}|{% include static \"row\" with item=1 count=2 name=\"x\" only %}", &genPage{Words: []string{"x", "y"}, Name: "flo"}, Limits{}, false},
+	{"{% include \"foobar\" ignore missing %}ok", &genPage{}, Limits{}, false},
+	{"{{ items[1 / zero] }}", &genPage{Items: []int{1, 2}}, Limits{}, false},
+	{"{% for i in items %}{{ i }}{% endfor %}", &genPage{Items: []int{1, 2, 3}}, Limits{MaxOutput: 2}, false},
+	{"{% for 3 %}{% for 3 %}{% endfor %}{% endfor %}", &genPage{}, Limits{MaxIterations: 11}, false},
+	{"{% include \"row\" with item=1 count=2 %}", &genPage{}, Limits{MaxDepth: 1}, false},
+	{"{% extends \"layout_child\" %}", &genPage{}, Limits{MaxDepth: 1}, false},
+	{"{{ name }}", &genPage{Name: "flo"}, Limits{}, true},
+	{"{% for w in words %}{{ w }}{% endfor %}", &genPage{Words: []string{"a", "b"}}, Limits{MaxOutput: 2, Timeout: time.Hour}, false},
+}
+
+// TestGenerateGo compares the generated code with gen_fixture_test.go (set
+// PONGO_UPDATE_GOLDEN to update it) and its output with the interpreter's.
+func TestGenerateGo(t *testing.T) {
+	funcs := make(map[string]*Template)
+	var renders []string
+	for i, test := range gen_tests {
+		tpl, err := FromString(fmt.Sprintf("gen%d", i), &test.tpl, getTemplateCallback)
+		if err != nil {
+			t.Fatalf("Test '%s': %v", test.tpl, err)
+		}
+		if err := tpl.Bind(&genPage{}); err != nil {
+			t.Fatalf("Test '%s': %v", test.tpl, err)
+		}
+		tpl.SetLimits(test.limits)
+		funcs[fmt.Sprintf("Render%d", i)] = tpl
+		renders = append(renders, fmt.Sprintf("\t%d: Render%d,\n", i, i))
+	}
+
+	src, err := GenerateGo(pongoPath, "pongo", funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = append(src, fmt.Sprintf("\nvar gen_renders = map[int]func(context.Context, *genPage) (*string, error){\n%s}\n", strings.Join(renders, ""))...)
+	if src, err = format.Source(src); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture, err := os.ReadFile("gen_fixture_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, fixture) {
+		if os.Getenv("PONGO_UPDATE_GOLDEN") == "" {
+			t.Fatal("The generated code differs from gen_fixture_test.go (run the tests with PONGO_UPDATE_GOLDEN=1 to update it).")
+		}
+		if err := os.WriteFile("gen_fixture_test.go", src, 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Skip("Updated gen_fixture_test.go, run the tests again.")
+	}
+
+	for i, test := range gen_tests {
+		go_ctx, cancel := context.WithCancel(context.Background())
+		if test.cancel {
+			cancel()
+		}
+		expected, expected_err := funcs[fmt.Sprintf("Render%d", i)].ExecuteContext(go_ctx, test.data)
+		out, err := gen_renders[i](go_ctx, test.data)
+		cancel()
+
+		switch {
+		case (err == nil) != (expected_err == nil):
+			t.Errorf("Test '%s': generated code returned error %v, interpreter %v", test.tpl, err, expected_err)
+		case err != nil && err.Error() != expected_err.Error():
+			t.Errorf("Test '%s': generated code returned error '%s', interpreter '%s'", test.tpl, err, expected_err)
+		case err == nil && *out != *expected:
+			t.Errorf("Test '%s': generated code rendered '%s', interpreter '%s'", test.tpl, *out, *expected)
+		}
+	}
+
+	// Unsupported templates
+	unsupported := map[string]string{
+		"{% extends basename %}":                          "Only templates with a constant name",
+		"{% include \"tree\" with node=user %}":           "Recursive template 'tree' is not supported",
+		"{% if true %}{% extends \"layout\" %}{% endif %}": "Extends must not be nested",
+		"{{ extra.Name }}":                                "only known at runtime",
+		"{{ _(\"Hello\") }}":                              "Translations are not supported",
+		"{{ unknown }}":                                   "Variable 'unknown' is not part of the Context.",
+	}
+	for tpl_src, expected_err := range unsupported {
+		tpl, err := FromString("unsupported", &tpl_src, getTemplateCallback)
+		if err != nil {
+			t.Fatalf("Test '%s': %v", tpl_src, err)
+		}
+		if err := tpl.Bind(&genPage{}); err != nil {
+			t.Fatalf("Test '%s': %v", tpl_src, err)
+		}
+		_, err = GenerateGo(pongoPath, "pongo", map[string]*Template{"Render": tpl})
+		if err == nil || !strings.Contains(err.Error(), expected_err) {
+			t.Errorf("Test '%s': expected error '%s', got: %v", tpl_src, expected_err, err)
+		}
+	}
+
+	// Templates must be bound to a struct
+	unbound_src := "{{ name }}"
+	unbound, err := FromString("unbound", &unbound_src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateGo(pongoPath, "pongo", map[string]*Template{"Render": unbound}); err == nil || !strings.Contains(err.Error(), "must be bound to a struct type") {
+		t.Errorf("Expected an error for an unbound template, got: %v", err)
+	}
+}
+
+// The identifier benchmarks compare resolving an identifier which has been
+// split at parse time with re-splitting it on every evaluation (as it was
+// done before) and the cached field/method lookups with plain reflection.
+
+var bench_ident = exprIdent("person.Friends.1.Greeting(\"Hi\").Upper.0")
+
+func BenchmarkResolveIdentSplit(b *testing.B) {
+	ctx := Context{"person": &person}
+	for i := 0; i < b.N; i++ {
+		if _, err := resolveIdent(bench_ident, &ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResolveIdentPresplit(b *testing.B) {
+	ctx := Context{"person": &person}
+	parts, err := splitIdent(bench_ident)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := resolveIdentParts(bench_ident, parts, &ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFieldByName(b *testing.B) {
+	rv := reflect.ValueOf(person)
+	for i := 0; i < b.N; i++ {
+		if !rv.FieldByName("Age").IsValid() {
+			b.Fatal("field not found")
+		}
+	}
+}
+
+func BenchmarkLookupField(b *testing.B) {
+	rv := reflect.ValueOf(person)
+	for i := 0; i < b.N; i++ {
+		if !lookupField(rv, "Age").IsValid() {
+			b.Fatal("field not found")
+		}
+	}
+}
+
+func BenchmarkMethodByName(b *testing.B) {
+	rv := reflect.ValueOf(&person)
+	for i := 0; i < b.N; i++ {
+		if !rv.MethodByName("SayHello").IsValid() {
+			b.Fatal("method not found")
+		}
+	}
+}
+
+func BenchmarkLookupMethod(b *testing.B) {
+	rv := reflect.ValueOf(&person)
+	for i := 0; i < b.N; i++ {
+		if !lookupMethod(rv, "SayHello").IsValid() {
+			b.Fatal("method not found")
+		}
+	}
+}
+
+var bench_list = `{% for friend in person.Friends %}{{ friend.Name }} ({{ friend.Age }}): {{ friend.Greeting("Hi").Upper }}, {{ person.Accounts.default }}
+{% endfor %}`
+
+// The execution benchmarks compare the interpreter with compiled templates.
+
+var bench_templates = map[string]string{
+	"list":       bench_list,
+	"conditions": `{% for 50 %}{% if forcounter > 25 %}{% if forloop.First %}first{% else %}{{ forcounter }}{% endif %}{% else %}-{% endif %}{% endfor %}`,
+	"nested":     `{% for 10 %}{% for word in words %}{% trim %} {{ word|capitalize }} {% endtrim %}{% endfor %}{% endfor %}`,
+	"blocks":     `{% extends "layout" %}{% block title %}{{ block.super }}+Child{% endblock %}{% block inner %}{% for word in words %}{{ word }}{% endfor %}{% endblock %}`,
+}
+
+func benchmarkExecute(b *testing.B, name string, interpreted bool) {
+	src := bench_templates[name]
+	tpl, err := FromString(name, &src, getTemplateCallback)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := Context{"person": &person, "words": []string{"hello", "world", "from", "pongo"}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if interpreted {
+			_, err = tpl.executeInterpreted(&ctx)
+		} else {
+			_, err = tpl.Execute(&ctx)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpretedList(b *testing.B)       { benchmarkExecute(b, "list", true) }
+func BenchmarkCompiledList(b *testing.B)          { benchmarkExecute(b, "list", false) }
+func BenchmarkInterpretedConditions(b *testing.B) { benchmarkExecute(b, "conditions", true) }
+func BenchmarkCompiledConditions(b *testing.B)    { benchmarkExecute(b, "conditions", false) }
+func BenchmarkInterpretedNested(b *testing.B)     { benchmarkExecute(b, "nested", true) }
+func BenchmarkCompiledNested(b *testing.B)        { benchmarkExecute(b, "nested", false) }
+func BenchmarkInterpretedBlocks(b *testing.B)     { benchmarkExecute(b, "blocks", true) }
+func BenchmarkCompiledBlocks(b *testing.B)        { benchmarkExecute(b, "blocks", false) }
+
 func ExampleParseArgs() {
 	in := `15029582`
 	r := splitArgs(&in, ",")